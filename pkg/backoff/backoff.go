@@ -16,6 +16,8 @@ import (
 	"math/rand"
 	"sync"
 	"time"
+
+	"go.uber.org/multierr"
 )
 
 // BackOff is a backoff policy for retrying an operation.
@@ -98,16 +100,23 @@ func WithContext(b BackOff, ctx context.Context) Context { // nolint: golint
 	return &backOffContext{BackOff: b, ctx: ctx}
 }
 
+// wrapper is a BackOff which wraps another one, like WithMaxRetries, WithBudget and WithBreaker.
+type wrapper interface {
+	unwrap() BackOff
+}
+
 func getContext(b BackOff) context.Context {
 	if cb, ok := b.(Context); ok {
 		return cb.Context()
 	}
-	if tb, ok := b.(*backOffTries); ok {
-		return getContext(tb.delegate)
+	if w, ok := b.(wrapper); ok {
+		return getContext(w.unwrap())
 	}
 	return context.Background()
 }
 
+func (b *backOffContext) unwrap() BackOff { return b.BackOff }
+
 func (b *backOffContext) Context() context.Context { return b.ctx }
 
 func (b *backOffContext) NextBackOff() time.Duration {
@@ -318,11 +327,24 @@ func RetryNotifyWithTimer(operation Operation, b BackOff, notify Notify, t Timer
 	}()
 
 	ctx := getContext(b)
+	gates := getGates(b)
 
 	b.Reset()
 
 	for retryTimes := 0; ; retryTimes++ {
-		if err = operation(retryTimes); err == nil {
+		if gerr := allowGates(gates, retryTimes); gerr != nil {
+			if err == nil {
+				return gerr
+			}
+			return multierr.Append(err, gerr)
+		}
+
+		err = operation(retryTimes)
+		for _, g := range gates {
+			g.Done(err)
+		}
+
+		if err == nil {
 			if retryTimes > 0 && notify != nil {
 				notify(retryTimes, err, 0)
 			}
@@ -525,6 +547,8 @@ func (b *backOffTries) NextBackOff() time.Duration {
 	return b.delegate.NextBackOff()
 }
 
+func (b *backOffTries) unwrap() BackOff { return b.delegate }
+
 func (b *backOffTries) Reset() {
 	b.numTries = 0
 	b.delegate.Reset()
@@ -0,0 +1,218 @@
+package backoff
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is the error when the attempt is denied by the open, or the busy half-open, Breaker.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// BreakerState is the state of the Breaker.
+type BreakerState int
+
+const (
+	// StateClosed allows all the attempts, and counts the failures.
+	StateClosed BreakerState = iota
+	// StateOpen denies all the attempts until OpenTimeout passed.
+	StateOpen
+	// StateHalfOpen allows HalfOpenMaxRequests probe attempts,
+	// which close the breaker when all succeed, or open it again on any failure.
+	StateHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// Default values for Breaker.
+const (
+	DefaultBreakerInterval     = 10 * time.Second
+	DefaultBreakerOpenTimeout  = 30 * time.Second
+	DefaultBreakerMinRequests  = 10
+	DefaultBreakerFailureRatio = 0.5
+)
+
+// Breaker is a circuit breaker shared by many callers, which plugs into Retry by WithBreaker.
+// In the closed state, it opens when the failure ratio of at least MinRequests attempts in the Interval
+// reaches FailureRatio, then denies the attempts with ErrCircuitOpen for OpenTimeout, and then half-opens.
+//
+// It is thread-safe.
+type Breaker struct {
+	// Interval is the cyclic period to clear the counts in the closed state, 0 to never clear.
+	Interval time.Duration
+	// OpenTimeout is the duration of the open state before half-open.
+	OpenTimeout time.Duration
+	// MinRequests is the min number of the attempts in the Interval before opening.
+	MinRequests int
+	// FailureRatio is the failure ratio to open.
+	FailureRatio float64
+	// HalfOpenMaxRequests is the number of the probe attempts allowed in the half-open state, default is 1.
+	HalfOpenMaxRequests int
+	// IsFailure tells whether the error counts as a failure, default is err != nil.
+	IsFailure func(err error) bool
+	// OnStateChange is called when the state changes, inside the lock of the Breaker.
+	OnStateChange func(from, to BreakerState)
+	Clock         Clock
+
+	mu        sync.Mutex
+	state     BreakerState
+	expiry    time.Time
+	requests  int
+	failures  int
+	successes int
+}
+
+// NewBreaker creates a Breaker using default values.
+func NewBreaker() *Breaker {
+	return &Breaker{
+		Interval:            DefaultBreakerInterval,
+		OpenTimeout:         DefaultBreakerOpenTimeout,
+		MinRequests:         DefaultBreakerMinRequests,
+		FailureRatio:        DefaultBreakerFailureRatio,
+		HalfOpenMaxRequests: 1,
+		Clock:               SystemClock,
+	}
+}
+
+// State returns the current state.
+func (b *Breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.currentState(b.Clock.Now())
+}
+
+func (b *Breaker) currentState(now time.Time) BreakerState {
+	switch b.state {
+	case StateClosed:
+		if b.expiry.IsZero() && b.Interval > 0 {
+			b.expiry = now.Add(b.Interval)
+		} else if !b.expiry.IsZero() && now.After(b.expiry) {
+			b.setState(StateClosed, now)
+		}
+	case StateOpen:
+		if now.After(b.expiry) {
+			b.setState(StateHalfOpen, now)
+		}
+	}
+
+	return b.state
+}
+
+func (b *Breaker) setState(state BreakerState, now time.Time) {
+	prev := b.state
+	b.state = state
+	b.requests, b.failures, b.successes = 0, 0, 0
+
+	switch state {
+	case StateClosed:
+		b.expiry = time.Time{}
+		if b.Interval > 0 {
+			b.expiry = now.Add(b.Interval)
+		}
+	case StateOpen:
+		b.expiry = now.Add(b.OpenTimeout)
+	default:
+		b.expiry = time.Time{}
+	}
+
+	if prev != state && b.OnStateChange != nil {
+		b.OnStateChange(prev, state)
+	}
+}
+
+func (b *Breaker) halfOpenMax() int {
+	if b.HalfOpenMaxRequests <= 0 {
+		return 1
+	}
+	return b.HalfOpenMaxRequests
+}
+
+// Allow tells whether an attempt is allowed, or returns ErrCircuitOpen.
+func (b *Breaker) Allow(int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.currentState(b.Clock.Now()) {
+	case StateOpen:
+		return ErrCircuitOpen
+	case StateHalfOpen:
+		if b.requests >= b.halfOpenMax() {
+			return ErrCircuitOpen
+		}
+	}
+
+	b.requests++
+	return nil
+}
+
+// Done reports the result of the allowed attempt.
+func (b *Breaker) Done(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.Clock.Now()
+	state := b.currentState(now)
+	if err != nil && isDenied(err) { // the attempt is not made at all
+		if b.requests > 0 {
+			b.requests--
+		}
+		return
+	}
+
+	failure := err != nil
+	if b.IsFailure != nil {
+		failure = b.IsFailure(err)
+	}
+
+	switch state {
+	case StateClosed:
+		if !failure {
+			b.successes++
+			return
+		}
+
+		b.failures++
+		if b.requests >= b.MinRequests && float64(b.failures)/float64(b.requests) >= b.FailureRatio {
+			b.setState(StateOpen, now)
+		}
+	case StateHalfOpen:
+		if failure {
+			b.setState(StateOpen, now)
+			return
+		}
+
+		if b.successes++; b.successes >= b.halfOpenMax() {
+			b.setState(StateClosed, now)
+		}
+	}
+}
+
+// Execute runs the operation once if allowed, and reports the result.
+func (b *Breaker) Execute(operation func() error) error {
+	if err := b.Allow(0); err != nil {
+		return err
+	}
+
+	err := operation()
+	b.Done(err)
+	return err
+}
+
+// WithBreaker creates a wrapper around another BackOff, which denies the attempts when the shared breaker is open.
+//
+// Note: the wrapper is not thread-safe, create one for each Retry with the shared breaker.
+func WithBreaker(b BackOff, breaker *Breaker) BackOff {
+	return &backOffGate{BackOff: b, Gate: breaker}
+}
@@ -0,0 +1,102 @@
+package backoff
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	breaker := NewBreaker()
+	breaker.MinRequests = 4
+	breaker.Clock = clock
+
+	var changes []string
+	breaker.OnStateChange = func(from, to BreakerState) {
+		changes = append(changes, from.String()+">"+to.String())
+	}
+
+	fail := errors.New("error")
+	failing := func() error { return fail }
+	ok := func() error { return nil }
+
+	// 2 failures of 4 requests open it
+	for _, f := range []func() error{ok, failing, ok, failing} {
+		_ = breaker.Execute(f)
+	}
+	if s := breaker.State(); s != StateOpen {
+		t.Fatalf("state=%s", s)
+	}
+	if err := breaker.Execute(ok); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// half-open after the open timeout, and opens again on a failed probe
+	clock.now = clock.now.Add(breaker.OpenTimeout + time.Second)
+	if s := breaker.State(); s != StateHalfOpen {
+		t.Fatalf("state=%s", s)
+	}
+	if err := breaker.Execute(failing); err != fail {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if s := breaker.State(); s != StateOpen {
+		t.Fatalf("state=%s", s)
+	}
+
+	// closes on a succeeded probe
+	clock.now = clock.now.Add(breaker.OpenTimeout + time.Second)
+	if err := breaker.Execute(ok); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if s := breaker.State(); s != StateClosed {
+		t.Fatalf("state=%s", s)
+	}
+
+	expected := "closed>open open>half-open half-open>open open>half-open half-open>closed"
+	if got := strings.Join(changes, " "); got != expected {
+		t.Errorf("changes=%s", got)
+	}
+}
+
+func TestBreakerInterval(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	breaker := NewBreaker()
+	breaker.MinRequests = 2
+	breaker.Clock = clock
+
+	_ = breaker.Execute(func() error { return errors.New("error") })
+	// the counts are cleared after the interval
+	clock.now = clock.now.Add(breaker.Interval + time.Second)
+	_ = breaker.Execute(func() error { return errors.New("error") })
+	if s := breaker.State(); s != StateClosed {
+		t.Fatalf("state=%s", s)
+	}
+}
+
+func TestRetryBreaker(t *testing.T) {
+	breaker := NewBreaker()
+	breaker.MinRequests = 3
+
+	calls := 0
+	f := func(retryTimes int) error {
+		calls++
+		return errors.New("error")
+	}
+
+	err := RetryNotifyWithTimer(f, WithBreaker(NewConstantBackOff(time.Millisecond), breaker), nil, &testTimer{})
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("invalid number of calls: %d", calls)
+	}
+
+	// other callers sharing the breaker are denied at once
+	calls = 0
+	err = RetryNotifyWithTimer(f, WithBreaker(NewConstantBackOff(time.Millisecond), breaker), nil, &testTimer{})
+	if !errors.Is(err, ErrCircuitOpen) || calls != 0 {
+		t.Errorf("err=%v calls=%d", err, calls)
+	}
+}
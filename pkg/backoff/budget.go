@@ -0,0 +1,138 @@
+package backoff
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrRetryBudgetExhausted is the error when the retry is denied by the RetryBudget.
+var ErrRetryBudgetExhausted = errors.New("retry budget exhausted")
+
+// Gate is an optional interface of the BackOff wrappers, which is consulted by Retry before each attempt,
+// and reported with the result of each attempt.
+type Gate interface {
+	// Allow tells whether the attempt is allowed, retryTimes is 0 for the first attempt.
+	Allow(retryTimes int) error
+	// Done reports the result of the allowed attempt,
+	// or the denial error of a later Gate when the attempt is not made at all.
+	Done(err error)
+}
+
+// getGates collects the gates in the wrapped BackOffs, the outermost first.
+func getGates(b BackOff) (gates []Gate) {
+	for b != nil {
+		if g, ok := b.(Gate); ok {
+			gates = append(gates, g)
+		}
+
+		w, ok := b.(wrapper)
+		if !ok {
+			break
+		}
+		b = w.unwrap()
+	}
+
+	return gates
+}
+
+// allowGates consults the gates, the allowed ones are reported by the denial error when a later one denies.
+func allowGates(gates []Gate, retryTimes int) error {
+	for i, g := range gates {
+		if err := g.Allow(retryTimes); err != nil {
+			for _, allowed := range gates[:i] {
+				allowed.Done(err)
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isDenied tells whether the error is a denial of the gates.
+func isDenied(err error) bool {
+	return errors.Is(err, ErrRetryBudgetExhausted) || errors.Is(err, ErrCircuitOpen)
+}
+
+// RetryBudget is a token bucket shared by many callers to limit the retries, not the first attempts,
+// so a downstream outage does not turn into a retry storm.
+// Every retry takes a token, the tokens are refilled by Rate per second,
+// and by Ratio per first attempt, up to MaxTokens.
+// For example, Ratio 0.1 allows the retries up to 10% of the requests, plus the Rate ones.
+//
+// It is thread-safe.
+type RetryBudget struct {
+	MaxTokens float64
+	Rate      float64
+	Ratio     float64
+	Clock     Clock
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewRetryBudget creates a full RetryBudget with rate tokens per second, ratio tokens per first attempt,
+// and maxTokens at most.
+func NewRetryBudget(maxTokens, rate, ratio float64) *RetryBudget {
+	return &RetryBudget{MaxTokens: maxTokens, Rate: rate, Ratio: ratio, Clock: SystemClock, tokens: maxTokens}
+}
+
+// Tokens returns the available tokens.
+func (r *RetryBudget) Tokens() float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.refill(0)
+	return r.tokens
+}
+
+func (r *RetryBudget) refill(deposit float64) {
+	now := r.Clock.Now()
+	if !r.last.IsZero() {
+		deposit += now.Sub(r.last).Seconds() * r.Rate
+	}
+	r.last = now
+
+	if r.tokens += deposit; r.tokens > r.MaxTokens {
+		r.tokens = r.MaxTokens
+	}
+}
+
+// Allow deposits Ratio tokens for the first attempt, and takes a token for a retry,
+// or returns ErrRetryBudgetExhausted.
+func (r *RetryBudget) Allow(retryTimes int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if retryTimes == 0 {
+		r.refill(r.Ratio)
+		return nil
+	}
+
+	if r.refill(0); r.tokens < 1 {
+		return ErrRetryBudgetExhausted
+	}
+
+	r.tokens--
+	return nil
+}
+
+// Done does nothing, the tokens are not returned.
+func (r *RetryBudget) Done(error) {}
+
+// WithBudget creates a wrapper around another BackOff, which stops retrying when the shared budget is exhausted.
+//
+// Note: the wrapper is not thread-safe, create one for each Retry with the shared budget.
+func WithBudget(b BackOff, budget *RetryBudget) BackOff {
+	return &backOffGate{BackOff: b, Gate: budget}
+}
+
+// backOffGate is a BackOff wrapper with a Gate.
+type backOffGate struct {
+	BackOff
+	Gate
+}
+
+func (b *backOffGate) unwrap() BackOff { return b.BackOff }
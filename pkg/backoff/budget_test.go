@@ -0,0 +1,56 @@
+package backoff
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func TestRetryBudget(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	budget := NewRetryBudget(2, 1, 0.5)
+	budget.Clock = clock
+
+	calls := 0
+	f := func(retryTimes int) error {
+		calls++
+		return errors.New("error")
+	}
+
+	err := RetryNotifyWithTimer(f, WithBudget(NewConstantBackOff(time.Millisecond), budget), nil, &testTimer{})
+	if !errors.Is(err, ErrRetryBudgetExhausted) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	// the first attempt, and 2 retries by the 2 tokens
+	if calls != 3 {
+		t.Errorf("invalid number of calls: %d", calls)
+	}
+	// the deposit of the first attempt is capped by MaxTokens
+	if tokens := budget.Tokens(); tokens != 0 {
+		t.Errorf("tokens=%f", tokens)
+	}
+
+	// refilled by the rate
+	clock.now = clock.now.Add(time.Second)
+	if tokens := budget.Tokens(); tokens != 1 {
+		t.Errorf("tokens=%f", tokens)
+	}
+	clock.now = clock.now.Add(time.Minute)
+	if tokens := budget.Tokens(); tokens != 2 {
+		t.Errorf("tokens=%f", tokens)
+	}
+}
+
+func TestRetryBudgetFirstAttempt(t *testing.T) {
+	budget := NewRetryBudget(0, 0, 0)
+	calls := 0
+	b := WithMaxRetries(WithBudget(NewConstantBackOff(time.Millisecond), budget), 3)
+	err := RetryNotifyWithTimer(func(int) error { calls++; return nil }, b, nil, &testTimer{})
+	if err != nil || calls != 1 {
+		t.Errorf("err=%v calls=%d", err, calls)
+	}
+}
@@ -0,0 +1,92 @@
+package backoff
+
+import (
+	"math/rand"
+	"time"
+)
+
+// FullJitterBackOff is a backoff policy which sleeps a random duration between 0 and
+// the exponentially growing interval:
+//
+//	sleep = random_between(0, min(MaxInterval, InitialInterval * 2 ^ attempt))
+//
+// It spreads the retries of many callers widely, to avoid the thundering herds.
+// See https://aws.amazon.com/blogs/architecture/exponential-backoff-and-jitter/
+//
+// Note: Implementation is not thread-safe.
+type FullJitterBackOff struct {
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+
+	attempt uint
+}
+
+// NewFullJitterBackOff creates a FullJitterBackOff.
+func NewFullJitterBackOff(initial, max time.Duration) *FullJitterBackOff {
+	return &FullJitterBackOff{InitialInterval: initial, MaxInterval: max}
+}
+
+// Reset the attempt back to the initial one.
+func (b *FullJitterBackOff) Reset() { b.attempt = 0 }
+
+// NextBackOff returns a random duration in [0, min(MaxInterval, InitialInterval * 2 ^ attempt)].
+func (b *FullJitterBackOff) NextBackOff() time.Duration {
+	interval := b.MaxInterval
+	// check for overflow, and cap by the max interval
+	if b.attempt < 62 && b.InitialInterval <= b.MaxInterval>>b.attempt {
+		interval = b.InitialInterval << b.attempt
+	}
+	b.attempt++
+
+	return randomBetween(0, interval)
+}
+
+// DecorrelatedJitterBackOff is a backoff policy which grows the interval by a random factor
+// of the previous one, so the callers retrying at the same time are decorrelated quickly:
+//
+//	sleep = min(MaxInterval, random_between(InitialInterval, previous sleep * 3))
+//
+// See https://aws.amazon.com/blogs/architecture/exponential-backoff-and-jitter/
+//
+// Note: Implementation is not thread-safe.
+type DecorrelatedJitterBackOff struct {
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+
+	previous time.Duration
+}
+
+// NewDecorrelatedJitterBackOff creates a DecorrelatedJitterBackOff.
+func NewDecorrelatedJitterBackOff(initial, max time.Duration) *DecorrelatedJitterBackOff {
+	b := &DecorrelatedJitterBackOff{InitialInterval: initial, MaxInterval: max}
+	b.Reset()
+	return b
+}
+
+// Reset the previous sleep back to the initial interval.
+func (b *DecorrelatedJitterBackOff) Reset() { b.previous = b.InitialInterval }
+
+// NextBackOff returns min(MaxInterval, random_between(InitialInterval, previous sleep * 3)).
+func (b *DecorrelatedJitterBackOff) NextBackOff() time.Duration {
+	upper := b.MaxInterval
+	if b.previous < b.MaxInterval/3 {
+		upper = b.previous * 3
+	}
+
+	next := randomBetween(b.InitialInterval, upper)
+	if next > b.MaxInterval {
+		next = b.MaxInterval
+	}
+
+	b.previous = next
+	return next
+}
+
+// randomBetween returns a random duration in [min, max].
+func randomBetween(min, max time.Duration) time.Duration {
+	if max <= min {
+		return min
+	}
+
+	return min + time.Duration(rand.Int63n(int64(max-min)+1))
+}
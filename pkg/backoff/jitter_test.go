@@ -0,0 +1,36 @@
+package backoff
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFullJitterBackOff(t *testing.T) {
+	b := NewFullJitterBackOff(10*time.Millisecond, 100*time.Millisecond)
+	for i, upper := range []time.Duration{10, 20, 40, 80, 100, 100} {
+		if d := b.NextBackOff(); d < 0 || d > upper*time.Millisecond {
+			t.Errorf("attempt %d: %s not in [0, %dms]", i, d, upper)
+		}
+	}
+
+	b.Reset()
+	if d := b.NextBackOff(); d > 10*time.Millisecond {
+		t.Errorf("after reset: %s not in [0, 10ms]", d)
+	}
+}
+
+func TestDecorrelatedJitterBackOff(t *testing.T) {
+	b := NewDecorrelatedJitterBackOff(10*time.Millisecond, 100*time.Millisecond)
+	prev := 10 * time.Millisecond
+	for i := 0; i < 100; i++ {
+		d := b.NextBackOff()
+		upper := prev * 3
+		if upper > 100*time.Millisecond {
+			upper = 100 * time.Millisecond
+		}
+		if d < 10*time.Millisecond || d > upper {
+			t.Fatalf("attempt %d: %s not in [10ms, %s]", i, d, upper)
+		}
+		prev = d
+	}
+}
@@ -201,3 +201,7 @@ func (fns WalkOptionsFns) Create() *WalkOptions {
 
 	return o
 }
+
+func (b *Badger) Del(k []byte) error {
+	return b.DB.Update(func(txn *badger.Txn) error { return txn.Delete(k) })
+}
@@ -3,6 +3,7 @@ package badgerdb
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/bingoohuang/gg/pkg/bytex"
 	"github.com/stretchr/testify/assert"
@@ -28,3 +29,24 @@ func TestNew(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Nil(t, db.Close())
 }
+
+func TestCacheStorage(t *testing.T) {
+	db, err := Open(WithInMemory(true))
+	assert.Nil(t, err)
+	defer db.Close()
+
+	s := CacheStorage{Badger: db, Prefix: "http:"}
+	assert.Nil(t, s.Set("GET /", []byte("hello"), time.Minute))
+
+	v, err := s.Get("GET /")
+	assert.Nil(t, err)
+	assert.Equal(t, "hello", string(v))
+
+	v, _ = db.Get([]byte("http:GET /"))
+	assert.Equal(t, "hello", string(v))
+
+	assert.Nil(t, s.Delete("GET /"))
+	v, err = s.Get("GET /")
+	assert.Nil(t, err)
+	assert.Nil(t, v)
+}
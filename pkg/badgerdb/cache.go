@@ -0,0 +1,21 @@
+package badgerdb
+
+import "time"
+
+// CacheStorage is a string-keyed storage with ttl on the Badger, e.g. for resty.ResponseCache.
+type CacheStorage struct {
+	*Badger
+	// Prefix is prepended to the keys, to share the Badger with others.
+	Prefix string
+}
+
+// Get returns the value of the key, or nil if not found.
+func (s CacheStorage) Get(key string) ([]byte, error) { return s.Badger.Get([]byte(s.Prefix + key)) }
+
+// Set sets the value of the key, which expires after the ttl.
+func (s CacheStorage) Set(key string, value []byte, ttl time.Duration) error {
+	return s.Badger.Set([]byte(s.Prefix+key), value, WithTTL(ttl))
+}
+
+// Delete deletes the key.
+func (s CacheStorage) Delete(key string) error { return s.Badger.Del([]byte(s.Prefix + key)) }
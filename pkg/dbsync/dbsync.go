@@ -3,7 +3,9 @@ package dbsync
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/bingoohuang/gg/pkg/mapp"
@@ -11,11 +13,12 @@ import (
 )
 
 type DbSync struct {
-	db     *sql.DB
-	table  string
-	config *Config
-	cache  map[string]string
-	stop   chan struct{}
+	db        *sql.DB
+	table     string
+	config    *Config
+	cache     map[string]string
+	watermark string
+	stop      chan struct{}
 }
 
 func NewDbSync(db *sql.DB, table string, options ...Option) *DbSync {
@@ -36,15 +39,21 @@ func (s *DbSync) loop() {
 	t := time.NewTicker(s.config.duration)
 	defer t.Stop()
 
-	s.cache = make(map[string]string)
-	query := s.config.CreateQuery(s.table)
+	var reconcile <-chan time.Time
+	if s.config.incremental && s.config.reconcile > 0 {
+		r := time.NewTicker(s.config.reconcile)
+		defer r.Stop()
+		reconcile = r.C
+	}
 
-	s.sync(query)
+	s.init()
 
 	for {
 		select {
 		case <-t.C:
-			s.sync(query)
+			s.tick()
+		case <-reconcile:
+			s.sync(s.config.CreateQuery(s.table))
 		case <-s.config.Context.Done():
 			return
 		case <-s.stop:
@@ -53,23 +62,77 @@ func (s *DbSync) loop() {
 	}
 }
 
+// init initializes the cache, and syncs for the first time.
+func (s *DbSync) init() {
+	s.cache = make(map[string]string)
+	if s.config.incremental {
+		s.initIncremental()
+	}
+
+	s.tick()
+}
+
+func (s *DbSync) tick() {
+	if s.config.incremental {
+		s.syncIncremental()
+	} else {
+		s.sync(s.config.CreateQuery(s.table))
+	}
+}
+
 type row struct {
-	Pk string
-	V  string
+	Pk      string
+	V       string
+	Deleted string
 }
 
-func (s *DbSync) sync(query string) {
+// isDeleted tells whether the tombstone column value means deleted.
+func (r row) isDeleted() bool {
+	switch strings.ToLower(r.Deleted) {
+	case "", "0", "f", "false", "n", "no":
+		return false
+	default:
+		return true
+	}
+}
+
+func (s *DbSync) query(query string, vars ...interface{}) ([]row, bool) {
 	var rows []row
-	err := sqx.NewSQL(query).Query(s.db, &rows)
-	if err != nil {
+	err := sqx.NewSQL(query, vars...).Query(s.db, &rows)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
 		log.Printf("E! failed to execute query: %s, err: %v", query, err)
-		return
+		return nil, false
 	}
 
-	current := mapp.Clone(s.cache)
+	return rows, true
+}
+
+func (s *DbSync) sync(query string) {
+	if rows, ok := s.query(query); ok {
+		s.apply(rows, true)
+	}
+}
+
+// apply notifies the events of the changed rows,
+// and the deletes of the cached rows which are missing in the full rows.
+func (s *DbSync) apply(rows []row, full bool) {
+	var current map[string]string
+	if full {
+		current = mapp.Clone(s.cache)
+	}
 
 	for _, r := range rows {
+		delete(current, r.Pk)
+
 		v, ok := s.cache[r.Pk]
+		if r.isDeleted() {
+			if ok {
+				delete(s.cache, r.Pk)
+				s.config.notify(EventDelete, r.Pk, r.V)
+			}
+			continue
+		}
+
 		if !ok || v != r.V {
 			s.cache[r.Pk] = r.V
 
@@ -79,10 +142,6 @@ func (s *DbSync) sync(query string) {
 				s.config.notify(EventModify, r.Pk, r.V)
 			}
 		}
-
-		if ok {
-			delete(current, r.Pk)
-		}
 	}
 
 	for k, v := range current {
@@ -111,10 +170,19 @@ type Config struct {
 	pk       string
 	duration time.Duration
 	notify   func(event Event, id, v string)
+
+	incremental    bool
+	tombstone      string
+	reconcile      time.Duration
+	watermarkStore WatermarkStore
 }
 
 func (c Config) CreateQuery(t string) string {
-	return "select " + c.pk + " as pk," + c.v + " as v from " + t
+	q := "select " + c.pk + " as pk," + c.v + " as v"
+	if c.tombstone != "" {
+		q += "," + c.tombstone + " as deleted"
+	}
+	return q + " from " + t
 }
 
 func createConfig(options []Option) *Config {
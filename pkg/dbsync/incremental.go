@@ -0,0 +1,112 @@
+package dbsync
+
+import (
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// WithIncremental enables the incremental mode, which only queries the rows
+// whose v column (like version or updated_at) is greater than the last seen watermark, instead of the whole table.
+// The deletes are detected by the tombstone column (WithTombstone) or the periodic full reconcile (WithReconcile).
+func WithIncremental(v bool) Option { return func(c *Config) { c.incremental = v } }
+
+// WithTombstone specifies the tombstone column, like deleted, the rows with a true value (not empty, 0, f, false, n or no)
+// are notified as EventDelete.
+func WithTombstone(v string) Option { return func(c *Config) { c.tombstone = v } }
+
+// WithReconcile specifies the interval of the full reconcile in the incremental mode, like 10m,
+// which detects the hard deletes and the changes missed by the watermark.
+func WithReconcile(v string) Option {
+	return func(c *Config) { c.reconcile, _ = time.ParseDuration(v) }
+}
+
+// WithWatermarkStore specifies the store to persist the watermark in the incremental mode,
+// so a restart does not re-emit EventCreate for every row.
+func WithWatermarkStore(v WatermarkStore) Option { return func(c *Config) { c.watermarkStore = v } }
+
+// WatermarkStore persists the watermark of the incremental mode.
+type WatermarkStore interface {
+	// Load loads the watermark, empty for none.
+	Load() (string, error)
+	// Save saves the watermark.
+	Save(watermark string) error
+}
+
+// FileWatermark is the WatermarkStore in the file of the path.
+type FileWatermark string
+
+// Load loads the watermark from the file, empty when the file does not exist.
+func (f FileWatermark) Load() (string, error) {
+	data, err := os.ReadFile(string(f))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+
+	return strings.TrimSpace(string(data)), err
+}
+
+// Save saves the watermark to the file.
+func (f FileWatermark) Save(watermark string) error {
+	return os.WriteFile(string(f), []byte(watermark), 0o644)
+}
+
+// CreateIncrementalQuery creates the query of the rows after the watermark, ordered by the v column.
+func (c Config) CreateIncrementalQuery(t string, watermarked bool) string {
+	q := c.CreateQuery(t)
+	if watermarked {
+		q += " where " + c.v + " > ?"
+	}
+	return q + " order by " + c.v
+}
+
+// initIncremental loads the persisted watermark, and the cache of the rows up to it without notifying.
+func (s *DbSync) initIncremental() {
+	if s.config.watermarkStore == nil {
+		return
+	}
+
+	watermark, err := s.config.watermarkStore.Load()
+	if err != nil {
+		log.Printf("E! failed to load watermark, err: %v", err)
+		return
+	}
+	if watermark == "" {
+		return
+	}
+
+	rows, ok := s.query(s.config.CreateQuery(s.table)+" where "+s.config.v+" <= ?", watermark)
+	if !ok {
+		return
+	}
+
+	for _, r := range rows {
+		if !r.isDeleted() {
+			s.cache[r.Pk] = r.V
+		}
+	}
+
+	s.watermark = watermark
+}
+
+func (s *DbSync) syncIncremental() {
+	var vars []interface{}
+	if s.watermark != "" {
+		vars = append(vars, s.watermark)
+	}
+
+	rows, ok := s.query(s.config.CreateIncrementalQuery(s.table, s.watermark != ""), vars...)
+	if !ok || len(rows) == 0 {
+		return
+	}
+
+	s.apply(rows, false)
+
+	s.watermark = rows[len(rows)-1].V
+	if s.config.watermarkStore != nil {
+		if err := s.config.watermarkStore.Save(s.watermark); err != nil {
+			log.Printf("E! failed to save watermark %s, err: %v", s.watermark, err)
+		}
+	}
+}
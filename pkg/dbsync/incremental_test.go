@@ -0,0 +1,61 @@
+package dbsync
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIncremental(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	assert.Nil(t, err)
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+
+	exec := func(query string) {
+		_, err := db.Exec(query)
+		assert.Nil(t, err)
+	}
+	exec("create table t_bucket (pk varchar(100) primary key, version int not null, deleted int default 0)")
+	exec("insert into t_bucket(pk, version) values('aa', 1), ('bb', 2)")
+
+	var events []string
+	store := FileWatermark(filepath.Join(t.TempDir(), "watermark"))
+	newSync := func() *DbSync {
+		return NewDbSync(db, "t_bucket", WithPk("pk"), WithV("version"),
+			WithIncremental(true), WithTombstone("deleted"), WithWatermarkStore(store),
+			WithNotify(func(event Event, id, v string) { events = append(events, fmt.Sprintf("%s:%s:%s", event, id, v)) }))
+	}
+
+	s := newSync()
+	s.init()
+	assert.Equal(t, []string{"EventCreate:aa:1", "EventCreate:bb:2"}, events)
+	watermark, _ := store.Load()
+	assert.Equal(t, "2", watermark)
+
+	events = nil
+	exec("update t_bucket set version = 3 where pk = 'aa'")
+	exec("update t_bucket set version = 4, deleted = 1 where pk = 'bb'")
+	exec("insert into t_bucket(pk, version) values('cc', 5)")
+	s.tick()
+	assert.Equal(t, []string{"EventModify:aa:3", "EventDelete:bb:4", "EventCreate:cc:5"}, events)
+
+	// a restart does not re-emit the rows before the watermark
+	events = nil
+	exec("insert into t_bucket(pk, version) values('dd', 6)")
+	s = newSync()
+	s.init()
+	assert.Equal(t, []string{"EventCreate:dd:6"}, events)
+
+	// the hard deletes are detected by the full reconcile
+	events = nil
+	exec("delete from t_bucket where pk = 'cc'")
+	s.tick()
+	assert.Nil(t, events)
+	s.sync(s.config.CreateQuery(s.table))
+	assert.Equal(t, []string{"EventDelete:cc:5"}, events)
+}
@@ -0,0 +1,321 @@
+package elasticsql
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/bingoohuang/gg/pkg/sqlparse/sqlparser"
+	"github.com/bingoohuang/gg/pkg/ss"
+)
+
+// defaultBucketSize is the size of the terms aggregation when no limit is specified.
+const defaultBucketSize = 200
+
+// buildAggs builds the aggregations from the group by, the aggregate functions in the select and the having.
+// The group by fields are nested from the first to the last, with the metrics and the having in the innermost one,
+// the limit is the size of the terms in every level, and the order by is the order of the innermost buckets.
+func buildAggs(sel *sqlparser.Select) (string, error) {
+	metrics := &metricAggs{names: map[string]string{}}
+	for _, selectExpr := range sel.SelectExprs {
+		switch e := selectExpr.(type) {
+		case *sqlparser.StarExpr:
+		case *sqlparser.AliasedExpr:
+			// the plain columns are the group by ones usually, which are the keys of the buckets
+			if funcExpr, ok := e.Expr.(*sqlparser.FuncExpr); ok {
+				if _, err := metrics.add(funcExpr, e.As.String()); err != nil {
+					return "", err
+				}
+			}
+		default:
+			return "", unsupported(selectExpr, "in select")
+		}
+	}
+
+	var (
+		having string
+		err    error
+	)
+	if sel.Having != nil {
+		if len(sel.GroupBy) == 0 {
+			return "", errors.New("elasticsql: having without group by is not supported")
+		}
+		if having, err = buildHaving(sel.Having.Expr, metrics); err != nil {
+			return "", err
+		}
+	}
+
+	// the having requires the group by
+	if len(sel.GroupBy) == 0 && len(metrics.aggs) == 0 {
+		return "", nil
+	}
+
+	order, err := buildBucketOrder(sel, metrics)
+	if err != nil {
+		return "", err
+	}
+
+	innerArr := metrics.aggs
+	if having != "" {
+		innerArr = append(innerArr, having)
+	}
+
+	size := defaultBucketSize
+	if sel.Limit != nil {
+		size = ss.ParseInt(sqlparser.String(sel.Limit.Rowcount))
+	}
+
+	inner := strings.Join(innerArr, ",")
+	for i := len(sel.GroupBy) - 1; i >= 0; i-- {
+		bucketOrder := ""
+		if i == len(sel.GroupBy)-1 {
+			bucketOrder = order
+		}
+		name, bucket, err := buildBucketAgg(sel.GroupBy[i], size, bucketOrder)
+		if err != nil {
+			return "", err
+		}
+		if inner == "" {
+			inner = fmt.Sprintf(`"%v" : {%v}`, name, bucket)
+		} else {
+			inner = fmt.Sprintf(`"%v" : {%v, "aggs" : {%v}}`, name, bucket, inner)
+		}
+	}
+	return "{" + inner + "}", nil
+}
+
+// buildBucketAgg builds the bucket aggregation of the group by expression, which is a column for the terms,
+// or date_histogram(field='create_time', calendar_interval='1d', format='yyyy-MM-dd'), with the optional order.
+func buildBucketAgg(expr sqlparser.Expr, size int, order string) (name, bucket string, err error) {
+	switch e := expr.(type) {
+	case *sqlparser.ColName:
+		name = strings.Replace(sqlparser.String(e), "`", "", -1)
+		if order != "" {
+			return name, fmt.Sprintf(`"terms" : {"field" : "%v", "size" : %v, %v}`, name, size, order), nil
+		}
+		return name, fmt.Sprintf(`"terms" : {"field" : "%v", "size" : %v}`, name, size), nil
+	case *sqlparser.FuncExpr:
+		if e.Name.Lowered() != "date_histogram" {
+			return "", "", unsupported(e, "function in group by")
+		}
+		var params []string
+		for _, param := range e.Exprs {
+			elem := strings.Replace(sqlparser.String(param), "`", "", -1) // a = b
+			kv := strings.Split(elem, "=")
+			if len(kv) != 2 {
+				return "", "", errors.New("elasticsql: the param should be field = xxx, calendar_interval = yyy, format = zzz")
+			}
+			k, v := strings.TrimSpace(kv[0]), strings.Trim(strings.TrimSpace(kv[1]), "'")
+			if k == "field" {
+				name = v
+			}
+			params = append(params, fmt.Sprintf(`"%v" : "%v"`, k, v))
+		}
+		if name == "" {
+			return "", "", errors.New("elasticsql: the date_histogram must have the field param")
+		}
+		if order != "" {
+			params = append(params, order)
+		}
+		return name, fmt.Sprintf(`"date_histogram" : {%v}`, strings.Join(params, ", ")), nil
+	default:
+		return "", "", unsupported(expr, "in group by")
+	}
+}
+
+// buildBucketOrder builds the order of the innermost bucket from the order by, which is the innermost group by
+// for the key of the buckets, or the aggregate functions and their aliases in the select, like
+// order by count(*) desc, a to "order" : [{"_count" : "desc"}, {"_key" : "asc"}].
+func buildBucketOrder(sel *sqlparser.Select, metrics *metricAggs) (string, error) {
+	if len(sel.OrderBy) == 0 {
+		return "", nil
+	}
+	if len(sel.GroupBy) == 0 {
+		return "", unsupported(sel.OrderBy[0], "in order by without group by")
+	}
+
+	innermost := sqlparser.String(sel.GroupBy[len(sel.GroupBy)-1])
+	var orders []string
+	for _, orderBy := range sel.OrderBy {
+		var path string
+		switch e := orderBy.Expr.(type) {
+		case *sqlparser.ColName:
+			// the alias of the aggregate function in the select, or the innermost group by
+			if name, ok := metrics.names[e.Name.String()]; ok && e.Qualifier.IsEmpty() {
+				path = name
+			} else if sqlparser.String(e) == innermost {
+				path = "_key"
+			}
+		case *sqlparser.FuncExpr:
+			if sqlparser.String(e) == innermost {
+				path = "_key"
+			} else if p, err := metrics.add(e, ""); err != nil {
+				return "", err
+			} else {
+				path = p
+			}
+		}
+		if path == "" {
+			return "", unsupported(orderBy.Expr, "in order by with group by, "+
+				"it must be the innermost group by or an aggregate function")
+		}
+		orders = append(orders, fmt.Sprintf(`{"%v" : "%v"}`, path, orderBy.Direction))
+	}
+	return fmt.Sprintf(`"order" : [%v]`, strings.Join(orders, ", ")), nil
+}
+
+// metricAggs is the metric aggregations of the aggregate functions.
+type metricAggs struct {
+	aggs []string
+	// names is the names of the aggregations keyed by the function, like max(price),
+	// and by the alias names, which are used as the buckets paths in the having.
+	names map[string]string
+}
+
+var nonWordReg = regexp.MustCompile(`\W+`)
+
+// add adds the aggregate function, and returns the buckets path of it.
+func (m *metricAggs) add(funcExpr *sqlparser.FuncExpr, alias string) (string, error) {
+	key := strings.ToLower(sqlparser.String(funcExpr))
+	if path, ok := m.names[key]; ok && alias == "" {
+		return path, nil
+	}
+
+	funcName := funcExpr.Name.Lowered()
+	if len(funcExpr.Exprs) != 1 {
+		return "", fmt.Errorf("elasticsql: the %s must have 1 param", funcName) // nolint:goerr113
+	}
+	if _, ok := funcExpr.Exprs[0].(*sqlparser.StarExpr); ok && funcName == "count" {
+		// count(*) is the doc count of the bucket
+		m.names[key] = "_count"
+		if alias != "" {
+			m.names[alias] = "_count"
+		}
+		return "_count", nil
+	}
+
+	aliased, ok := funcExpr.Exprs[0].(*sqlparser.AliasedExpr)
+	if !ok {
+		return "", unsupported(funcExpr.Exprs[0], "in "+funcName)
+	}
+	colName, ok := aliased.Expr.(*sqlparser.ColName)
+	if !ok {
+		return "", unsupported(aliased.Expr, "in "+funcName+", the param must be a column name")
+	}
+	field := strings.Replace(sqlparser.String(colName), "`", "", -1)
+
+	aggType := funcName
+	switch funcName {
+	case "count":
+		aggType = "value_count"
+		if funcExpr.Distinct {
+			aggType = "cardinality"
+		}
+	case "min", "max", "avg", "sum", "stats", "extended_stats", "percentiles":
+	default:
+		return "", unsupported(funcExpr, "aggregate function")
+	}
+
+	name := alias
+	if name == "" {
+		name = aggType + "_" + nonWordReg.ReplaceAllString(field, "_")
+	}
+	m.aggs = append(m.aggs, fmt.Sprintf(`"%v" : {"%v" : {"field" : "%v"}}`, name, aggType, field))
+	m.names[key] = name
+	m.names[name] = name
+	return name, nil
+}
+
+// buildHaving builds the bucket_selector of the having, like
+// having count(*) > 1 and max(price) < 100 to
+// "having" : {"bucket_selector" : {"buckets_path" : {"v0" : "_count", "v1" : "max_price"},
+// "script" : "params.v0 > 1 && params.v1 < 100"}}.
+func buildHaving(expr sqlparser.Expr, metrics *metricAggs) (string, error) {
+	h := &havingBuilder{metrics: metrics}
+	script, err := h.script(expr)
+	if err != nil {
+		return "", err
+	}
+
+	paths := make([]string, len(h.paths))
+	for i, path := range h.paths {
+		paths[i] = fmt.Sprintf(`"v%d" : "%v"`, i, path)
+	}
+	return fmt.Sprintf(`"having" : {"bucket_selector" : {"buckets_path" : {%v}, "script" : "%v"}}`,
+		strings.Join(paths, ", "), script), nil
+}
+
+type havingBuilder struct {
+	metrics *metricAggs
+	paths   []string
+}
+
+func (h *havingBuilder) script(expr sqlparser.Expr) (string, error) {
+	switch e := expr.(type) {
+	case *sqlparser.AndExpr:
+		return h.binary(e.Left, "&&", e.Right)
+	case *sqlparser.OrExpr:
+		return h.binary(e.Left, "||", e.Right)
+	case *sqlparser.NotExpr:
+		s, err := h.script(e.Expr)
+		return "!(" + s + ")", err
+	case *sqlparser.ParenExpr:
+		s, err := h.script(e.Expr)
+		return "(" + s + ")", err
+	case *sqlparser.ComparisonExpr:
+		switch e.Operator {
+		case sqlparser.EqualStr:
+			return h.binary(e.Left, "==", e.Right)
+		case sqlparser.NotEqualStr:
+			return h.binary(e.Left, "!=", e.Right)
+		case sqlparser.LessThanStr, sqlparser.GreaterThanStr, sqlparser.LessEqualStr, sqlparser.GreaterEqualStr:
+			return h.binary(e.Left, e.Operator, e.Right)
+		default:
+			return "", unsupported(e, "operator "+e.Operator+" in having")
+		}
+	case *sqlparser.FuncExpr:
+		path, err := h.metrics.add(e, "")
+		if err != nil {
+			return "", err
+		}
+		return h.param(path), nil
+	case *sqlparser.ColName:
+		// the alias of the aggregate function in the select
+		path, ok := h.metrics.names[e.Name.String()]
+		if !ok || !e.Qualifier.IsEmpty() {
+			return "", unsupported(e, "in having, the column must be an alias of the aggregate function")
+		}
+		return h.param(path), nil
+	case *sqlparser.SQLVal:
+		if e.Type != sqlparser.IntVal && e.Type != sqlparser.FloatVal {
+			return "", unsupported(e, "in having, the value must be a number")
+		}
+		return string(e.Val), nil
+	default:
+		return "", unsupported(expr, "in having")
+	}
+}
+
+func (h *havingBuilder) binary(left sqlparser.Expr, op string, right sqlparser.Expr) (string, error) {
+	l, err := h.script(left)
+	if err != nil {
+		return "", err
+	}
+	r, err := h.script(right)
+	if err != nil {
+		return "", err
+	}
+	return l + " " + op + " " + r, nil
+}
+
+// param returns the script param of the buckets path.
+func (h *havingBuilder) param(path string) string {
+	for i, p := range h.paths {
+		if p == path {
+			return fmt.Sprintf("params.v%d", i)
+		}
+	}
+	h.paths = append(h.paths, path)
+	return fmt.Sprintf("params.v%d", len(h.paths)-1)
+}
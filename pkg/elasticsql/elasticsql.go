@@ -3,18 +3,21 @@ package elasticsql
 import (
 	"errors"
 	"fmt"
+	"reflect"
 	"strings"
 
 	"github.com/bingoohuang/gg/pkg/sqlparse/sqlparser"
 	"github.com/bingoohuang/gg/pkg/ss"
 )
 
-// Convert will transform sql to elasticsearch dsl string
+// Convert will transform sql to elasticsearch dsl string,
+// the sql can be a full select, or the part from the where clause, like a = 1 group by b having count(*) > 1.
 func Convert(sql string) (dsl string, err error) {
 	switch firstWord := strings.ToLower(ss.FirstWord(sql)); firstWord {
 	case "update", "delete", "insert":
 		return "", errors.New("unsupported")
-	case "limit", "order", "where":
+	case "select":
+	case "limit", "order", "where", "group", "having":
 		sql = "select * from t " + sql
 	default:
 		sql = "select * from t where " + sql
@@ -30,10 +33,20 @@ func Convert(sql string) (dsl string, err error) {
 	case *sqlparser.Select:
 		return handleSelect(t)
 	default:
-		return "", errors.New("unsupported")
+		return "", unsupported(stmt, "statement")
 	}
 }
 
+// unsupported returns the error naming the unsupported AST node, like
+// elasticsql: unsupported NotExpr in where: not id = 1.
+func unsupported(node sqlparser.SQLNode, where string) error {
+	name := fmt.Sprintf("%T", node)
+	if v := reflect.ValueOf(node); v.IsValid() {
+		name = reflect.Indirect(v).Type().Name()
+	}
+	return fmt.Errorf("elasticsql: unsupported %s %s: %s", name, where, sqlparser.String(node)) // nolint:goerr113
+}
+
 func handleSelect(sel *sqlparser.Select) (dsl string, err error) {
 	// Handle where
 	// top level node pass in an empty interface
@@ -56,11 +69,12 @@ func handleSelect(sel *sqlparser.Select) (dsl string, err error) {
 	queryFrom, querySize := "", ""
 
 	// if the request is to aggregation
-	// then set aggFlag to true, and querySize to 0
+	// then set querySize to 0
 	// to not return any query result
-
-	sel.GroupBy = nil
-	sel.SelectExprs = nil
+	aggs, err := buildAggs(sel)
+	if err != nil {
+		return "", err
+	}
 
 	// Handle limit
 	if sel.Limit != nil {
@@ -71,7 +85,7 @@ func handleSelect(sel *sqlparser.Select) (dsl string, err error) {
 	}
 
 	// Handle order by
-	// when executing aggregations, order by is useless
+	// when executing aggregations, the order by is the order of the innermost buckets, see buildAggs
 	var orderByArr []string
 	for _, orderByExpr := range sel.OrderBy {
 		s := strings.Replace(sqlparser.String(orderByExpr.Expr), "`", "", -1)
@@ -88,13 +102,17 @@ func handleSelect(sel *sqlparser.Select) (dsl string, err error) {
 		resultMap["from"] = ss.ParseInt(queryFrom)
 	}
 
-	if len(orderByArr) > 0 {
+	if aggs != "" {
+		resultMap["size"] = 0
+		delete(resultMap, "from")
+		resultMap["aggs"] = aggs
+	} else if len(orderByArr) > 0 {
 		resultMap["sort"] = fmt.Sprintf("[%v]", strings.Join(orderByArr, ","))
 	}
 
 	// keep the traversal in order, avoid unpredicted json
 	var resultArr []string
-	for _, mapKey := range []string{"query", "from", "size", "sort"} {
+	for _, mapKey := range []string{"query", "from", "size", "sort", "aggs"} {
 		if val, ok := resultMap[mapKey]; ok {
 			resultArr = append(resultArr, fmt.Sprintf(`"%v" : %v`, mapKey, val))
 		}
@@ -105,7 +123,7 @@ func handleSelect(sel *sqlparser.Select) (dsl string, err error) {
 }
 
 func buildNestedFuncStrValue(nestedFunc *sqlparser.FuncExpr) (string, error) {
-	return "", errors.New("elasticsql: unsupported function" + nestedFunc.Name.String())
+	return "", unsupported(nestedFunc, "function")
 }
 
 func handleSelectWhereAndExpr(expr *sqlparser.Expr, parent *sqlparser.Expr) (string, error) {
@@ -193,7 +211,7 @@ func buildComparisonExprRightStr(expr sqlparser.Expr) (string, bool, error) {
 	case sqlparser.ValTuple:
 		rightStr = sqlparser.String(expr)
 	default:
-		// cannot reach here
+		return "", false, unsupported(expr, "on the right side of compare operator")
 	}
 	return rightStr, false, err
 }
@@ -203,7 +221,7 @@ func handleSelectWhereComparisonExpr(expr *sqlparser.Expr, topLevel bool, parent
 	colName, ok := comparisonExpr.Left.(*sqlparser.ColName)
 
 	if !ok {
-		return "", errors.New("elasticsql: invalid comparison expression, the left must be a column name")
+		return "", unsupported(comparisonExpr.Left, "on the left side of compare operator, the left must be a column name")
 	}
 
 	colNameStr := sqlparser.String(colName)
@@ -258,6 +276,8 @@ func handleSelectWhereComparisonExpr(expr *sqlparser.Expr, topLevel bool, parent
 		rightStr = strings.Trim(rightStr, "(")
 		rightStr = strings.Trim(rightStr, ")")
 		resultStr = fmt.Sprintf(`{"bool" : {"must_not" : {"terms" : {"%v" : [%v]}}}}`, colNameStr, rightStr)
+	default:
+		return "", unsupported(comparisonExpr, "operator "+comparisonExpr.Operator)
 	}
 
 	// the root node need to have bool and must
@@ -283,7 +303,7 @@ func handleSelectWhere(expr *sqlparser.Expr, topLevel bool, parent *sqlparser.Ex
 		return handleSelectWhereComparisonExpr(expr, topLevel, parent)
 
 	case *sqlparser.IsExpr:
-		return "", errors.New("elasticsql: is expression currently not supported")
+		return handleSelectWhereIsExpr(e, topLevel)
 	case *sqlparser.RangeCond:
 		// between a and b
 		// the meaning is equal to range query
@@ -291,7 +311,7 @@ func handleSelectWhere(expr *sqlparser.Expr, topLevel bool, parent *sqlparser.Ex
 		colName, ok := rangeCond.Left.(*sqlparser.ColName)
 
 		if !ok {
-			return "", errors.New("elasticsql: range column name missing")
+			return "", unsupported(rangeCond.Left, "in between, the left must be a column name")
 		}
 
 		colNameStr := sqlparser.String(colName)
@@ -300,6 +320,9 @@ func handleSelectWhere(expr *sqlparser.Expr, topLevel bool, parent *sqlparser.Ex
 		toStr := strings.Trim(sqlparser.String(rangeCond.To), `'`)
 
 		resultStr := fmt.Sprintf(`{"range" : {"%v" : {"from" : "%v", "to" : "%v"}}}`, colNameStr, fromStr, toStr)
+		if rangeCond.Operator == sqlparser.NotBetweenStr {
+			resultStr = fmt.Sprintf(`{"bool" : {"must_not" : [%v]}}`, resultStr)
+		}
 		if topLevel {
 			resultStr = fmt.Sprintf(`{"bool" : {"must" : [%v]}}`, resultStr)
 		}
@@ -316,10 +339,10 @@ func handleSelectWhere(expr *sqlparser.Expr, topLevel bool, parent *sqlparser.Ex
 			isThisTopLevel = true
 		}
 		return handleSelectWhere(&boolExpr, isThisTopLevel, parent)
-	case *sqlparser.NotExpr:
-		return "", errors.New("elasticsql: not expression currently not supported")
 	case *sqlparser.FuncExpr:
 		switch e.Name.Lowered() {
+		case "nested":
+			return handleSelectWhereNested(e)
 		case "multi_match":
 			params := e.Exprs
 			if len(params) > 3 || len(params) < 2 {
@@ -355,9 +378,66 @@ func handleSelectWhere(expr *sqlparser.Expr, topLevel bool, parent *sqlparser.Ex
 			}
 			return fmt.Sprintf(`{"multi_match" : {"query" : "%v", "type" : "%v", "fields" : [%v]}}`, query, typ, fields), nil
 		default:
-			return "", errors.New("elaticsql: function in where not supported" + e.Name.Lowered())
+			return "", unsupported(e, "function in where")
 		}
 	}
 
-	return "", errors.New("elaticsql: logically cannot reached here")
+	return "", unsupported(*expr, "in where")
+}
+
+// handleSelectWhereIsExpr handles is null and is not null by the exists query.
+func handleSelectWhereIsExpr(isExpr *sqlparser.IsExpr, topLevel bool) (string, error) {
+	colName, ok := isExpr.Expr.(*sqlparser.ColName)
+	if !ok {
+		return "", unsupported(isExpr.Expr, "in is expression, the left must be a column name")
+	}
+
+	colNameStr := strings.Replace(sqlparser.String(colName), "`", "", -1)
+	resultStr := fmt.Sprintf(`{"exists" : {"field" : "%v"}}`, colNameStr)
+	switch isExpr.Operator {
+	case sqlparser.IsNotNullStr:
+	case sqlparser.IsNullStr:
+		resultStr = fmt.Sprintf(`{"bool" : {"must_not" : [%v]}}`, resultStr)
+	default:
+		return "", unsupported(isExpr, "operator "+isExpr.Operator)
+	}
+
+	if topLevel {
+		resultStr = fmt.Sprintf(`{"bool" : {"must" : [%v]}}`, resultStr)
+	}
+	return resultStr, nil
+}
+
+// handleSelectWhereNested handles nested(path, expr) for the nested documents,
+// like nested(comments, comments.author = 'alice' and comments.stars > 3).
+func handleSelectWhereNested(nestedFunc *sqlparser.FuncExpr) (string, error) {
+	if len(nestedFunc.Exprs) != 2 {
+		return "", errors.New("elasticsql: the nested must have 2 params, (path, expression)")
+	}
+
+	var exprs []sqlparser.Expr
+	for _, param := range nestedFunc.Exprs {
+		aliased, ok := param.(*sqlparser.AliasedExpr)
+		if !ok {
+			return "", unsupported(param, "in nested")
+		}
+		exprs = append(exprs, aliased.Expr)
+	}
+
+	var path string
+	switch p := exprs[0].(type) {
+	case *sqlparser.ColName:
+		path = strings.Replace(sqlparser.String(p), "`", "", -1)
+	case *sqlparser.SQLVal:
+		path = string(p.Val)
+	default:
+		return "", unsupported(p, "as the path of nested")
+	}
+
+	var rootParent sqlparser.Expr
+	queryStr, err := handleSelectWhere(&exprs[1], true, &rootParent)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(`{"nested" : {"path" : "%v", "query" : %v}}`, path, queryStr), nil
 }
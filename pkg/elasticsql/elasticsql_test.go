@@ -7,16 +7,16 @@ import (
 )
 
 var selectCaseMap = map[string]string{
-	"process_id= 1":                 `{"query" : {"bool" : {"must" : [{"match" : {"process_id" : {"query" : "1"}}}]}}}`,
-	"(process_id= 1)":               `{"query" : {"bool" : {"must" : [{"match" : {"process_id" : {"query" : "1"}}}]}}}`,
-	"((process_id= 1))":             `{"query" : {"bool" : {"must" : [{"match" : {"process_id" : {"query" : "1"}}}]}}}`,
-	"(process_id = 1 and status=1)": `{"query" : {"bool" : {"must" : [{"match" : {"process_id" : {"query" : "1"}}},{"match" : {"status" : {"query" : "1"}}}]}}}`,
-	"process_id > 1":                `{"query" : {"bool" : {"must" : [{"range" : {"process_id" : {"gt" : "1"}}}]}}}`,
-	"process_id < 1":                `{"query" : {"bool" : {"must" : [{"range" : {"process_id" : {"lt" : "1"}}}]}}}`,
-	"process_id <= 1":               `{"query" : {"bool" : {"must" : [{"range" : {"process_id" : {"to" : "1"}}}]}}}`,
-	"process_id >= '1'":             `{"query" : {"bool" : {"must" : [{"range" : {"process_id" : {"from" : "1"}}}]}}}`,
-	"process_id != 1":               `{"query" : {"bool" : {"must" : [{"bool" : {"must_not" : [{"match" : {"process_id" : {"query" : "1"}}}]}}]}}}`,
-	"process_id = 0 and status= 1 and channel = 4":                        `{"query" : {"bool" : {"must" : [{"match" : {"process_id" : {"query" : "0"}}},{"match" : {"status" : {"query" : "1"}}},{"match" : {"channel" : {"query" : "4"}}}]}}}`,
+	"process_id= 1":                                `{"query" : {"bool" : {"must" : [{"match" : {"process_id" : {"query" : "1"}}}]}}}`,
+	"(process_id= 1)":                              `{"query" : {"bool" : {"must" : [{"match" : {"process_id" : {"query" : "1"}}}]}}}`,
+	"((process_id= 1))":                            `{"query" : {"bool" : {"must" : [{"match" : {"process_id" : {"query" : "1"}}}]}}}`,
+	"(process_id = 1 and status=1)":                `{"query" : {"bool" : {"must" : [{"match" : {"process_id" : {"query" : "1"}}},{"match" : {"status" : {"query" : "1"}}}]}}}`,
+	"process_id > 1":                               `{"query" : {"bool" : {"must" : [{"range" : {"process_id" : {"gt" : "1"}}}]}}}`,
+	"process_id < 1":                               `{"query" : {"bool" : {"must" : [{"range" : {"process_id" : {"lt" : "1"}}}]}}}`,
+	"process_id <= 1":                              `{"query" : {"bool" : {"must" : [{"range" : {"process_id" : {"to" : "1"}}}]}}}`,
+	"process_id >= '1'":                            `{"query" : {"bool" : {"must" : [{"range" : {"process_id" : {"from" : "1"}}}]}}}`,
+	"process_id != 1":                              `{"query" : {"bool" : {"must" : [{"bool" : {"must_not" : [{"match" : {"process_id" : {"query" : "1"}}}]}}]}}}`,
+	"process_id = 0 and status= 1 and channel = 4": `{"query" : {"bool" : {"must" : [{"match" : {"process_id" : {"query" : "0"}}},{"match" : {"status" : {"query" : "1"}}},{"match" : {"channel" : {"query" : "4"}}}]}}}`,
 	"create_time between '2015-01-01 00:00:00' and '2015-01-01 00:00:00'": `{"query" : {"bool" : {"must" : [{"range" : {"create_time" : {"from" : "2015-01-01 00:00:00", "to" : "2015-01-01 00:00:00"}}}]}}}`,
 	"process_id > 1 and status = 1":                                       `{"query" : {"bool" : {"must" : [{"range" : {"process_id" : {"gt" : "1"}}},{"match" : {"status" : {"query" : "1"}}}]}}}`,
 	"create_time between '2015-01-01T00:00:00+0800' and '2017-01-01T00:00:00+0800' and process_id = 0 and status >= 1 and content = '三个男人' and phone = '15810324322'": `{"query" : {"bool" : {"must" : [{"range" : {"create_time" : {"from" : "2015-01-01T00:00:00+0800", "to" : "2017-01-01T00:00:00+0800"}}},{"match" : {"process_id" : {"query" : "0"}}},{"range" : {"status" : {"from" : "1"}}},{"match" : {"content" : {"query" : "三个男人"}}},{"match" : {"phone" : {"query" : "15810324322"}}}]}}}`,
@@ -41,6 +41,21 @@ var selectCaseMap = map[string]string{
 	"multi_match(query='this is a test', fields=(title,title.origin))":                       `{"query" : {"multi_match" : {"query" : "this is a test", "fields" : ["title","title.origin"]}}}`,
 	"a= 1 and multi_match(query='this is a test', fields=(title,title.origin))":              `{"query" : {"bool" : {"must" : [{"match" : {"a" : {"query" : "1"}}},{"multi_match" : {"query" : "this is a test", "fields" : ["title","title.origin"]}}]}}}`,
 	"a= 1 and multi_match(query='this is a test', fields=(title,title.origin), type=phrase)": `{"query" : {"bool" : {"must" : [{"match" : {"a" : {"query" : "1"}}},{"multi_match" : {"query" : "this is a test", "type" : "phrase", "fields" : ["title","title.origin"]}}]}}}`,
+	"id is null":                                `{"query" : {"bool" : {"must" : [{"bool" : {"must_not" : [{"exists" : {"field" : "id"}}]}}]}}}`,
+	"id is not null and a = 1":                  `{"query" : {"bool" : {"must" : [{"exists" : {"field" : "id"}},{"match" : {"a" : {"query" : "1"}}}]}}}`,
+	"id not between 1 and 10":                   `{"query" : {"bool" : {"must" : [{"bool" : {"must_not" : [{"range" : {"id" : {"from" : "1", "to" : "10"}}}]}}]}}}`,
+	"select * from t where id between 1 and 10": `{"query" : {"bool" : {"must" : [{"range" : {"id" : {"from" : "1", "to" : "10"}}}]}}}`,
+	"nested(comments, comments.author = 'alice' and comments.stars > 3)":                                                         `{"query" : {"nested" : {"path" : "comments", "query" : {"bool" : {"must" : [{"match" : {"comments.author" : {"query" : "alice"}}},{"range" : {"comments.stars" : {"gt" : "3"}}}]}}}}}`,
+	"a = 1 and nested('comments', comments.stars is null)":                                                                       `{"query" : {"bool" : {"must" : [{"match" : {"a" : {"query" : "1"}}},{"nested" : {"path" : "comments", "query" : {"bool" : {"must" : [{"bool" : {"must_not" : [{"exists" : {"field" : "comments.stars"}}]}}]}}}}]}}}`,
+	"select count(distinct u), sum(v) as total from t":                                                                           `{"query" : {"bool" : {"must": [{"match_all" : {}}]}},"size" : 0,"aggs" : {"cardinality_u" : {"cardinality" : {"field" : "u"}},"total" : {"sum" : {"field" : "v"}}}}`,
+	"select a, count(*) from t group by a order by count(*) desc, a":                                                             `{"query" : {"bool" : {"must": [{"match_all" : {}}]}},"size" : 0,"aggs" : {"a" : {"terms" : {"field" : "a", "size" : 200, "order" : [{"_count" : "desc"}, {"_key" : "asc"}]}}}}`,
+	"select a, b, max(price) as mp from t group by a, b order by mp desc":                                                        `{"query" : {"bool" : {"must": [{"match_all" : {}}]}},"size" : 0,"aggs" : {"a" : {"terms" : {"field" : "a", "size" : 200}, "aggs" : {"b" : {"terms" : {"field" : "b", "size" : 200, "order" : [{"mp" : "desc"}]}, "aggs" : {"mp" : {"max" : {"field" : "price"}}}}}}}}`,
+	"group by date_histogram(field='b', calendar_interval='1d') order by date_histogram(field='b', calendar_interval='1d') desc": `{"query" : {"bool" : {"must": [{"match_all" : {}}]}},"size" : 0,"aggs" : {"b" : {"date_histogram" : {"field" : "b", "calendar_interval" : "1d", "order" : [{"_key" : "desc"}]}}}}`,
+	"group by a having count(*) > 2":                                                                                             `{"query" : {"bool" : {"must": [{"match_all" : {}}]}},"size" : 0,"aggs" : {"a" : {"terms" : {"field" : "a", "size" : 200}, "aggs" : {"having" : {"bucket_selector" : {"buckets_path" : {"v0" : "_count"}, "script" : "params.v0 > 2"}}}}}}`,
+	"select a, count(*) as cnt, max(price) from t where x = 1 group by a, date_histogram(field='b', calendar_interval='1d') having cnt > 1 and (avg(price) < 10 or max(price) = 0) limit 10": `{"query" : {"bool" : {"must" : [{"match" : {"x" : {"query" : "1"}}}]}},"size" : 0,` +
+		`"aggs" : {"a" : {"terms" : {"field" : "a", "size" : 10}, "aggs" : {"b" : {"date_histogram" : {"field" : "b", "calendar_interval" : "1d"}, "aggs" : {` +
+		`"max_price" : {"max" : {"field" : "price"}},"avg_price" : {"avg" : {"field" : "price"}},` +
+		`"having" : {"bucket_selector" : {"buckets_path" : {"v0" : "_count", "v1" : "avg_price", "v2" : "max_price"}, "script" : "params.v0 > 1 && (params.v1 < 10 || params.v2 == 0)"}}}}}}}}`,
 }
 
 func TestSupported(t *testing.T) {
@@ -73,7 +88,10 @@ var unsupportedCaseList = []string{
 	"select * from ak where NOT(id=1)",
 	"select * from ak where 1 = 1",
 	"1=a",
-	"id is null",
+	"id is true",
+	"select a from t having count(*) > 1",
+	"group by a having a > 1",
+	"group by lower(a)",
 	" a= 1 and multi_match(zz=1, query='this is a test', fields=(title,title.origin), type=phrase)",
 	"zz(k=2)",
 }
@@ -101,3 +119,22 @@ func TestBadSQL(t *testing.T) {
 		}
 	}
 }
+
+func TestUnsupportedNode(t *testing.T) {
+	cases := map[string]string{
+		"not id = 1":                            "elasticsql: unsupported NotExpr in where: not id = 1",
+		"a regexp 'x'":                          "elasticsql: unsupported ComparisonExpr operator regexp: a regexp 'x'",
+		"1 = a":                                 "elasticsql: unsupported SQLVal on the left side of compare operator, the left must be a column name: 1",
+		"select now(a) from t":                  "elasticsql: unsupported FuncExpr aggregate function: now(a)",
+		"group by a having a > 1":               "elasticsql: unsupported ColName in having, the column must be an alias of the aggregate function: a",
+		"select * from t union select * from t": "elasticsql: unsupported Union statement: select * from t union select * from t",
+		"group by a, b order by a":              "elasticsql: unsupported ColName in order by with group by, it must be the innermost group by or an aggregate function: a",
+		"select max(a) from t order by b":       "elasticsql: unsupported Order in order by without group by: b",
+	}
+	for sql, want := range cases {
+		_, err := Convert(sql)
+		if err == nil || err.Error() != want {
+			t.Errorf("Convert(%q) error = %v, want %s", sql, err, want)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package badgerkv
+
+import (
+	"context"
+	"time"
+
+	"github.com/bingoohuang/gg/pkg/badgerdb"
+	"github.com/bingoohuang/gg/pkg/gokv"
+)
+
+type Config struct {
+	// DB is the opened badger db, or it will be opened with Path/InMemory.
+	DB       *badgerdb.Badger
+	Path     string
+	InMemory bool
+
+	// TTL is the time to live of the keys, 0 for never expired.
+	TTL time.Duration
+}
+
+// Client is a gokv.Store implementation on badgerdb.
+type Client struct {
+	Config
+
+	watchers gokv.Watchers
+}
+
+var (
+	_ gokv.Store   = (*Client)(nil)
+	_ gokv.Watcher = (*Client)(nil)
+)
+
+func NewClient(c Config) (*Client, error) {
+	if c.DB == nil {
+		db, err := badgerdb.Open(badgerdb.WithPath(c.Path), badgerdb.WithInMemory(c.InMemory))
+		if err != nil {
+			return nil, err
+		}
+		c.DB = db
+	}
+
+	return &Client{Config: c}, nil
+}
+
+// Close closes the badger db.
+func (c *Client) Close() error { return c.DB.Close() }
+
+// All list the keys in the store.
+func (c *Client) All() (map[string]string, error) {
+	kvs := make(map[string]string)
+	err := c.DB.Walk(func(k, v []byte) error {
+		kvs[string(k)] = string(v)
+		return nil
+	})
+
+	return kvs, err
+}
+
+// Set stores the given value for the given key.
+func (c *Client) Set(k, v string) error {
+	if err := c.DB.Set([]byte(k), []byte(v), badgerdb.WithTTL(c.TTL)); err != nil {
+		return err
+	}
+
+	c.watchers.Notify(gokv.Event{Type: gokv.EventSet, Key: k, Value: v})
+	return nil
+}
+
+// Get retrieves the value for the given key, empty for not found.
+func (c *Client) Get(k string) (string, error) {
+	v, err := c.DB.Get([]byte(k))
+	return string(v), err
+}
+
+// Del deletes the stored value for the given key.
+// Deleting a non-existing key-value pair does NOT lead to an error.
+func (c *Client) Del(k string) error {
+	if err := c.DB.Del([]byte(k)); err != nil {
+		return err
+	}
+
+	c.watchers.Notify(gokv.Event{Type: gokv.EventDel, Key: k})
+	return nil
+}
+
+// Watch returns the channel of the change events of the keys with the prefix.
+func (c *Client) Watch(ctx context.Context, prefix string) (<-chan gokv.Event, error) {
+	return c.watchers.Watch(ctx, prefix)
+}
@@ -0,0 +1,242 @@
+package filekv
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bingoohuang/gg/pkg/gokv"
+	"github.com/bingoohuang/gg/pkg/yaml"
+)
+
+type Config struct {
+	// Path is the file path, like kv.json or kv.yaml.
+	Path string
+	// Format is json or yaml, default by the extension of the path.
+	Format string
+	// ReloadInterval will Reload the file changed by others in every interval, 0 to disable.
+	ReloadInterval time.Duration
+}
+
+// Client is a gokv.Store implementation on a JSON/YAML file of string key values.
+type Client struct {
+	Config
+
+	kvs      map[string]string
+	modTime  time.Time
+	lock     sync.Mutex
+	watchers gokv.Watchers
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+var (
+	_ gokv.Store   = (*Client)(nil)
+	_ gokv.Watcher = (*Client)(nil)
+)
+
+func NewClient(c Config) (*Client, error) {
+	if c.Format == "" {
+		c.Format = "json"
+		if ext := strings.ToLower(filepath.Ext(c.Path)); ext == ".yaml" || ext == ".yml" {
+			c.Format = "yaml"
+		}
+	}
+
+	cli := &Client{Config: c, kvs: make(map[string]string), stop: make(chan struct{})}
+	if _, err := cli.Reload(); err != nil {
+		return nil, err
+	}
+
+	if c.ReloadInterval > 0 {
+		go cli.tickerReload()
+	}
+
+	return cli, nil
+}
+
+// Close stops the reloading.
+func (c *Client) Close() error {
+	c.stopOnce.Do(func() { close(c.stop) })
+	return nil
+}
+
+func (c *Client) tickerReload() {
+	ticker := time.NewTicker(c.ReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			if _, err := c.Reload(); err != nil {
+				log.Printf("W! reload error %v", err)
+			}
+		}
+	}
+}
+
+// Reload reloads the file when it is modified, and notifies the changes to the watchers.
+func (c *Client) Reload() (reloaded bool, err error) {
+	c.lock.Lock()
+	stat, err := os.Stat(c.Path)
+	if os.IsNotExist(err) || err == nil && stat.ModTime().Equal(c.modTime) {
+		c.lock.Unlock()
+		return false, nil
+	} else if err != nil {
+		c.lock.Unlock()
+		return false, err
+	}
+
+	kvs, err := c.read()
+	if err != nil {
+		c.lock.Unlock()
+		return false, err
+	}
+
+	var events []gokv.Event
+	for k, v := range kvs {
+		if old, ok := c.kvs[k]; !ok || old != v {
+			events = append(events, gokv.Event{Type: gokv.EventSet, Key: k, Value: v})
+		}
+	}
+	for k, v := range c.kvs {
+		if _, ok := kvs[k]; !ok {
+			events = append(events, gokv.Event{Type: gokv.EventDel, Key: k, Value: v})
+		}
+	}
+
+	c.kvs, c.modTime = kvs, stat.ModTime()
+	c.lock.Unlock()
+
+	for _, e := range events {
+		c.watchers.Notify(e)
+	}
+
+	return true, nil
+}
+
+func (c *Client) read() (map[string]string, error) {
+	data, err := os.ReadFile(c.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	kvs := make(map[string]string)
+	if len(data) == 0 {
+		return kvs, nil
+	}
+
+	if c.Format == "yaml" {
+		err = yaml.Unmarshal(data, &kvs)
+	} else {
+		err = json.Unmarshal(data, &kvs)
+	}
+
+	return kvs, err
+}
+
+// save writes the key values to a temporary file and renames it to the path, in case of partial writes.
+func (c *Client) save() error {
+	var data []byte
+	var err error
+	if c.Format == "yaml" {
+		data, err = yaml.Marshal(c.kvs)
+	} else {
+		data, err = json.MarshalIndent(c.kvs, "", "  ")
+	}
+	if err != nil {
+		return err
+	}
+
+	tmp := c.Path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, c.Path); err != nil {
+		return err
+	}
+
+	if stat, err := os.Stat(c.Path); err == nil {
+		c.modTime = stat.ModTime()
+	}
+
+	return nil
+}
+
+// All list the keys in the store.
+func (c *Client) All() (map[string]string, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	kvs := make(map[string]string, len(c.kvs))
+	for k, v := range c.kvs {
+		kvs[k] = v
+	}
+
+	return kvs, nil
+}
+
+// Set stores the given value for the given key.
+func (c *Client) Set(k, v string) error {
+	c.lock.Lock()
+	old, ok := c.kvs[k]
+	c.kvs[k] = v
+	err := c.save()
+	if err != nil {
+		if ok {
+			c.kvs[k] = old
+		} else {
+			delete(c.kvs, k)
+		}
+	}
+	c.lock.Unlock()
+
+	if err == nil {
+		c.watchers.Notify(gokv.Event{Type: gokv.EventSet, Key: k, Value: v})
+	}
+	return err
+}
+
+// Get retrieves the value for the given key, empty for not found.
+func (c *Client) Get(k string) (string, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.kvs[k], nil
+}
+
+// Del deletes the stored value for the given key.
+// Deleting a non-existing key-value pair does NOT lead to an error.
+func (c *Client) Del(k string) error {
+	c.lock.Lock()
+	old, ok := c.kvs[k]
+	if !ok {
+		c.lock.Unlock()
+		return nil
+	}
+
+	delete(c.kvs, k)
+	err := c.save()
+	if err != nil {
+		c.kvs[k] = old
+	}
+	c.lock.Unlock()
+
+	if err == nil {
+		c.watchers.Notify(gokv.Event{Type: gokv.EventDel, Key: k, Value: old})
+	}
+	return err
+}
+
+// Watch returns the channel of the change events of the keys with the prefix,
+// including the ones changed in the file by others when ReloadInterval is set.
+func (c *Client) Watch(ctx context.Context, prefix string) (<-chan gokv.Event, error) {
+	return c.watchers.Watch(ctx, prefix)
+}
@@ -0,0 +1,104 @@
+package memkv
+
+import (
+	"context"
+	"time"
+
+	"github.com/bingoohuang/gg/pkg/gokv"
+	"github.com/bingoohuang/gg/pkg/ttlcache"
+)
+
+type Config struct {
+	// TTL is the default time to live of the keys, 0 for never expired.
+	TTL time.Duration
+	// Capacity is the max number of the keys, 0 for unlimited, the least recently used ones are evicted.
+	Capacity uint64
+}
+
+// Client is an in-process gokv.Store implementation on ttlcache with per-key expiry.
+type Client struct {
+	Config
+
+	cache         *ttlcache.Cache[string, string]
+	watchers      gokv.Watchers
+	stopEvictions func()
+}
+
+var (
+	_ gokv.Store   = (*Client)(nil)
+	_ gokv.Watcher = (*Client)(nil)
+)
+
+func NewClient(c Config) *Client {
+	cache := ttlcache.New[string, string](
+		ttlcache.WithTTL[string, string](c.TTL),
+		ttlcache.WithCapacity[string, string](c.Capacity),
+		ttlcache.WithDisableTouchOnHit[string, string](),
+	)
+
+	cli := &Client{Config: c, cache: cache}
+	cli.stopEvictions = cache.OnEviction(func(_ context.Context, r ttlcache.EvictionReason, item *ttlcache.Item[string, string]) {
+		if r != ttlcache.EvictionReasonDeleted { // the deleted ones are notified by Del
+			cli.watchers.Notify(gokv.Event{Type: gokv.EventDel, Key: item.Key(), Value: item.Value()})
+		}
+	})
+
+	go cache.Start()
+
+	return cli
+}
+
+// Close stops the expiring of the keys.
+func (c *Client) Close() error {
+	c.cache.Stop()
+	c.stopEvictions()
+	return nil
+}
+
+// All list the keys in the store.
+func (c *Client) All() (map[string]string, error) {
+	kvs := make(map[string]string)
+	for k, item := range c.cache.Items() {
+		if !item.IsExpired() {
+			kvs[k] = item.Value()
+		}
+	}
+
+	return kvs, nil
+}
+
+// Set stores the given value for the given key with the default TTL.
+func (c *Client) Set(k, v string) error {
+	return c.SetTTL(k, v, ttlcache.DefaultTTL)
+}
+
+// SetTTL stores the given value for the given key with the ttl,
+// ttlcache.DefaultTTL for the default TTL, or ttlcache.NoTTL for never expired.
+func (c *Client) SetTTL(k, v string, ttl time.Duration) error {
+	c.cache.Set(k, v, ttl)
+	c.watchers.Notify(gokv.Event{Type: gokv.EventSet, Key: k, Value: v})
+	return nil
+}
+
+// Get retrieves the value for the given key, empty for not found or expired.
+func (c *Client) Get(k string) (string, error) {
+	if item := c.cache.Get(k); item != nil && !item.IsExpired() {
+		return item.Value(), nil
+	}
+
+	return "", nil
+}
+
+// Del deletes the stored value for the given key.
+// Deleting a non-existing key-value pair does NOT lead to an error.
+func (c *Client) Del(k string) error {
+	c.cache.Delete(k)
+	c.watchers.Notify(gokv.Event{Type: gokv.EventDel, Key: k})
+	return nil
+}
+
+// Watch returns the channel of the change events of the keys with the prefix,
+// including the expired and evicted ones.
+func (c *Client) Watch(ctx context.Context, prefix string) (<-chan gokv.Event, error) {
+	return c.watchers.Watch(ctx, prefix)
+}
@@ -0,0 +1,166 @@
+package gokv_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bingoohuang/gg/pkg/gokv"
+	"github.com/bingoohuang/gg/pkg/gokv/badgerkv"
+	"github.com/bingoohuang/gg/pkg/gokv/filekv"
+	"github.com/bingoohuang/gg/pkg/gokv/memkv"
+	"github.com/stretchr/testify/assert"
+)
+
+type watchStore interface {
+	gokv.Store
+	gokv.Watcher
+}
+
+func TestStores(t *testing.T) {
+	bkv, err := badgerkv.NewClient(badgerkv.Config{InMemory: true})
+	assert.Nil(t, err)
+	defer bkv.Close()
+
+	mkv := memkv.NewClient(memkv.Config{})
+	defer mkv.Close()
+
+	jkv, err := filekv.NewClient(filekv.Config{Path: filepath.Join(t.TempDir(), "kv.json")})
+	assert.Nil(t, err)
+	ykv, err := filekv.NewClient(filekv.Config{Path: filepath.Join(t.TempDir(), "kv.yaml")})
+	assert.Nil(t, err)
+
+	for name, store := range map[string]watchStore{"badger": bkv, "mem": mkv, "json": jkv, "yaml": ykv} {
+		t.Run(name, func(t *testing.T) { testStore(t, store) })
+	}
+}
+
+func testStore(t *testing.T, store watchStore) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := store.Watch(ctx, "app.")
+	assert.Nil(t, err)
+
+	assert.Nil(t, store.Set("app.name", "gokv"))
+	assert.Nil(t, store.Set("other", "x"))
+	assert.Nil(t, store.Set("app.name", "gokv2"))
+
+	v, err := store.Get("app.name")
+	assert.Nil(t, err)
+	assert.Equal(t, "gokv2", v)
+
+	v, err = store.Get("missing")
+	assert.Nil(t, err)
+	assert.Equal(t, "", v)
+
+	all, err := store.All()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"app.name": "gokv2", "other": "x"}, all)
+
+	assert.Nil(t, store.Del("app.name"))
+	assert.Nil(t, store.Del("missing"))
+
+	assert.Equal(t, gokv.Event{Type: gokv.EventSet, Key: "app.name", Value: "gokv"}, <-ch)
+	assert.Equal(t, gokv.Event{Type: gokv.EventSet, Key: "app.name", Value: "gokv2"}, <-ch)
+	e := <-ch
+	assert.Equal(t, gokv.EventDel, e.Type)
+	assert.Equal(t, "app.name", e.Key)
+
+	cancel()
+	_, ok := <-ch
+	assert.False(t, ok)
+}
+
+func TestMemExpiry(t *testing.T) {
+	store := memkv.NewClient(memkv.Config{TTL: 50 * time.Millisecond})
+	defer store.Close()
+
+	ch, _ := store.Watch(context.Background(), "")
+	assert.Nil(t, store.Set("k", "v"))
+	assert.Equal(t, gokv.EventSet, (<-ch).Type)
+
+	select {
+	case e := <-ch:
+		assert.Equal(t, gokv.Event{Type: gokv.EventDel, Key: "k", Value: "v"}, e)
+	case <-time.After(time.Second):
+		t.Fatal("no expired event")
+	}
+
+	v, _ := store.Get("k")
+	assert.Equal(t, "", v)
+}
+
+func TestFileReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kv.json")
+	assert.Nil(t, os.WriteFile(path, []byte(`{"a":"1","b":"2"}`), 0o644))
+
+	store, err := filekv.NewClient(filekv.Config{Path: path})
+	assert.Nil(t, err)
+	defer store.Close()
+
+	ch, _ := store.Watch(context.Background(), "")
+	assert.Nil(t, os.WriteFile(path, []byte(`{"a":"1","c":"3"}`), 0o644))
+	assert.Nil(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Second)))
+
+	reloaded, err := store.Reload()
+	assert.Nil(t, err)
+	assert.True(t, reloaded)
+
+	events := map[string]gokv.Event{}
+	for i := 0; i < 2; i++ {
+		e := <-ch
+		events[e.Key] = e
+	}
+	assert.Equal(t, gokv.Event{Type: gokv.EventSet, Key: "c", Value: "3"}, events["c"])
+	assert.Equal(t, gokv.Event{Type: gokv.EventDel, Key: "b", Value: "2"}, events["b"])
+}
+
+func TestWatchersOverflow(t *testing.T) {
+	var w gokv.Watchers
+	ch, _ := w.Watch(context.Background(), "k")
+
+	for i := 0; i < 100; i++ {
+		w.Notify(gokv.Event{Type: gokv.EventSet, Key: "k", Value: "v"})
+	}
+	for i := 0; i < 64; i++ {
+		assert.Equal(t, gokv.EventSet, (<-ch).Type)
+	}
+	assert.Equal(t, gokv.Event{Type: gokv.EventOverflow, Key: "k"}, <-ch)
+
+	// the events are delivered again after the watcher catches up
+	w.Notify(gokv.Event{Type: gokv.EventDel, Key: "k"})
+	assert.Equal(t, gokv.Event{Type: gokv.EventDel, Key: "k"}, <-ch)
+}
+
+func TestWatcherWritesStore(t *testing.T) {
+	store := memkv.NewClient(memkv.Config{})
+	defer store.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, _ := store.Watch(ctx, "")
+	go func() {
+		for e := range ch {
+			if e.Type == gokv.EventSet && !strings.HasPrefix(e.Key, "echo.") {
+				_ = store.Set("echo."+e.Key, e.Value)
+			}
+		}
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 200; i++ {
+			_ = store.Set(fmt.Sprintf("k%d", i), "v")
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the store is stalled by the watcher")
+	}
+}
@@ -0,0 +1,113 @@
+package gokv
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// EventType is the type of the key change event.
+type EventType int
+
+const (
+	// EventSet is the event of a key set.
+	EventSet EventType = iota
+	// EventDel is the event of a key deleted or expired.
+	EventDel
+	// EventOverflow is the event that the following events are dropped because the watcher is too slow,
+	// the Key is the watching prefix, and the watcher should reload the keys of the prefix.
+	EventOverflow
+)
+
+func (e EventType) String() string {
+	switch e {
+	case EventSet:
+		return "EventSet"
+	case EventDel:
+		return "EventDel"
+	case EventOverflow:
+		return "EventOverflow"
+	}
+
+	return "Unknown"
+}
+
+// Event is the change event of a key.
+type Event struct {
+	Type  EventType
+	Key   string
+	Value string
+}
+
+// Watcher is the optional interface of Store to watch the changes.
+type Watcher interface {
+	// Watch returns the channel of the change events of the keys with the prefix,
+	// the channel is closed when the ctx is done.
+	Watch(ctx context.Context, prefix string) (<-chan Event, error)
+}
+
+// watchBuffer is the size of the buffered events of a watcher.
+const watchBuffer = 64
+
+type subscriber struct {
+	prefix string
+	// ch has one more slot than watchBuffer, reserved for the EventOverflow.
+	ch         chan Event
+	overflowed bool
+}
+
+// Watchers helps the Store implementations to implement Watcher.
+// The zero value is ready to use.
+type Watchers struct {
+	lock sync.Mutex
+	subs map[*subscriber]struct{}
+}
+
+// Watch returns the channel of the change events of the keys with the prefix,
+// the channel is closed when the ctx is done.
+func (w *Watchers) Watch(ctx context.Context, prefix string) (<-chan Event, error) {
+	s := &subscriber{prefix: prefix, ch: make(chan Event, watchBuffer+1)}
+
+	w.lock.Lock()
+	if w.subs == nil {
+		w.subs = make(map[*subscriber]struct{})
+	}
+	w.subs[s] = struct{}{}
+	w.lock.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		w.lock.Lock()
+		delete(w.subs, s)
+		close(s.ch)
+		w.lock.Unlock()
+	}()
+
+	return s.ch, nil
+}
+
+// Notify sends the event to the watching channels of the matched prefixes.
+// It never blocks, so a slow watcher does not stall the store, and the watcher can write the store itself.
+// When the buffer of a watcher is full, the events are dropped after an EventOverflow,
+// until the watcher catches up.
+func (w *Watchers) Notify(e Event) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	for s := range w.subs {
+		if !strings.HasPrefix(e.Key, s.prefix) {
+			continue
+		}
+
+		switch {
+		case len(s.ch) < watchBuffer:
+			s.overflowed = false
+			s.ch <- e
+		case !s.overflowed:
+			// the reserved slot is free, because only Notify sends under the lock.
+			s.overflowed = true
+			s.ch <- Event{Type: EventOverflow, Key: s.prefix}
+		}
+	}
+}
@@ -51,6 +51,7 @@ type API interface {
 	RegisterExtension(extension Extension)
 	DecoderOf(typ reflect2.Type) ValDecoder
 	EncoderOf(typ reflect2.Type) ValEncoder
+	SchemaOf(v interface{}) *Schema
 
 	RegisterTypeEncoder(typ string, encoder ValEncoder)
 	RegisterTypeDecoder(typ string, decoder ValDecoder)
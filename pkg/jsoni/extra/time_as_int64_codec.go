@@ -28,6 +28,11 @@ func (codec *timeAsInt64Codec) IsEmpty(_ context.Context, ptr unsafe.Pointer, _
 	return ts.UnixNano() == 0
 }
 
+// JSONSchema implements jsoni.SchemaDescriber.
+func (codec *timeAsInt64Codec) JSONSchema() *jsoni.Schema {
+	return &jsoni.Schema{Type: jsoni.SchemaType{"integer"}}
+}
+
 func (codec *timeAsInt64Codec) Encode(_ context.Context, ptr unsafe.Pointer, stream *jsoni.Stream) {
 	ts := *((*time.Time)(ptr))
 	stream.WriteInt64(ts.UnixNano() / codec.precision.Nanoseconds())
@@ -30,3 +30,12 @@ func Test_time_as_int64_keep_microsecond(t *testing.T) {
 	should.Nil(jsoni.Unmarshal(output, &val))
 	should.Equal(int64(1000001000), val.UnixNano())
 }
+
+func Test_time_as_int64_schema(t *testing.T) {
+	should := require.New(t)
+	RegisterTimeAsInt64Codec(time.Nanosecond)
+	s := jsoni.SchemaOf(struct {
+		T time.Time `json:"t"`
+	}{})
+	should.Equal(jsoni.SchemaType{"integer"}, s.Properties["t"].Type)
+}
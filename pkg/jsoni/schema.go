@@ -0,0 +1,362 @@
+package jsoni
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"time"
+
+	"github.com/modern-go/reflect2"
+)
+
+// SchemaDraft is the JSON Schema dialect generated by SchemaOf.
+const SchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is a JSON Schema (draft 2020-12), with the keywords needed to describe the JSON encoded by jsoni.
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Ref                  string             `json:"$ref,omitempty"`
+	Defs                 map[string]*Schema `json:"$defs,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 SchemaType         `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	ContentEncoding      string             `json:"contentEncoding,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	Not                  *Schema            `json:"not,omitempty"`
+}
+
+// SchemaType is the type keyword of Schema, a single type or a list of types.
+type SchemaType []string
+
+// MarshalJSON marshals the single type as a string.
+func (t SchemaType) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+// UnmarshalJSON unmarshals a string or a list of strings.
+func (t *SchemaType) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*t = SchemaType{s}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(t))
+}
+
+// SchemaDescriber describes the JSON Schema of the JSON it is encoded into.
+// It is implemented by the types with the custom marshaling, or by the registered ValEncoder
+// (e.g. extra.RegisterTimeAsInt64Codec), otherwise SchemaOf describes them as any value.
+type SchemaDescriber interface {
+	JSONSchema() *Schema
+}
+
+var (
+	schemaDescriberType = PtrElem((*SchemaDescriber)(nil))
+	timeType            = PtrElem((*time.Time)(nil))
+)
+
+// SchemaOf generates the JSON Schema of the JSON encoded from v by the ConfigDefault.
+func SchemaOf(v interface{}) *Schema { return ConfigDefault.SchemaOf(v) }
+
+// SchemaOf generates the JSON Schema of the JSON encoded from v by the config,
+// honouring the TagKey, OmitEmptyStructField, Int64AsString, NilAsEmpty, the naming strategies
+// and the registered type encoders. The named structs are put in $defs.
+func (c *frozenConfig) SchemaOf(v interface{}) *Schema {
+	g := &schemaGenerator{
+		ctx: &ctx{
+			frozenConfig: c,
+			decoders:     map[reflect2.Type]ValDecoder{},
+			encoders:     map[reflect2.Type]ValEncoder{},
+		},
+		defs:  map[string]*Schema{},
+		names: map[reflect2.Type]string{},
+		types: map[string]reflect2.Type{},
+	}
+
+	root := Schema{}
+	if v != nil {
+		typ := reflect2.TypeOf(v)
+		for typ.Kind() == reflect.Ptr {
+			typ = typ.(reflect2.PtrType).Elem()
+		}
+		root = *g.typeSchema(typ, false)
+	}
+	root.Schema = SchemaDraft
+	if len(g.defs) > 0 {
+		root.Defs = g.defs
+	}
+	return &root
+}
+
+type schemaGenerator struct {
+	ctx   *ctx
+	defs  map[string]*Schema
+	names map[reflect2.Type]string
+	types map[string]reflect2.Type
+}
+
+func newTypeSchema(types ...string) *Schema { return &Schema{Type: types} }
+
+func intPtr(v int) *int { return &v }
+
+func floatPtr(v float64) *float64 { return &v }
+
+// nullable allows null besides the schema s.
+func nullable(s *Schema) *Schema {
+	switch {
+	case s.Ref == "" && len(s.AnyOf) == 0 && len(s.Type) == 0 && s.Not == nil:
+		return s // any value, including null
+	case s.Ref == "" && len(s.AnyOf) == 0 && len(s.Type) > 0:
+		for _, t := range s.Type {
+			if t == "null" {
+				return s
+			}
+		}
+		n := *s
+		n.Type = append(append(SchemaType{}, s.Type...), "null")
+		return &n
+	default:
+		return &Schema{AnyOf: []*Schema{s, newTypeSchema("null")}}
+	}
+}
+
+// describe returns the schema described by the SchemaDescriber, or an empty schema for any value.
+func describe(v interface{}) *Schema {
+	if d, ok := v.(SchemaDescriber); ok {
+		if s := d.JSONSchema(); s != nil {
+			return s
+		}
+	}
+	return &Schema{}
+}
+
+func (g *schemaGenerator) typeSchema(typ reflect2.Type, nilAsEmpty bool) *Schema {
+	rt := typ.Type1()
+	if reflect.PtrTo(rt).Implements(schemaDescriberType.Type1()) {
+		return describe(reflect.New(rt).Interface())
+	}
+	if e := g.ctx.frozenConfig.extensions.createEncoder(typ); e != nil {
+		return describe(e)
+	}
+	if e := g.ctx.frozenConfig.typeEncoders[typ.String()]; e != nil {
+		return describe(e)
+	}
+
+	switch typ {
+	case timeType:
+		return &Schema{Type: SchemaType{"string"}, Format: "date-time"}
+	case jsonNumberType, jsoniNumberType:
+		return newTypeSchema("number")
+	case jsonRawMessageType, jsoniRawMessageType, anyType:
+		return &Schema{}
+	}
+
+	if typ.Kind() == reflect.Ptr {
+		return nullable(g.typeSchema(typ.(reflect2.PtrType).Elem(), nilAsEmpty))
+	}
+
+	ptrType := reflect2.PtrTo(typ)
+	if typ.Implements(marshalerType) || ptrType.Implements(marshalerType) ||
+		typ.Implements(marshalerContextType) || ptrType.Implements(marshalerContextType) {
+		return &Schema{}
+	}
+	if typ.Implements(textMarshalerType) || ptrType.Implements(textMarshalerType) {
+		return newTypeSchema("string")
+	}
+
+	switch kind := typ.Kind(); kind {
+	case reflect.String:
+		return newTypeSchema("string")
+	case reflect.Bool:
+		return newTypeSchema("boolean")
+	case reflect.Float32, reflect.Float64:
+		return newTypeSchema("number")
+	case reflect.Int64, reflect.Uint64:
+		if g.ctx.int64AsString {
+			return int64StringSchema(kind)
+		}
+		return intSchema(kind)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uintptr:
+		return intSchema(kind)
+	case reflect.Interface:
+		return &Schema{}
+	case reflect.Slice:
+		elem := typ.(reflect2.SliceType).Elem()
+		if elem.Kind() == reflect.Uint8 {
+			return nullable(&Schema{Type: SchemaType{"string"}, ContentEncoding: "base64"})
+		}
+		s := &Schema{Type: SchemaType{"array"}, Items: g.typeSchema(elem, false)}
+		if nilAsEmpty || g.ctx.nilAsEmpty {
+			return s
+		}
+		return nullable(s)
+	case reflect.Array:
+		arrayType := typ.(reflect2.ArrayType)
+		return &Schema{
+			Type:     SchemaType{"array"},
+			Items:    g.typeSchema(arrayType.Elem(), false),
+			MinItems: intPtr(arrayType.Len()),
+			MaxItems: intPtr(arrayType.Len()),
+		}
+	case reflect.Map:
+		elem := typ.(reflect2.MapType).Elem()
+		return nullable(&Schema{Type: SchemaType{"object"}, AdditionalProperties: g.typeSchema(elem, false)})
+	case reflect.Struct:
+		return g.structSchema(typ)
+	default:
+		return &Schema{}
+	}
+}
+
+func intSchema(kind reflect.Kind) *Schema {
+	s := newTypeSchema("integer")
+	switch kind {
+	case reflect.Int8:
+		s.Minimum, s.Maximum = floatPtr(-1<<7), floatPtr(1<<7-1)
+	case reflect.Int16:
+		s.Minimum, s.Maximum = floatPtr(-1<<15), floatPtr(1<<15-1)
+	case reflect.Int32:
+		s.Minimum, s.Maximum = floatPtr(-1<<31), floatPtr(1<<31-1)
+	case reflect.Uint8:
+		s.Minimum, s.Maximum = floatPtr(0), floatPtr(1<<8-1)
+	case reflect.Uint16:
+		s.Minimum, s.Maximum = floatPtr(0), floatPtr(1<<16-1)
+	case reflect.Uint32:
+		s.Minimum, s.Maximum = floatPtr(0), floatPtr(1<<32-1)
+	case reflect.Uint, reflect.Uint64, reflect.Uintptr:
+		s.Minimum = floatPtr(0)
+	}
+	return s
+}
+
+func int64StringSchema(kind reflect.Kind) *Schema {
+	if kind == reflect.Uint64 {
+		return &Schema{Type: SchemaType{"string"}, Pattern: `^[0-9]+$`}
+	}
+	return &Schema{Type: SchemaType{"string"}, Pattern: `^-?[0-9]+$`}
+}
+
+var reDefName = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// defName returns the $defs name of the named struct, or "" for the anonymous one.
+func (g *schemaGenerator) defName(typ reflect2.Type) string {
+	if name, ok := g.names[typ]; ok {
+		return name
+	}
+
+	name := typ.Type1().Name()
+	if name == "" {
+		return ""
+	}
+	name = reDefName.ReplaceAllString(name, "_")
+	if t, ok := g.types[name]; ok && t != typ {
+		name = reDefName.ReplaceAllString(typ.String(), "_")
+	}
+	g.names[typ] = name
+	g.types[name] = typ
+	return name
+}
+
+func (g *schemaGenerator) structSchema(typ reflect2.Type) *Schema {
+	name := g.defName(typ)
+	if name != "" {
+		ref := &Schema{Ref: "#/$defs/" + name}
+		if _, ok := g.defs[name]; ok {
+			return ref
+		}
+		g.defs[name] = &Schema{} // placeholder for the recursive types
+		g.defs[name] = g.objectSchema(typ)
+		return ref
+	}
+
+	return g.objectSchema(typ)
+}
+
+// objectSchema describes the struct by the fields of its encoder,
+// so the naming strategies and the conflicts resolution are the same as encoding.
+func (g *schemaGenerator) objectSchema(typ reflect2.Type) *Schema {
+	s := &Schema{Type: SchemaType{"object"}, Properties: map[string]*Schema{}}
+	if g.ctx.disallowUnknownFields {
+		s.AdditionalProperties = &Schema{Not: &Schema{}}
+	}
+
+	encoder, ok := encoderOfStruct(g.ctx, typ).(*structEncoder)
+	if !ok {
+		return s
+	}
+
+	for _, f := range encoder.fields {
+		// the fields of the embedded structs are wrapped by the encoders of the embedding fields
+		owner, leaf, viaPtr := typ, f.encoder, false
+	unwrap:
+		for {
+			switch e := leaf.fieldEncoder.(type) {
+			case *structFieldEncoder:
+				owner, leaf = leaf.field.Type(), e
+			case *dereferenceEncoder:
+				fe, ok := e.ValueEncoder.(*structFieldEncoder)
+				if !ok {
+					break unwrap
+				}
+				owner, leaf, viaPtr = leaf.field.Type().(reflect2.PtrType).Elem(), fe, true
+			default:
+				break unwrap
+			}
+		}
+
+		fs := g.fieldSchema(owner, leaf)
+		if viaPtr {
+			fs = nullable(fs)
+		}
+		s.Properties[f.toName] = fs
+		if !f.encoder.omitempty {
+			s.Required = append(s.Required, f.toName)
+		}
+	}
+
+	return s
+}
+
+func (g *schemaGenerator) fieldSchema(owner reflect2.Type, f *structFieldEncoder) *Schema {
+	switch e := f.fieldEncoder.(type) {
+	case SchemaDescriber:
+		return describe(e)
+	case *stringModeNumberEncoder:
+		kind := f.field.Type().Kind()
+		if kind == reflect.Ptr {
+			kind = f.field.Type().(reflect2.PtrType).Elem().Kind()
+		}
+		switch kind {
+		case reflect.Float32, reflect.Float64:
+			return &Schema{Type: SchemaType{"string"}, Pattern: `^-?[0-9]+(\.[0-9]+)?([eE][-+]?[0-9]+)?$`}
+		case reflect.Bool:
+			return &Schema{Type: SchemaType{"string"}, Pattern: `^(true|false)$`}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			return int64StringSchema(reflect.Uint64)
+		default:
+			return int64StringSchema(reflect.Int64)
+		}
+	case *stringModeStringEncoder:
+		return newTypeSchema("string")
+	}
+
+	if g.ctx.frozenConfig.fieldEncoders[fmt.Sprintf("%s/%s", owner.String(), f.field.Name())] != nil {
+		return &Schema{} // the registered field encoder without SchemaDescriber
+	}
+	return g.typeSchema(f.field.Type(), f.nilAsEmpty)
+}
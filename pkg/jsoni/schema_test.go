@@ -0,0 +1,123 @@
+package jsoni_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/bingoohuang/gg/pkg/jsoni"
+	"github.com/bingoohuang/gg/pkg/jsoni/extra"
+	"github.com/bingoohuang/gg/pkg/strcase"
+	"github.com/stretchr/testify/assert"
+)
+
+type SchemaAudit struct {
+	CreatedBy string
+	UpdatedAt *time.Time `json:",omitempty"`
+}
+
+type SchemaOrder struct {
+	SchemaAudit
+	OrderID  int64    `json:"id"`
+	Amount   float64  `json:"amount,string"`
+	Tags     []string `json:"tags,nilasempty"`
+	Items    []*SchemaOrder
+	Attrs    map[string]int8
+	Note     *string
+	Secret   string `json:"-"`
+	Duration unixDuration
+}
+
+// unixDuration is encoded by the registered encoder in seconds.
+type unixDuration time.Duration
+
+type unixDurationEncoder struct{}
+
+func (unixDurationEncoder) Encode(_ context.Context, ptr unsafe.Pointer, stream *jsoni.Stream) {
+	stream.WriteInt64(int64(time.Duration(*(*unixDuration)(ptr)) / time.Second))
+}
+
+func (unixDurationEncoder) IsEmpty(_ context.Context, ptr unsafe.Pointer, _ bool) bool {
+	return *(*unixDuration)(ptr) == 0
+}
+
+func (unixDurationEncoder) JSONSchema() *jsoni.Schema {
+	return &jsoni.Schema{Type: jsoni.SchemaType{"integer"}, Description: "seconds"}
+}
+
+func TestSchemaOf(t *testing.T) {
+	c := jsoni.Config{Int64AsString: true}.Froze()
+	c.RegisterExtension(&extra.NamingStrategyExtension{Translate: strcase.ToCamelLower})
+	c.RegisterTypeEncoder("jsoni_test.unixDuration", unixDurationEncoder{})
+
+	s := c.SchemaOf(&SchemaOrder{})
+	assert.Equal(t, jsoni.SchemaDraft, s.Schema)
+	assert.Equal(t, "#/$defs/SchemaOrder", s.Ref)
+
+	order := s.Defs["SchemaOrder"]
+	assert.Equal(t, []string{"createdBy", "id", "amount", "tags", "items", "attrs", "note", "duration"}, order.Required)
+	assert.Equal(t, jsoni.SchemaType{"string"}, order.Properties["createdBy"].Type)
+	assert.Equal(t, jsoni.SchemaType{"string", "null"}, order.Properties["updatedAt"].Type)
+	assert.Equal(t, "date-time", order.Properties["updatedAt"].Format)
+	assert.Equal(t, &jsoni.Schema{Type: jsoni.SchemaType{"string"}, Pattern: `^-?[0-9]+$`}, order.Properties["id"])
+	assert.Equal(t, jsoni.SchemaType{"string"}, order.Properties["amount"].Type)
+	assert.Equal(t, jsoni.SchemaType{"array"}, order.Properties["tags"].Type)
+	assert.Equal(t, jsoni.SchemaType{"array", "null"}, order.Properties["items"].Type)
+	assert.Equal(t, "#/$defs/SchemaOrder", order.Properties["items"].Items.AnyOf[0].Ref)
+	assert.Equal(t, float64(127), *order.Properties["attrs"].AdditionalProperties.Maximum)
+	assert.Equal(t, "seconds", order.Properties["duration"].Description)
+	assert.Nil(t, order.Properties["Secret"])
+	assert.Nil(t, order.Properties["secret"])
+
+	note := "n"
+	data, err := c.Marshal(context.Background(), &SchemaOrder{
+		SchemaAudit: SchemaAudit{CreatedBy: "bingoo"},
+		OrderID:     1, Amount: 1.5, Note: &note, Duration: unixDuration(time.Minute),
+		Items: []*SchemaOrder{{OrderID: 2}},
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, s.Validate(data))
+
+	// the schema is published as JSON, and works after loaded back
+	sj, err := json.Marshal(s)
+	assert.Nil(t, err)
+	var loaded jsoni.Schema
+	assert.Nil(t, json.Unmarshal(sj, &loaded))
+	assert.Nil(t, loaded.Validate(data))
+}
+
+func TestSchemaValidate(t *testing.T) {
+	s := jsoni.Config{DisallowUnknownFields: true}.Froze().SchemaOf(SchemaOrder{})
+
+	err := s.Validate([]byte(`{"CreatedBy":"a","id":1,"amount":"1","tags":null,"Items":[{"id":"x"}],` +
+		`"Attrs":{"a":300},"Note":null,"Duration":"1s","unknown/key":1}`))
+	var errs jsoni.ValidationErrors
+	assert.True(t, errors.As(err, &errs))
+	assert.Equal(t, []string{
+		`/Attrs/a: 300 is greater than the maximum 127`,
+		`/Duration: expected integer, got string`,
+		`/Items/0: missing required property "CreatedBy"`,
+		`/Items/0: missing required property "amount"`,
+		`/Items/0: missing required property "tags"`,
+		`/Items/0: missing required property "Items"`,
+		`/Items/0: missing required property "Attrs"`,
+		`/Items/0: missing required property "Note"`,
+		`/Items/0: missing required property "Duration"`,
+		`/Items/0/id: expected integer, got string`,
+		`/tags: expected array, got null`,
+		`/unknown~1key: is not allowed`,
+	}, validationMessages(errs))
+
+	assert.NotNil(t, s.Validate([]byte(`{`)))
+}
+
+func validationMessages(errs jsoni.ValidationErrors) []string {
+	msgs := make([]string, len(errs))
+	for i, e := range errs {
+		msgs[i] = e.Error()
+	}
+	return msgs
+}
@@ -0,0 +1,224 @@
+package jsoni
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ValidationError is a violation of the JSON Schema.
+type ValidationError struct {
+	// Path is the JSON Pointer of the invalid value, e.g. /items/0/name, "" for the root.
+	Path    string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Path == "" {
+		return "/: " + e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// ValidationErrors is all the violations of the JSON Schema.
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, v := range e {
+		msgs[i] = v.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Validate validates the JSON data against the schema,
+// and returns ValidationErrors with all the violations, or the error when the data is not a valid JSON.
+func (s *Schema) Validate(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return err
+	}
+
+	var errs ValidationErrors
+	(&schemaValidator{root: s, patterns: map[string]*regexp.Regexp{}}).validate(s, v, "", &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+type schemaValidator struct {
+	root     *Schema
+	patterns map[string]*regexp.Regexp
+}
+
+func (sv *schemaValidator) resolve(ref string) (*Schema, error) {
+	if ref == "#" {
+		return sv.root, nil
+	}
+	if name := strings.TrimPrefix(ref, "#/$defs/"); name != ref {
+		if s, ok := sv.root.Defs[name]; ok {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("unresolvable $ref %s", ref)
+}
+
+func (sv *schemaValidator) valid(s *Schema, v interface{}, path string) bool {
+	var errs ValidationErrors
+	sv.validate(s, v, path, &errs)
+	return len(errs) == 0
+}
+
+func (sv *schemaValidator) validate(s *Schema, v interface{}, path string, errs *ValidationErrors) {
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, &ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if s.Ref != "" {
+		rs, err := sv.resolve(s.Ref)
+		if err != nil {
+			fail("%v", err)
+			return
+		}
+		sv.validate(rs, v, path, errs)
+	}
+	if s.Not != nil && sv.valid(s.Not, v, path) {
+		fail("is not allowed")
+		return
+	}
+	if len(s.AnyOf) > 0 {
+		matched := false
+		for _, sub := range s.AnyOf {
+			if sv.valid(sub, v, path) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			// report the violations of the first non-null alternative, which is the most helpful usually
+			for _, sub := range s.AnyOf {
+				if len(sub.Type) != 1 || sub.Type[0] != "null" {
+					sv.validate(sub, v, path, errs)
+					return
+				}
+			}
+			fail("does not match any of the schemas")
+			return
+		}
+	}
+
+	if len(s.Type) > 0 {
+		actual := jsonTypeOf(v)
+		matched := false
+		for _, t := range s.Type {
+			if t == actual || t == "number" && actual == "integer" {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			fail("expected %s, got %s", strings.Join(s.Type, " or "), actual)
+			return
+		}
+	}
+
+	switch val := v.(type) {
+	case string:
+		sv.validateString(s, val, fail)
+	case json.Number:
+		f, _ := val.Float64()
+		if s.Minimum != nil && f < *s.Minimum {
+			fail("%s is less than the minimum %v", val, *s.Minimum)
+		}
+		if s.Maximum != nil && f > *s.Maximum {
+			fail("%s is greater than the maximum %v", val, *s.Maximum)
+		}
+	case []interface{}:
+		if s.MinItems != nil && len(val) < *s.MinItems {
+			fail("expected at least %d items, got %d", *s.MinItems, len(val))
+		}
+		if s.MaxItems != nil && len(val) > *s.MaxItems {
+			fail("expected at most %d items, got %d", *s.MaxItems, len(val))
+		}
+		if s.Items != nil {
+			for i, item := range val {
+				sv.validate(s.Items, item, path+"/"+strconv.Itoa(i), errs)
+			}
+		}
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := val[name]; !ok {
+				fail("missing required property %q", name)
+			}
+		}
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			p := path + "/" + escapeJSONPointer(k)
+			if ps, ok := s.Properties[k]; ok {
+				sv.validate(ps, val[k], p, errs)
+			} else if s.AdditionalProperties != nil {
+				sv.validate(s.AdditionalProperties, val[k], p, errs)
+			}
+		}
+	}
+}
+
+func (sv *schemaValidator) validateString(s *Schema, v string, fail func(format string, args ...interface{})) {
+	if s.Pattern != "" {
+		re, ok := sv.patterns[s.Pattern]
+		if !ok {
+			var err error
+			if re, err = regexp.Compile(s.Pattern); err != nil {
+				fail("invalid pattern %s: %v", s.Pattern, err)
+				return
+			}
+			sv.patterns[s.Pattern] = re
+		}
+		if !re.MatchString(v) {
+			fail("%q does not match the pattern %s", v, s.Pattern)
+		}
+	}
+	if s.Format == "date-time" {
+		if _, err := time.Parse(time.RFC3339Nano, v); err != nil {
+			fail("%q is not a valid date-time", v)
+		}
+	}
+}
+
+// jsonTypeOf returns the JSON Schema type of the value decoded by json.Decoder with UseNumber.
+func jsonTypeOf(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if f, err := val.Float64(); err == nil && f == math.Trunc(f) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+var jsonPointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+func escapeJSONPointer(s string) string { return jsonPointerEscaper.Replace(s) }
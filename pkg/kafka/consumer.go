@@ -0,0 +1,294 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/bingoohuang/gg/pkg/backoff"
+	"go.uber.org/multierr"
+)
+
+// The headers added to the dead letters, to tell where and why the message failed.
+const (
+	HeaderDeadLetterError     = "x-dead-letter-error"
+	HeaderDeadLetterTopic     = "x-dead-letter-topic"
+	HeaderDeadLetterPartition = "x-dead-letter-partition"
+	HeaderDeadLetterOffset    = "x-dead-letter-offset"
+)
+
+// Handler handles a consumed message.
+// The message is retried with backoff on error, and is sent to the dead-letter topic
+// when the retries are exhausted, or at once when the error is backoff.Permanent.
+// The ctx is done when the partition is revoked by rebalancing, or the consumer is closing,
+// then the message is not committed, and will be redelivered to the next owner of the partition.
+type Handler func(ctx context.Context, msg *sarama.ConsumerMessage) error
+
+// Middleware wraps a Handler, for logging, metrics and so on.
+type Middleware func(Handler) Handler
+
+// TypedHandler creates a Handler which decodes the message value into T by decode (json.Unmarshal if nil),
+// the decoding errors are permanent, so the undecodable messages go to the dead-letter topic without retries.
+func TypedHandler[T any](decode func(data []byte, v any) error, h func(ctx context.Context, v T, msg *sarama.ConsumerMessage) error) Handler {
+	if decode == nil {
+		decode = json.Unmarshal
+	}
+
+	return func(ctx context.Context, msg *sarama.ConsumerMessage) error {
+		var v T
+		if err := decode(msg.Value, &v); err != nil {
+			return backoff.Permanent(fmt.Errorf("decode message: %w", err))
+		}
+		return h(ctx, v, msg)
+	}
+}
+
+type ConsumerConfig struct {
+	Brokers []string
+	Version string
+	Group   string
+	Topics  []string
+	// BalanceStrategy is sticky, roundrobin or range (default).
+	BalanceStrategy string
+	// Oldest consumes from the oldest offset when the group has no committed offset, default from the newest.
+	Oldest bool
+
+	// MaxRetries is the max number of retries of a failed message, default 3, negative for no retries.
+	MaxRetries int
+	// NewBackOff creates the backoff between the retries, default exponential from 100ms up to 10s.
+	NewBackOff func() backoff.BackOff
+	// DeadLetterTopic is the topic to publish the messages failed after the retries,
+	// the messages are skipped with a warning log if empty.
+	DeadLetterTopic string
+	// DeadLetterProducer publishes the dead letters, default a sync producer to the same brokers.
+	DeadLetterProducer *Producer
+
+	// Middlewares wrap the handler, the first one is the outermost.
+	Middlewares []Middleware
+
+	// OnAssigned is called with the assigned partitions when a session starts after rebalancing.
+	OnAssigned func(claims map[string][]int32)
+	// OnRevoked is called with the partitions to revoke, after the offsets committed, when the session ends.
+	OnRevoked func(claims map[string][]int32)
+
+	TlsConfig TlsConfig
+
+	SASLUser     string
+	SASLPassword string
+	SASLVersion  *int
+}
+
+// Consumer is a consumer group member, which commits the offset of a message
+// only after it is handled or dead-lettered, so the messages are delivered at least once.
+type Consumer struct {
+	Config *ConsumerConfig
+
+	group      sarama.ConsumerGroup
+	handler    Handler
+	deadLetter *Producer
+	ownsDLQ    bool
+}
+
+func defaultBackOff() backoff.BackOff {
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = 100 * time.Millisecond
+	b.MaxInterval = 10 * time.Second
+	b.MaxElapsedTime = 0
+	return b
+}
+
+// NewConsumer creates a Consumer with the handler, call Run to start consuming.
+func (c *ConsumerConfig) NewConsumer(handler Handler) (*Consumer, error) {
+	if c.MaxRetries == 0 {
+		c.MaxRetries = 3
+	}
+	if c.NewBackOff == nil {
+		c.NewBackOff = defaultBackOff
+	}
+
+	sc := sarama.NewConfig()
+	if err := ParseVersion(sc, c.Version); err != nil {
+		return nil, err
+	}
+	if err := ConfigNet(sc, c.TlsConfig, c.SASLUser, c.SASLPassword, c.SASLVersion); err != nil {
+		return nil, err
+	}
+
+	sc.Consumer.Group.Rebalance.GroupStrategies = []sarama.BalanceStrategy{ParseBalanceStrategy(c.BalanceStrategy)}
+	sc.Consumer.Offsets.AutoCommit.Enable = false
+	if c.Oldest {
+		sc.Consumer.Offsets.Initial = sarama.OffsetOldest
+	}
+
+	for i := len(c.Middlewares) - 1; i >= 0; i-- {
+		handler = c.Middlewares[i](handler)
+	}
+	consumer := &Consumer{Config: c, handler: handler, deadLetter: c.DeadLetterProducer}
+
+	if c.DeadLetterTopic != "" && consumer.deadLetter == nil {
+		pc := &ProducerConfig{
+			Topic:        c.DeadLetterTopic,
+			Version:      c.Version,
+			Brokers:      c.Brokers,
+			Sync:         true,
+			RequiredAcks: sarama.WaitForAll,
+			TlsConfig:    c.TlsConfig,
+			SASLUser:     c.SASLUser,
+			SASLPassword: c.SASLPassword,
+			SASLVersion:  c.SASLVersion,
+		}
+		p, err := pc.NewProducer()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create dead-letter producer, %w", err)
+		}
+		consumer.deadLetter, consumer.ownsDLQ = p, true
+	}
+
+	group, err := sarama.NewConsumerGroup(c.Brokers, c.Group, sc)
+	if err != nil {
+		if consumer.ownsDLQ {
+			_ = consumer.deadLetter.Close()
+		}
+		return nil, fmt.Errorf("failed to start Sarama ConsumerGroup, %w", err)
+	}
+	consumer.group = group
+
+	return consumer, nil
+}
+
+// Run consumes the topics until the ctx is done or the consumer is closed,
+// and rejoins the group after each rebalancing.
+func (c *Consumer) Run(ctx context.Context) error {
+	h := &groupHandler{Consumer: c}
+	for {
+		if err := c.group.Consume(ctx, c.Config.Topics, h); err != nil {
+			if errors.Is(err, sarama.ErrClosedConsumerGroup) {
+				return nil
+			}
+			return err
+		}
+
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// Close leaves the group, and closes the dead-letter producer created by the consumer.
+func (c *Consumer) Close() error {
+	err := c.group.Close()
+	if c.ownsDLQ {
+		err = multierr.Append(err, c.deadLetter.Close())
+	}
+	return err
+}
+
+// handle handles the message with retries, and dead-letters it when failed.
+// It returns an error only when the message should not be committed.
+func (c *Consumer) handle(ctx context.Context, msg *sarama.ConsumerMessage) error {
+	maxRetries := c.Config.MaxRetries
+	if maxRetries < 0 {
+		maxRetries = 0
+	}
+
+	b := backoff.WithContext(backoff.WithMaxRetries(c.Config.NewBackOff(), uint64(maxRetries)), ctx)
+	err := backoff.RetryNotify(func(int) error {
+		return c.handler(ctx, msg)
+	}, b, func(retryTimes int, err error, next time.Duration) {
+		if err != nil {
+			log.Printf("W! handle kafka message %s/%d@%d failed, retry %d after %s, error: %v",
+				msg.Topic, msg.Partition, msg.Offset, retryTimes+1, next, err)
+		}
+	})
+	if err == nil {
+		return nil
+	}
+	if ctx.Err() != nil { // revoked or closing, left for the redelivery
+		return ctx.Err()
+	}
+
+	return c.sendDeadLetter(ctx, msg, err)
+}
+
+func (c *Consumer) sendDeadLetter(ctx context.Context, msg *sarama.ConsumerMessage, cause error) error {
+	if c.deadLetter == nil {
+		log.Printf("W! kafka message %s/%d@%d is skipped, error: %v", msg.Topic, msg.Partition, msg.Offset, cause)
+		return nil
+	}
+
+	options := []OptionFn{WithKey(string(msg.Key))}
+	for _, h := range msg.Headers {
+		options = append(options, WithHeader(string(h.Key), string(h.Value)))
+	}
+	options = append(options,
+		WithHeader(HeaderDeadLetterError, cause.Error()),
+		WithHeader(HeaderDeadLetterTopic, msg.Topic),
+		WithHeader(HeaderDeadLetterPartition, strconv.FormatInt(int64(msg.Partition), 10)),
+		WithHeader(HeaderDeadLetterOffset, strconv.FormatInt(msg.Offset, 10)),
+	)
+
+	// retry until succeeded, or the ctx is done, because the offset can not be committed before that.
+	err := backoff.Retry(func(int) error {
+		_, err := c.deadLetter.Publish(c.Config.DeadLetterTopic, msg.Value, options...)
+		return err
+	}, backoff.WithContext(c.Config.NewBackOff(), ctx))
+	if err != nil {
+		return fmt.Errorf("publish dead letter of %s/%d@%d, %w", msg.Topic, msg.Partition, msg.Offset, err)
+	}
+
+	log.Printf("W! kafka message %s/%d@%d is sent to the dead-letter topic %s, error: %v",
+		msg.Topic, msg.Partition, msg.Offset, c.Config.DeadLetterTopic, cause)
+	return nil
+}
+
+// groupHandler is the sarama.ConsumerGroupHandler of one Consumer.
+type groupHandler struct {
+	*Consumer
+}
+
+func (h *groupHandler) Setup(session sarama.ConsumerGroupSession) error {
+	if h.Config.OnAssigned != nil {
+		h.Config.OnAssigned(session.Claims())
+	}
+	return nil
+}
+
+func (h *groupHandler) Cleanup(session sarama.ConsumerGroupSession) error {
+	session.Commit()
+	if h.Config.OnRevoked != nil {
+		h.Config.OnRevoked(session.Claims())
+	}
+	return nil
+}
+
+// ConsumeClaim handles the messages of a partition one by one, and commits each after handled.
+// When the dead letter can not be published, it stops consuming the partition until the next rebalancing,
+// to avoid committing over the failed message.
+func (h *groupHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	ctx := session.Context()
+	for {
+		select {
+		case msg, ok := <-claim.Messages():
+			if !ok {
+				return nil
+			}
+
+			if err := h.handle(ctx, msg); err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return err
+			}
+
+			session.MarkMessage(msg, "")
+			session.Commit()
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/bingoohuang/gg/pkg/backoff"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConsumer(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 0)
+	defer broker.Close()
+
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("my-topic", 0, broker.BrokerID()).
+			SetLeader("my-dlq", 0, broker.BrokerID()),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetOffset("my-topic", 0, sarama.OffsetOldest, 0).
+			SetOffset("my-topic", 0, sarama.OffsetNewest, 4),
+		"FindCoordinatorRequest": sarama.NewMockFindCoordinatorResponse(t).
+			SetCoordinator(sarama.CoordinatorGroup, "my-group", broker),
+		"HeartbeatRequest": sarama.NewMockHeartbeatResponse(t),
+		"JoinGroupRequest": sarama.NewMockJoinGroupResponse(t).SetGroupProtocol(sarama.RangeBalanceStrategyName),
+		"SyncGroupRequest": sarama.NewMockSyncGroupResponse(t).SetMemberAssignment(
+			&sarama.ConsumerGroupMemberAssignment{Topics: map[string][]int32{"my-topic": {0}}}),
+		"OffsetFetchRequest": sarama.NewMockOffsetFetchResponse(t).
+			SetOffset("my-group", "my-topic", 0, -1, "", sarama.ErrNoError).SetError(sarama.ErrNoError),
+		"FetchRequest": sarama.NewMockFetchResponse(t, 10).
+			SetMessage("my-topic", 0, 0, sarama.StringEncoder(`{"name":"a"}`)).
+			SetMessage("my-topic", 0, 1, sarama.StringEncoder(`{"name":"poison"}`)).
+			SetMessage("my-topic", 0, 2, sarama.StringEncoder(`not json`)).
+			SetMessage("my-topic", 0, 3, sarama.StringEncoder(`{"name":"b"}`)),
+		"OffsetCommitRequest": sarama.NewMockOffsetCommitResponse(t),
+		"ProduceRequest":      sarama.NewMockProduceResponse(t),
+		"LeaveGroupRequest":   sarama.NewMockLeaveGroupResponse(t),
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	type event struct {
+		Name string `json:"name"`
+	}
+
+	var (
+		mu       sync.Mutex
+		handled  []string
+		observed int
+		assigned map[string][]int32
+	)
+	handler := TypedHandler(nil, func(ctx context.Context, v event, msg *sarama.ConsumerMessage) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		handled = append(handled, v.Name)
+		switch v.Name {
+		case "poison":
+			return errors.New("poison")
+		case "b":
+			cancel()
+		}
+		return nil
+	})
+
+	c := &ConsumerConfig{
+		Brokers:         []string{broker.Addr()},
+		Version:         "2.0.0",
+		Group:           "my-group",
+		Topics:          []string{"my-topic"},
+		Oldest:          true,
+		MaxRetries:      2,
+		NewBackOff:      func() backoff.BackOff { return backoff.NewConstantBackOff(time.Millisecond) },
+		DeadLetterTopic: "my-dlq",
+		Middlewares: []Middleware{
+			LogMiddleware(true),
+			MetricsMiddleware(func(*sarama.ConsumerMessage, time.Duration, error) { observed++ }),
+		},
+		OnAssigned: func(claims map[string][]int32) { assigned = claims },
+	}
+	consumer, err := c.NewConsumer(handler)
+	assert.Nil(t, err)
+
+	assert.Nil(t, consumer.Run(ctx))
+	assert.Nil(t, consumer.Close())
+
+	assert.Equal(t, map[string][]int32{"my-topic": {0}}, assigned)
+	// the poison message is retried twice, the undecodable one is not retried
+	assert.Equal(t, []string{"a", "poison", "poison", "poison", "b"}, handled)
+	assert.Equal(t, 6, observed)
+
+	var produced int
+	var committed int64
+	for _, r := range broker.History() {
+		switch req := r.Request.(type) {
+		case *sarama.ProduceRequest:
+			produced++
+		case *sarama.OffsetCommitRequest:
+			committed, _, _ = req.Offset("my-topic", 0)
+		}
+	}
+	// the poison and the undecodable ones are dead-lettered
+	assert.Equal(t, 2, produced)
+	// the offset next to the last handled message is committed
+	assert.Equal(t, int64(4), committed)
+}
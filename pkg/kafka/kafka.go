@@ -2,12 +2,21 @@ package kafka
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 
 	"github.com/IBM/sarama"
 	"github.com/bingoohuang/gg/pkg/ss"
+	"go.uber.org/multierr"
+)
+
+var (
+	// ErrTxnUnsupported is the error when the idempotent or transactional producer is not supported by the Kafka version.
+	ErrTxnUnsupported = errors.New("idempotent or transactional producer requires Kafka version 0.11.0 or later")
+	// ErrNotTransactional is the error when a transaction is begun on a producer without TransactionalID.
+	ErrNotTransactional = errors.New("producer is not transactional")
 )
 
 type ProducerConfig struct {
@@ -19,6 +28,16 @@ type ProducerConfig struct {
 	Sync            bool
 	RequiredAcks    sarama.RequiredAcks
 
+	// Idempotent enables the idempotent producer, which writes each message exactly once per partition
+	// on the retries, RequiredAcks is forced to WaitForAll.
+	Idempotent bool
+	// TransactionalID enables the transactional producer, which implies Idempotent,
+	// see Producer.BeginTxn, CommitTxn, AbortTxn and Transact.
+	TransactionalID string
+	// OnDelivery is called with the delivery report of each message in the async mode.
+	// The failed ones are logged if it is nil.
+	OnDelivery func(DeliveryReport)
+
 	TlsConfig TlsConfig
 	Context   context.Context
 
@@ -32,9 +51,20 @@ type PublishResponse struct {
 	Topic  string
 }
 
+// DeliveryReport is the delivery report of a message in the async mode.
+type DeliveryReport struct {
+	Topic     string
+	Partition int32
+	Offset    int64
+	// Metadata is the one set by WithMetadata when publishing, to correlate the report with the message.
+	Metadata interface{}
+	Err      error
+}
+
 type Options struct {
 	MessageKey string
 	Headers    map[string]string
+	Metadata   interface{}
 }
 
 func (o *Options) Fulfil(msg *sarama.ProducerMessage) {
@@ -48,6 +78,8 @@ func (o *Options) Fulfil(msg *sarama.ProducerMessage) {
 	if len(o.MessageKey) > 0 {
 		msg.Key = sarama.StringEncoder(o.MessageKey)
 	}
+
+	msg.Metadata = o.Metadata
 }
 
 type OptionFn func(*Options)
@@ -55,6 +87,9 @@ type OptionFn func(*Options)
 func WithKey(key string) OptionFn     { return func(options *Options) { options.MessageKey = key } }
 func WithHeader(k, v string) OptionFn { return func(options *Options) { options.Headers[k] = v } }
 
+// WithMetadata sets the metadata of the message, which is returned in the DeliveryReport.
+func WithMetadata(v interface{}) OptionFn { return func(options *Options) { options.Metadata = v } }
+
 type asyncProducer struct {
 	sarama.AsyncProducer
 }
@@ -64,6 +99,15 @@ func (p asyncProducer) SendMessage(msg *sarama.ProducerMessage) (interface{}, er
 	return AsyncProducerResult{Enqueued: true}, nil
 }
 
+func (p asyncProducer) SendMessages(msgs []*sarama.ProducerMessage) ([]PublishResult, error) {
+	results := make([]PublishResult, len(msgs))
+	for i, msg := range msgs {
+		p.Input() <- msg
+		results[i] = PublishResult{Topic: msg.Topic, Result: AsyncProducerResult{Enqueued: true}}
+	}
+	return results, nil
+}
+
 type AsyncProducerResult struct {
 	Enqueued    bool
 	ContextDone bool
@@ -83,8 +127,38 @@ func (p syncProducer) SendMessage(msg *sarama.ProducerMessage) (interface{}, err
 	return SyncProducerResult{Partition: partition, Offset: offset}, err
 }
 
+func (p syncProducer) SendMessages(msgs []*sarama.ProducerMessage) ([]PublishResult, error) {
+	err := p.SyncProducer.SendMessages(msgs)
+	failed := make(map[*sarama.ProducerMessage]error)
+	var errs sarama.ProducerErrors
+	if errors.As(err, &errs) {
+		for _, e := range errs {
+			failed[e.Msg] = e.Err
+		}
+	}
+
+	results := make([]PublishResult, len(msgs))
+	for i, msg := range msgs {
+		if e, ok := failed[msg]; ok {
+			results[i] = PublishResult{Topic: msg.Topic, Err: e}
+		} else if err != nil && len(errs) == 0 { // not a per-message error
+			results[i] = PublishResult{Topic: msg.Topic, Err: err}
+		} else {
+			results[i] = PublishResult{Topic: msg.Topic, Result: SyncProducerResult{Partition: msg.Partition, Offset: msg.Offset}}
+		}
+	}
+	return results, err
+}
+
 type producer interface {
 	SendMessage(*sarama.ProducerMessage) (interface{}, error)
+	SendMessages([]*sarama.ProducerMessage) ([]PublishResult, error)
+
+	IsTransactional() bool
+	TxnStatus() sarama.ProducerTxnStatusFlag
+	BeginTxn() error
+	CommitTxn() error
+	AbortTxn() error
 }
 
 type Producer struct {
@@ -112,6 +186,62 @@ func (p Producer) Publish(topic string, vv []byte, optionsFns ...OptionFn) (*Pub
 	return &PublishResponse{Result: result, Topic: msg.Topic}, nil
 }
 
+// BatchMessage is a message of PublishBatch.
+type BatchMessage struct {
+	// Topic is the topic of the message, default is the one of the config.
+	Topic string
+	Value []byte
+	Options
+}
+
+// PublishResult is the result of a message of PublishBatch.
+type PublishResult struct {
+	// Result is SyncProducerResult in the sync mode, or AsyncProducerResult in the async mode.
+	Result interface{}
+	Topic  string
+	Err    error
+}
+
+// PublishBatch publishes the messages, and returns the result of each message in the same order.
+// In the sync mode, the error is not nil if any message failed, check the Err of each result for details.
+// In the async mode, the messages are only enqueued, see ProducerConfig.OnDelivery for the delivery reports.
+func (p Producer) PublishBatch(messages []BatchMessage) ([]PublishResult, error) {
+	msgs := make([]*sarama.ProducerMessage, len(messages))
+	for i, m := range messages {
+		msgs[i] = &sarama.ProducerMessage{Topic: ss.Or(m.Topic, p.Config.Topic), Value: sarama.ByteEncoder(m.Value)}
+		m.Options.Fulfil(msgs[i])
+	}
+
+	return p.producer.SendMessages(msgs)
+}
+
+// BeginTxn begins a transaction, the messages published before CommitTxn or AbortTxn are in the transaction.
+func (p Producer) BeginTxn() error {
+	if !p.IsTransactional() {
+		return ErrNotTransactional
+	}
+	return p.producer.BeginTxn()
+}
+
+// Transact publishes the messages in f in a transaction, which is committed if f returns nil, or aborted otherwise.
+func (p Producer) Transact(f func() error) error {
+	if err := p.BeginTxn(); err != nil {
+		return err
+	}
+
+	err := f()
+	if err == nil {
+		if err = p.CommitTxn(); err == nil {
+			return nil
+		}
+		if p.TxnStatus()&sarama.ProducerTxnFlagAbortableError == 0 {
+			return err
+		}
+	}
+
+	return multierr.Append(err, p.AbortTxn())
+}
+
 func (c *ProducerConfig) NewProducer() (*Producer, error) {
 	// For the data collector, we are looking for strong consistency semantics.
 	// Because we don't change the flush settings, sarama will try to produce messages
@@ -130,22 +260,18 @@ func (c *ProducerConfig) NewProducer() (*Producer, error) {
 		sc.Producer.MaxMessageBytes = int(sarama.MaxRequestSize)
 	}
 	sc.Producer.Return.Successes = true
-	if tc := c.TlsConfig.Create(); tc != nil {
-		sc.Net.TLS.Config = tc
-		sc.Net.TLS.Enable = true
-	}
-
-	if c.SASLUser != "" {
-		sc.Net.SASL.Enable = true
-		sc.Net.SASL.User = c.SASLUser
-		sc.Net.SASL.Password = c.SASLPassword
-		sc.Net.SASL.Handshake = true
-		sc.Net.SASL.Mechanism = sarama.SASLTypePlaintext
-		version, err := SASLVersion(sc.Version, c.SASLVersion)
-		if err != nil {
-			return nil, err
+	if c.Idempotent || c.TransactionalID != "" {
+		if !sc.Version.IsAtLeast(sarama.V0_11_0_0) {
+			return nil, ErrTxnUnsupported
 		}
-		sc.Net.SASL.Version = version
+
+		sc.Producer.Idempotent = true
+		sc.Producer.RequiredAcks = sarama.WaitForAll
+		sc.Net.MaxOpenRequests = 1
+		sc.Producer.Transaction.ID = c.TransactionalID
+	}
+	if err := ConfigNet(sc, c.TlsConfig, c.SASLUser, c.SASLPassword, c.SASLVersion); err != nil {
+		return nil, err
 	}
 
 	// On the broker side, you may want to change the following settings to get
@@ -157,7 +283,7 @@ func (c *ProducerConfig) NewProducer() (*Producer, error) {
 		if err != nil {
 			return nil, fmt.Errorf("failed to start Sarama SyncProducer, %w", err)
 		}
-		return &Producer{producer: &syncProducer{SyncProducer: p}, Config: c}, nil
+		return &Producer{producer: &syncProducer{SyncProducer: p}, Closer: p, Config: c}, nil
 	}
 
 	sc.Producer.Return.Errors = true
@@ -165,19 +291,39 @@ func (c *ProducerConfig) NewProducer() (*Producer, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to start Sarama NewAsyncProducer, %w", err)
 	}
-	// We will just log to STDOUT if we're not able to produce messages.
+	ctx := c.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	// We will just log to STDOUT if we're not able to produce messages, and no OnDelivery.
 	// Note: messages will only be returned here after all retry attempts are exhausted.
 	go func() {
-		for {
+		successes, errs := p.Successes(), p.Errors()
+		for successes != nil || errs != nil {
 			select {
-			case <-c.Context.Done():
+			case <-ctx.Done():
 				return
-			case <-p.Successes():
-			case err := <-p.Errors():
-				log.Println("Failed to write access log entry:", err)
+			case msg, ok := <-successes:
+				if !ok {
+					successes = nil
+				} else if c.OnDelivery != nil {
+					c.OnDelivery(newDeliveryReport(msg, nil))
+				}
+			case err, ok := <-errs:
+				if !ok {
+					errs = nil
+				} else if c.OnDelivery != nil {
+					c.OnDelivery(newDeliveryReport(err.Msg, err.Err))
+				} else {
+					log.Println("Failed to write access log entry:", err)
+				}
 			}
 		}
 	}()
 
-	return &Producer{producer: &asyncProducer{AsyncProducer: p}, Config: c}, nil
+	return &Producer{producer: &asyncProducer{AsyncProducer: p}, Closer: p, Config: c}, nil
+}
+
+func newDeliveryReport(msg *sarama.ProducerMessage, err error) DeliveryReport {
+	return DeliveryReport{Topic: msg.Topic, Partition: msg.Partition, Offset: msg.Offset, Metadata: msg.Metadata, Err: err}
 }
@@ -0,0 +1,162 @@
+package kafka
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+)
+
+func newMockProduceBroker(t *testing.T, extra map[string]sarama.MockResponse) *sarama.MockBroker {
+	broker := sarama.NewMockBroker(t, 1)
+	handlers := map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("my-topic", 0, broker.BrokerID()),
+		"ProduceRequest": sarama.NewMockProduceResponse(t),
+	}
+	for k, v := range extra {
+		handlers[k] = v
+	}
+	broker.SetHandlerByMap(handlers)
+	return broker
+}
+
+func TestPublishBatch(t *testing.T) {
+	broker := newMockProduceBroker(t, nil)
+	defer broker.Close()
+
+	c := &ProducerConfig{Topic: "my-topic", Brokers: []string{broker.Addr()}, Sync: true, RequiredAcks: sarama.WaitForLocal}
+	p, err := c.NewProducer()
+	assert.Nil(t, err)
+	defer p.Close()
+
+	results, err := p.PublishBatch([]BatchMessage{
+		{Value: []byte("a")},
+		{Value: []byte("b"), Options: Options{MessageKey: "k", Headers: map[string]string{"h": "v"}}},
+	})
+	assert.Nil(t, err)
+	assert.Len(t, results, 2)
+	for _, r := range results {
+		assert.Nil(t, r.Err)
+		assert.Equal(t, "my-topic", r.Topic)
+		assert.IsType(t, SyncProducerResult{}, r.Result)
+	}
+}
+
+func TestPublishBatchFailed(t *testing.T) {
+	broker := newMockProduceBroker(t, map[string]sarama.MockResponse{
+		"ProduceRequest": sarama.NewMockProduceResponse(t).SetError("my-topic", 0, sarama.ErrMessageSizeTooLarge),
+	})
+	defer broker.Close()
+
+	c := &ProducerConfig{Topic: "my-topic", Brokers: []string{broker.Addr()}, Sync: true, RequiredAcks: sarama.WaitForLocal}
+	p, err := c.NewProducer()
+	assert.Nil(t, err)
+	defer p.Close()
+
+	results, err := p.PublishBatch([]BatchMessage{{Value: []byte("a")}, {Value: []byte("b")}})
+	assert.NotNil(t, err)
+	for _, r := range results {
+		assert.True(t, errors.Is(r.Err, sarama.ErrMessageSizeTooLarge))
+	}
+}
+
+func TestOnDelivery(t *testing.T) {
+	broker := newMockProduceBroker(t, nil)
+	defer broker.Close()
+
+	reports := make(chan DeliveryReport, 2)
+	c := &ProducerConfig{
+		Topic: "my-topic", Brokers: []string{broker.Addr()}, RequiredAcks: sarama.WaitForLocal,
+		OnDelivery: func(r DeliveryReport) { reports <- r },
+	}
+	p, err := c.NewProducer()
+	assert.Nil(t, err)
+	defer p.Close()
+
+	rsp, err := p.Publish("", []byte("a"), WithMetadata(1))
+	assert.Nil(t, err)
+	assert.Equal(t, AsyncProducerResult{Enqueued: true}, rsp.Result)
+	_, err = p.PublishBatch([]BatchMessage{{Value: []byte("b"), Options: Options{Metadata: 2}}})
+	assert.Nil(t, err)
+
+	metadata := map[interface{}]bool{}
+	for i := 0; i < 2; i++ {
+		select {
+		case r := <-reports:
+			assert.Nil(t, r.Err)
+			assert.Equal(t, "my-topic", r.Topic)
+			metadata[r.Metadata] = true
+		case <-time.After(5 * time.Second):
+			t.Fatal("no delivery report")
+		}
+	}
+	assert.Equal(t, map[interface{}]bool{1: true, 2: true}, metadata)
+}
+
+func TestTransact(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("my-topic", 0, broker.BrokerID()),
+		"FindCoordinatorRequest": sarama.NewMockFindCoordinatorResponse(t).
+			SetCoordinator(sarama.CoordinatorTransaction, "my-txn", broker),
+		"InitProducerIDRequest": sarama.NewMockInitProducerIDResponse(t),
+		"AddPartitionsToTxnRequest": sarama.NewMockWrapper(&sarama.AddPartitionsToTxnResponse{
+			Errors: map[string][]*sarama.PartitionError{"my-topic": {{Partition: 0}}},
+		}),
+		"ProduceRequest": sarama.NewMockProduceResponse(t),
+		"EndTxnRequest":  sarama.NewMockWrapper(&sarama.EndTxnResponse{}),
+	})
+
+	c := &ProducerConfig{Topic: "my-topic", Version: "2.0.0", Brokers: []string{broker.Addr()}, Sync: true, TransactionalID: "my-txn"}
+	p, err := c.NewProducer()
+	assert.Nil(t, err)
+	defer p.Close()
+
+	assert.True(t, p.IsTransactional())
+	err = p.Transact(func() error {
+		_, err := p.PublishBatch([]BatchMessage{{Value: []byte("a")}, {Value: []byte("b")}})
+		return err
+	})
+	assert.Nil(t, err)
+
+	fail := errors.New("fail")
+	err = p.Transact(func() error {
+		if _, err := p.Publish("", []byte("c")); err != nil {
+			return err
+		}
+		return fail
+	})
+	assert.Equal(t, fail, err)
+
+	var endTxns []bool
+	for _, r := range broker.History() {
+		if req, ok := r.Request.(*sarama.EndTxnRequest); ok {
+			endTxns = append(endTxns, req.TransactionResult)
+		}
+	}
+	assert.Equal(t, []bool{true, false}, endTxns)
+}
+
+func TestNotTransactional(t *testing.T) {
+	broker := newMockProduceBroker(t, nil)
+	defer broker.Close()
+
+	c := &ProducerConfig{Topic: "my-topic", Brokers: []string{broker.Addr()}, Sync: true, RequiredAcks: sarama.WaitForLocal}
+	p, err := c.NewProducer()
+	assert.Nil(t, err)
+	defer p.Close()
+
+	assert.Equal(t, ErrNotTransactional, p.Transact(func() error { return nil }))
+
+	c = &ProducerConfig{Version: "0.10.2.0", Brokers: []string{broker.Addr()}, Idempotent: true}
+	_, err = c.NewProducer()
+	assert.Equal(t, ErrTxnUnsupported, err)
+}
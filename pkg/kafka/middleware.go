@@ -0,0 +1,57 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/bingoohuang/gg/pkg/backoff"
+)
+
+// LogMiddleware logs the failed handlings, and the succeeded ones too when verbose.
+func LogMiddleware(verbose bool) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, msg *sarama.ConsumerMessage) error {
+			start := time.Now()
+			err := next(ctx, msg)
+			if err != nil {
+				log.Printf("E! handle kafka message %s/%d@%d failed, cost %s, error: %v",
+					msg.Topic, msg.Partition, msg.Offset, time.Since(start), err)
+			} else if verbose {
+				log.Printf("I! handle kafka message %s/%d@%d, cost %s",
+					msg.Topic, msg.Partition, msg.Offset, time.Since(start))
+			}
+			return err
+		}
+	}
+}
+
+// MetricsMiddleware reports the cost and the result of each handling to observe,
+// e.g. to update the histograms and the counters of the metrics system.
+func MetricsMiddleware(observe func(msg *sarama.ConsumerMessage, cost time.Duration, err error)) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, msg *sarama.ConsumerMessage) error {
+			start := time.Now()
+			err := next(ctx, msg)
+			observe(msg, time.Since(start), err)
+			return err
+		}
+	}
+}
+
+// RecoverMiddleware turns the panics of the handler into permanent errors,
+// so a poison message goes to the dead-letter topic instead of crashing the consumer.
+func RecoverMiddleware() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, msg *sarama.ConsumerMessage) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					err = backoff.Permanent(fmt.Errorf("panic: %v", r))
+				}
+			}()
+			return next(ctx, msg)
+		}
+	}
+}
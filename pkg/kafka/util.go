@@ -84,3 +84,26 @@ func SASLVersion(kafkaVersion sarama.KafkaVersion, saslVersion *int) (int16, err
 		return 0, fmt.Errorf("invalid SASL version")
 	}
 }
+
+// ConfigNet configures the TLS and SASL/PLAIN of the connections to the brokers.
+func ConfigNet(sc *sarama.Config, tlsConfig TlsConfig, saslUser, saslPassword string, saslVersion *int) error {
+	if tc := tlsConfig.Create(); tc != nil {
+		sc.Net.TLS.Config = tc
+		sc.Net.TLS.Enable = true
+	}
+
+	if saslUser != "" {
+		sc.Net.SASL.Enable = true
+		sc.Net.SASL.User = saslUser
+		sc.Net.SASL.Password = saslPassword
+		sc.Net.SASL.Handshake = true
+		sc.Net.SASL.Mechanism = sarama.SASLTypePlaintext
+		version, err := SASLVersion(sc.Version, saslVersion)
+		if err != nil {
+			return err
+		}
+		sc.Net.SASL.Version = version
+	}
+
+	return nil
+}
@@ -0,0 +1,134 @@
+package rbac
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RoleInclude stores the inheritance between roles, the role includes all the permissions of the included role.
+type RoleInclude struct {
+	ID         uint
+	RoleID     uint
+	IncludedID uint
+}
+
+// TableName sets the table name
+func (r RoleInclude) TableName() string { return tablePrefix + "role_includes" }
+
+// IncludeRoles makes the role include the given roles, so the role has all their permissions.
+// it returns ErrRoleCycle if any of the included roles already includes the role, directly or indirectly.
+// The inclusions are checked against the ones locked in the transaction, instead of the cached policy,
+// so the concurrent inclusions can't make a cycle together.
+func (a *Rbac) IncludeRoles(roleName string, includedNames ...string) error {
+	p, err := a.loadPolicy()
+	if err != nil {
+		return err
+	}
+
+	defer a.Invalidate()
+
+	roleID, ok := p.roles[roleName]
+	if !ok {
+		return ErrRoleNotFound
+	}
+
+	var includedIDs []uint
+	for _, name := range includedNames {
+		includedID, ok := p.roles[name]
+		if !ok {
+			return ErrRoleNotFound
+		}
+
+		includedIDs = append(includedIDs, includedID)
+	}
+
+	return a.DB.Transaction(func(tx *gorm.DB) error {
+		var roleIncludes []RoleInclude
+		if r := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Find(&roleIncludes); r.Error != nil {
+			return r.Error
+		}
+
+		locked := &policy{includes: make(map[uint][]uint)}
+		for _, ri := range roleIncludes {
+			locked.includes[ri.RoleID] = append(locked.includes[ri.RoleID], ri.IncludedID)
+		}
+
+		for _, includedID := range includedIDs {
+			// the reachable roles contain the included role itself, so including itself is also a cycle
+			reachable := make(map[uint]bool)
+			locked.collect(includedID, reachable)
+			if reachable[roleID] {
+				return ErrRoleCycle
+			}
+			if containsID(locked.includes[roleID], includedID) {
+				continue
+			}
+
+			if err := tx.Create(&RoleInclude{RoleID: roleID, IncludedID: includedID}).Error; err != nil {
+				return err
+			}
+			locked.includes[roleID] = append(locked.includes[roleID], includedID)
+		}
+		return nil
+	})
+}
+
+func containsID(ids []uint, id uint) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// ExcludeRoles removes the given roles from the included ones of the role.
+func (a *Rbac) ExcludeRoles(roleName string, includedNames ...string) error {
+	defer a.Invalidate()
+
+	var role Role
+	if r := a.DB.Where("name=?", roleName).First(&role); r.Error != nil {
+		if errors.Is(r.Error, gorm.ErrRecordNotFound) {
+			return ErrRoleNotFound
+		}
+		return r.Error
+	}
+
+	var included []Role
+	if r := a.DB.Where("name IN (?)", includedNames).Find(&included); r.Error != nil {
+		return r.Error
+	}
+	if len(included) != len(includedNames) {
+		return ErrRoleNotFound
+	}
+
+	for _, in := range included {
+		if r := a.DB.Where("role_id=?", role.ID).Where("included_id=?", in.ID).Delete(RoleInclude{}); r.Error != nil {
+			return r.Error
+		}
+	}
+
+	return nil
+}
+
+// GetIncludedRoles returns the roles directly included by the role.
+func (a *Rbac) GetIncludedRoles(roleName string) ([]string, error) {
+	p, err := a.loadPolicy()
+	if err != nil {
+		return nil, err
+	}
+
+	roleID, ok := p.roles[roleName]
+	if !ok {
+		return nil, ErrRoleNotFound
+	}
+
+	var result []string
+	for _, id := range p.includes[roleID] {
+		result = append(result, p.roleNames[id])
+	}
+
+	return result, nil
+}
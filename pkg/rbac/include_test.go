@@ -0,0 +1,113 @@
+package rbac_test
+
+import (
+	"testing"
+
+	"github.com/bingoohuang/gg/pkg/rbac"
+)
+
+func TestIncludeRoles(t *testing.T) {
+	auth := rbac.New(rbac.Options{TablesPrefix: "rbac_", DB: db})
+
+	auth.NewRole("role-admin")
+	auth.NewRole("role-editor")
+	auth.NewRole("role-viewer")
+	auth.NewPerm("orders:read")
+	auth.NewPerm("orders:*")
+	auth.AssignPerms("role-viewer", "orders:read")
+	auth.AssignPerms("role-admin", "orders:*")
+
+	if err := auth.IncludeRoles("role-editor", "role-viewer"); err != nil {
+		t.Error("unexpected error while including roles.", err)
+	}
+	if err := auth.IncludeRoles("role-admin", "role-editor"); err != nil {
+		t.Error("unexpected error while including roles.", err)
+	}
+
+	// cycles
+	if err := auth.IncludeRoles("role-viewer", "role-admin"); err != rbac.ErrRoleCycle {
+		t.Error("expecting cycle error when including an ancestor role", err)
+	}
+	if err := auth.IncludeRoles("role-viewer", "role-viewer"); err != rbac.ErrRoleCycle {
+		t.Error("expecting cycle error when including itself", err)
+	}
+
+	// inherited permission
+	if ok, _ := auth.CheckRolePerm("role-editor", "orders:read"); !ok {
+		t.Error("expecting the permission of the included role")
+	}
+
+	// wildcard permission, the checked one is not stored
+	if ok, err := auth.CheckRolePerm("role-admin", "orders:delete"); err != nil || !ok {
+		t.Error("expecting the wildcard permission to match", err)
+	}
+	if ok, _ := auth.CheckRolePerm("role-editor", "orders:delete"); ok {
+		t.Error("expecting false when the wildcard is not granted")
+	}
+
+	// resource-scoped assignments
+	auth.AssignRoleOn(7, "role-admin", "tenant:3")
+	if ok, _ := auth.CheckPermOn(7, "orders:delete", "tenant:3"); !ok {
+		t.Error("expecting the permission on the assigned resource")
+	}
+	if ok, _ := auth.CheckPermOn(7, "orders:delete", "tenant:4"); ok {
+		t.Error("expecting false on another resource")
+	}
+	if ok, _ := auth.CheckPerm(7, "orders:read"); ok {
+		t.Error("expecting false for the global check of a scoped assignment")
+	}
+	if ok, _ := auth.CheckRoleOn(7, "role-viewer", "tenant:3"); !ok {
+		t.Error("expecting the included role on the assigned resource")
+	}
+
+	// global assignment applies to all resources
+	auth.AssignRole(8, "role-viewer")
+	if ok, _ := auth.CheckPermOn(8, "orders:read", "tenant:4"); !ok {
+		t.Error("expecting the global assignment to apply on any resource")
+	}
+
+	// the cache is invalidated on writes
+	auth.ExcludeRoles("role-editor", "role-viewer")
+	if ok, _ := auth.CheckRolePerm("role-editor", "orders:read"); ok {
+		t.Error("expecting false after excluding the role")
+	}
+
+	// clean up
+	auth.RevokeRoleOn(7, "role-admin", "tenant:3")
+	auth.RevokeRole(8, "role-viewer")
+	for _, role := range []string{"role-admin", "role-editor", "role-viewer"} {
+		auth.ExcludeRoles("role-admin", "role-editor")
+		auth.RevokeRolePerm(role, "orders:read")
+		auth.RevokeRolePerm(role, "orders:*")
+	}
+	for _, role := range []string{"role-admin", "role-editor", "role-viewer"} {
+		auth.DeleteRole(role)
+	}
+	auth.DeletePerm("orders:read")
+	auth.DeletePerm("orders:*")
+}
+
+func TestIncludeRolesStaleCache(t *testing.T) {
+	auth := rbac.New(rbac.Options{TablesPrefix: "rbac_", DB: db})
+	other := rbac.New(rbac.Options{TablesPrefix: "rbac_", DB: db})
+
+	auth.NewRole("role-x")
+	auth.NewRole("role-y")
+
+	// the other caches the policy before the inclusion
+	if _, err := other.GetIncludedRoles("role-y"); err != nil {
+		t.Error("unexpected error while getting the included roles.", err)
+	}
+	if err := auth.IncludeRoles("role-x", "role-y"); err != nil {
+		t.Error("unexpected error while including roles.", err)
+	}
+	if err := other.IncludeRoles("role-y", "role-x"); err != rbac.ErrRoleCycle {
+		t.Error("expecting cycle error with the stale cached policy", err)
+	}
+
+	// clean up
+	auth.ExcludeRoles("role-x", "role-y")
+	other.ExcludeRoles("role-y", "role-x")
+	auth.DeleteRole("role-x")
+	auth.DeleteRole("role-y")
+}
@@ -0,0 +1,201 @@
+package rbac
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// policy is the in-memory snapshot of the rbac tables, which answers the checks without queries.
+type policy struct {
+	roles     map[string]uint
+	roleNames map[uint]string
+	perms     map[string]bool
+	// patterns are the stored wildcard permissions, like orders:*.
+	patterns []string
+	includes map[uint][]uint
+	// closure is the set of the roles reachable from a role by inclusion, including itself.
+	closure map[uint]map[uint]bool
+	// grants are the effective permissions of a role, including the included roles' ones.
+	grants map[uint]*grantSet
+	users  map[uint][]UserRole
+}
+
+type grantSet struct {
+	exact    map[string]bool
+	patterns []string
+}
+
+func (g *grantSet) has(perm string) bool {
+	if g == nil {
+		return false
+	}
+	if g.exact[perm] {
+		return true
+	}
+	for _, pattern := range g.patterns {
+		if MatchPerm(pattern, perm) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (ur UserRole) applies(resource string) bool {
+	return ur.Resource == "" || ur.Resource == resource
+}
+
+func (p *policy) knownPerm(perm string) bool {
+	if p.perms[perm] {
+		return true
+	}
+	for _, pattern := range p.patterns {
+		if MatchPerm(pattern, perm) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MatchPerm tells whether the permission matches the pattern, segmented by colons.
+// A * segment matches any single segment, and a trailing * matches all the rest segments,
+// e.g. orders:* matches orders:read and orders:items:read, *:read matches orders:read, * matches all.
+func MatchPerm(pattern, perm string) bool {
+	if !strings.Contains(pattern, "*") {
+		return pattern == perm
+	}
+
+	ps, ss := strings.Split(pattern, ":"), strings.Split(perm, ":")
+	for i, p := range ps {
+		if i == len(ps)-1 && p == "*" {
+			return len(ss) >= len(ps)
+		}
+		if i >= len(ss) || (p != "*" && p != ss[i]) {
+			return false
+		}
+	}
+
+	return len(ss) == len(ps)
+}
+
+// Invalidate drops the in-memory policy cache, the next check will reload it from the database.
+// It is called after every write by the Rbac, call it when the tables are changed by others.
+func (a *Rbac) Invalidate() {
+	a.mu.Lock()
+	a.policy = nil
+	a.version++
+	a.mu.Unlock()
+}
+
+// loadPolicy returns the cached policy, or queries it without holding the lock,
+// so the checks are not blocked by the queries of the others.
+func (a *Rbac) loadPolicy() (*policy, error) {
+	a.mu.Lock()
+	if a.policy != nil && (a.cacheTTL <= 0 || time.Since(a.loadedAt) < a.cacheTTL) {
+		p := a.policy
+		a.mu.Unlock()
+		return p, nil
+	}
+	version := a.version
+	a.mu.Unlock()
+
+	p, err := queryPolicy(a.DB)
+	if err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	if a.version == version {
+		a.policy, a.loadedAt = p, time.Now()
+	}
+	a.mu.Unlock()
+	return p, nil
+}
+
+func queryPolicy(db *gorm.DB) (*policy, error) {
+	var roles []Role
+	var perms []Perm
+	var rolePerms []RolePerm
+	var roleIncludes []RoleInclude
+	var userRoles []UserRole
+
+	for _, dest := range []interface{}{&roles, &perms, &rolePerms, &roleIncludes, &userRoles} {
+		if r := db.Find(dest); r.Error != nil {
+			return nil, r.Error
+		}
+	}
+
+	p := &policy{
+		roles:     make(map[string]uint, len(roles)),
+		roleNames: make(map[uint]string, len(roles)),
+		perms:     make(map[string]bool, len(perms)),
+		includes:  make(map[uint][]uint),
+		closure:   make(map[uint]map[uint]bool, len(roles)),
+		grants:    make(map[uint]*grantSet, len(roles)),
+		users:     make(map[uint][]UserRole),
+	}
+
+	for _, r := range roles {
+		p.roles[r.Name] = r.ID
+		p.roleNames[r.ID] = r.Name
+	}
+
+	permNames := make(map[uint]string, len(perms))
+	for _, perm := range perms {
+		permNames[perm.ID] = perm.Name
+		p.perms[perm.Name] = true
+		if strings.Contains(perm.Name, "*") {
+			p.patterns = append(p.patterns, perm.Name)
+		}
+	}
+
+	direct := make(map[uint][]string)
+	for _, rp := range rolePerms {
+		if name, ok := permNames[rp.PermID]; ok {
+			direct[rp.RoleID] = append(direct[rp.RoleID], name)
+		}
+	}
+
+	for _, ri := range roleIncludes {
+		p.includes[ri.RoleID] = append(p.includes[ri.RoleID], ri.IncludedID)
+	}
+
+	for _, r := range roles {
+		closure := make(map[uint]bool)
+		p.collect(r.ID, closure)
+		p.closure[r.ID] = closure
+
+		g := &grantSet{exact: make(map[string]bool)}
+		for id := range closure {
+			for _, name := range direct[id] {
+				if strings.Contains(name, "*") {
+					g.patterns = append(g.patterns, name)
+				} else {
+					g.exact[name] = true
+				}
+			}
+		}
+		p.grants[r.ID] = g
+	}
+
+	for _, ur := range userRoles {
+		p.users[ur.UserID] = append(p.users[ur.UserID], ur)
+	}
+
+	return p, nil
+}
+
+// collect collects the roles reachable from the role, the visited set also stops the cycles.
+func (p *policy) collect(roleID uint, visited map[uint]bool) {
+	if visited[roleID] {
+		return
+	}
+
+	visited[roleID] = true
+	for _, id := range p.includes[roleID] {
+		p.collect(id, visited)
+	}
+}
@@ -0,0 +1,31 @@
+package rbac_test
+
+import (
+	"testing"
+
+	"github.com/bingoohuang/gg/pkg/rbac"
+)
+
+func TestMatchPerm(t *testing.T) {
+	cases := []struct {
+		pattern, perm string
+		match         bool
+	}{
+		{"orders:read", "orders:read", true},
+		{"orders:read", "orders:write", false},
+		{"orders:*", "orders:read", true},
+		{"orders:*", "orders:items:read", true},
+		{"orders:*", "orders", false},
+		{"orders:*", "users:read", false},
+		{"*:read", "orders:read", true},
+		{"*:read", "orders:write", false},
+		{"*:read", "orders:items:read", false},
+		{"*", "orders:read", true},
+	}
+
+	for _, c := range cases {
+		if rbac.MatchPerm(c.pattern, c.perm) != c.match {
+			t.Errorf("MatchPerm(%q, %q) should be %t", c.pattern, c.perm, c.match)
+		}
+	}
+}
@@ -2,6 +2,8 @@ package rbac
 
 import (
 	"errors"
+	"sync"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -10,6 +12,7 @@ var (
 	ErrRoleNotFound       = errors.New("role not found")
 	ErrPermNotFound       = errors.New("permission not found")
 	ErrDeleteAssignedPerm = errors.New("cannot delete assigned permission")
+	ErrRoleCycle          = errors.New("role inclusion cycle")
 )
 
 // UserRole represents the relationship between users and roles
@@ -17,6 +20,8 @@ type UserRole struct {
 	ID     uint
 	UserID uint
 	RoleID uint
+	// Resource is the scope of the assignment, like tenant:3, empty for the global assignment.
+	Resource string `gorm:"size:191;default:''"`
 }
 
 // TableName sets the table name
@@ -53,12 +58,22 @@ func (p Perm) TableName() string { return tablePrefix + "perms" }
 // Rbac helps deal with permissions
 type Rbac struct {
 	DB *gorm.DB
+
+	cacheTTL time.Duration
+	mu       sync.Mutex
+	policy   *policy
+	loadedAt time.Time
+	// version is increased by Invalidate, not to cache the policy loaded before it.
+	version uint64
 }
 
 // Options has the options for initiating the package.
 type Options struct {
 	DB           *gorm.DB
 	TablesPrefix string
+	// CacheTTL is the max age of the in-memory policy cache, 0 to keep it until the next write.
+	// Set it when the tables are also written by other processes.
+	CacheTTL time.Duration
 }
 
 var (
@@ -69,7 +84,7 @@ var (
 // New initiates authority.
 func New(opts Options) *Rbac {
 	tablePrefix = opts.TablesPrefix
-	rbac = &Rbac{DB: opts.DB}
+	rbac = &Rbac{DB: opts.DB, cacheTTL: opts.CacheTTL}
 	migrateTables(opts.DB)
 	return rbac
 }
@@ -79,6 +94,8 @@ func Instance() *Rbac { return rbac }
 
 // NewRole stores a role in the database it accepts the role name.
 func (a *Rbac) NewRole(roleName string) error {
+	defer a.Invalidate()
+
 	var dbRole Role
 	r := a.DB.Where("name=?", roleName).First(&dbRole)
 	if r.Error != nil && errors.Is(r.Error, gorm.ErrRecordNotFound) {
@@ -90,6 +107,8 @@ func (a *Rbac) NewRole(roleName string) error {
 
 // NewPerm stores a permission in the database it accepts the permission name.
 func (a *Rbac) NewPerm(permName string) error {
+	defer a.Invalidate()
+
 	perm := Perm{}
 	r := a.DB.Where("name=?", permName).First(&perm)
 	if r.Error != nil && errors.Is(r.Error, gorm.ErrRecordNotFound) {
@@ -107,6 +126,8 @@ func (a *Rbac) NewPerm(permName string) error {
 // and error is returned
 // in case of success nothing is returned
 func (a *Rbac) AssignPerms(roleName string, permNames ...string) error {
+	defer a.Invalidate()
+
 	var role Role
 	if r := a.DB.Where("name=?", roleName).First(&role); r.Error != nil {
 		if errors.Is(r.Error, gorm.ErrRecordNotFound) {
@@ -147,6 +168,14 @@ func (a *Rbac) AssignPerms(roleName string, permNames ...string) error {
 // if the role name doesn't have a matching record in the data base an error is returned
 // if the user have already a role assigned to him an error is returned
 func (a *Rbac) AssignRole(userID uint, roleName string) error {
+	return a.AssignRoleOn(userID, roleName, "")
+}
+
+// AssignRoleOn assigns a given role to a user on the resource, like "user 7 is admin of tenant:3"
+// the empty resource means the global assignment which applies to all resources.
+func (a *Rbac) AssignRoleOn(userID uint, roleName, resource string) error {
+	defer a.Invalidate()
+
 	// make sure the role exist
 	var role Role
 	if r := a.DB.Where("name=?", roleName).First(&role); r.Error != nil {
@@ -158,114 +187,107 @@ func (a *Rbac) AssignRole(userID uint, roleName string) error {
 
 	// check if the role is already assigned
 	userRole := UserRole{}
-	if r := a.DB.Where("user_id=?", userID).Where("role_id=?", role.ID).First(&userRole); r.Error == nil {
+	if r := a.DB.Where("user_id=?", userID).Where("role_id=?", role.ID).
+		Where("resource=?", resource).First(&userRole); r.Error == nil {
 		return nil
 	}
 
 	// assign the role
-	a.DB.Create(&UserRole{UserID: userID, RoleID: role.ID})
-
-	return nil
+	return a.DB.Create(&UserRole{UserID: userID, RoleID: role.ID, Resource: resource}).Error
 }
 
 // CheckRole checks if a role is assigned to a user it accepts the user id as the first parameter
-// the role as the second parameter
+// the role as the second parameter, the roles included by the assigned roles are also counted.
 // it returns an error if the role is not present in database.
 func (a *Rbac) CheckRole(userID uint, roleName string) (bool, error) {
-	// find the role
-	var role Role
-	if r := a.DB.Where("name=?", roleName).First(&role); r.Error != nil {
-		if errors.Is(r.Error, gorm.ErrRecordNotFound) {
-			return false, ErrRoleNotFound
-		}
-		return false, r.Error
+	return a.CheckRoleOn(userID, roleName, "")
+}
+
+// CheckRoleOn checks if a role is assigned to a user on the resource, or globally.
+func (a *Rbac) CheckRoleOn(userID uint, roleName, resource string) (bool, error) {
+	p, err := a.loadPolicy()
+	if err != nil {
+		return false, err
 	}
 
-	// check if the role is assigned
-	userRole := UserRole{}
-	if r := a.DB.Where("user_id=?", userID).Where("role_id=?", role.ID).First(&userRole); r.Error != nil {
-		if errors.Is(r.Error, gorm.ErrRecordNotFound) {
-			return false, nil
+	roleID, ok := p.roles[roleName]
+	if !ok {
+		return false, ErrRoleNotFound
+	}
+
+	for _, ur := range p.users[userID] {
+		if ur.applies(resource) && p.closure[ur.RoleID][roleID] {
+			return true, nil
 		}
-		return false, r.Error
 	}
 
-	return true, nil
+	return false, nil
 }
 
 // CheckPerm checks if a permission is assigned to the role that's assigned to the user.
 // it accepts the user id as the first parameter
-// the permission as the second parameter
+// the permission as the second parameter, wildcard permissions like orders:* are matched.
 // it returns an error if the permission is not present in the database
 func (a *Rbac) CheckPerm(userID uint, permName string) (bool, error) {
-	var userRoles []UserRole
-	if r := a.DB.Where("user_id=?", userID).Find(&userRoles); r.Error != nil {
-		if errors.Is(r.Error, gorm.ErrRecordNotFound) {
-			return false, nil
-		}
-		return false, r.Error
+	return a.CheckPermOn(userID, permName, "")
+}
+
+// CheckPermOn checks if a permission is granted to the user by the roles assigned on the resource, or globally.
+func (a *Rbac) CheckPermOn(userID uint, permName, resource string) (bool, error) {
+	p, err := a.loadPolicy()
+	if err != nil {
+		return false, err
 	}
 
-	// prepare an array of role ids
-	var roleIDs []uint
-	for _, r := range userRoles {
-		roleIDs = append(roleIDs, r.RoleID)
+	if !p.knownPerm(permName) {
+		return false, ErrPermNotFound
 	}
 
-	// find the permission
-	var perm Perm
-	if r := a.DB.Where("name=?", permName).First(&perm); r.Error != nil {
-		if errors.Is(r.Error, gorm.ErrRecordNotFound) {
-			return false, ErrPermNotFound
+	for _, ur := range p.users[userID] {
+		if ur.applies(resource) && p.grants[ur.RoleID].has(permName) {
+			return true, nil
 		}
-		return false, r.Error
 	}
 
-	// find the role permission
-	var rolePerm RolePerm
-	r := a.DB.Where("role_id IN (?)", roleIDs).Where("perm_id=?", perm.ID).First(&rolePerm)
-	return r.Error == nil, nil
+	return false, nil
 }
 
-// CheckRolePerm checks if a role has the permission assigned
+// CheckRolePerm checks if a role has the permission assigned, directly, by wildcard or by included roles
 // it accepts the role as the first parameter
 // it accepts the permission as the second parameter
 // it returns an error if the role is not present in database
 // it returns an error if the permission is not present in database
 func (a *Rbac) CheckRolePerm(roleName string, permName string) (bool, error) {
-	// find the role
-	var role Role
-	if r := a.DB.Where("name=?", roleName).First(&role); r.Error != nil {
-		if errors.Is(r.Error, gorm.ErrRecordNotFound) {
-			return false, errors.New("role not found")
-		}
-		return false, r.Error
+	p, err := a.loadPolicy()
+	if err != nil {
+		return false, err
 	}
 
-	// find the permission
-	var perm Perm
-	if r := a.DB.Where("name=?", permName).First(&perm); r.Error != nil {
-		if errors.Is(r.Error, gorm.ErrRecordNotFound) {
-			return false, errors.New("permission not found")
-		}
-		return false, r.Error
+	roleID, ok := p.roles[roleName]
+	if !ok {
+		return false, ErrRoleNotFound
 	}
-
-	// find the rolePerm
-	var rolePerm RolePerm
-	if r := a.DB.Where("role_id=?", role.ID).Where("perm_id=?", perm.ID).First(&rolePerm); r.Error != nil {
-		if errors.Is(r.Error, gorm.ErrRecordNotFound) {
-			return false, nil
-		}
-		return false, r.Error
+	if !p.knownPerm(permName) {
+		return false, ErrPermNotFound
 	}
 
-	return true, nil
+	return p.grants[roleID].has(permName), nil
 }
 
-// RevokeRole revokes a user's role
+// RevokeRole revokes a user's role, all the assignments of the role on any resource or globally
 // it returns a error in case of any
 func (a *Rbac) RevokeRole(userID uint, roleName string) error {
+	return a.revokeRole(userID, roleName, func(db *gorm.DB) *gorm.DB { return db })
+}
+
+// RevokeRoleOn revokes a user's role on the resource, the empty resource for the global assignment only.
+func (a *Rbac) RevokeRoleOn(userID uint, roleName, resource string) error {
+	return a.revokeRole(userID, roleName, func(db *gorm.DB) *gorm.DB { return db.Where("resource=?", resource) })
+}
+
+func (a *Rbac) revokeRole(userID uint, roleName string, scope func(*gorm.DB) *gorm.DB) error {
+	defer a.Invalidate()
+
 	// find the role
 	var role Role
 	if r := a.DB.Where("name=?", roleName).First(&role); r.Error != nil {
@@ -276,12 +298,14 @@ func (a *Rbac) RevokeRole(userID uint, roleName string) error {
 	}
 
 	// revoke the role
-	return a.DB.Where("user_id=?", userID).Where("role_id=?", role.ID).Delete(UserRole{}).Error
+	return scope(a.DB.Where("user_id=?", userID).Where("role_id=?", role.ID)).Delete(UserRole{}).Error
 }
 
 // RevokePerm revokes a permission from the user's assigned role
 // it returns an error in case of any
 func (a *Rbac) RevokePerm(userID uint, permName string) error {
+	defer a.Invalidate()
+
 	// revoke the permission from all roles of the user
 	// find the user roles
 	var userRoles []UserRole
@@ -311,6 +335,8 @@ func (a *Rbac) RevokePerm(userID uint, permName string) error {
 // RevokeRolePerm revokes a permission from a given role
 // it returns an error in case of any
 func (a *Rbac) RevokeRolePerm(roleName string, permName string) error {
+	defer a.Invalidate()
+
 	// find the role
 	var role Role
 	if r := a.DB.Where("name=?", roleName).First(&role); r.Error != nil {
@@ -379,6 +405,8 @@ func (a *Rbac) GetPerms() ([]string, error) {
 // DeleteRole deletes a given role
 // if the role is assigned to a user it returns an error
 func (a *Rbac) DeleteRole(roleName string) error {
+	defer a.Invalidate()
+
 	// find the role
 	var role Role
 	if r := a.DB.Where("name=?", roleName).First(&role); r.Error != nil {
@@ -394,8 +422,9 @@ func (a *Rbac) DeleteRole(roleName string) error {
 		return ErrDeleteAssignedPerm
 	}
 
-	// revoke the assignment of permissions before deleting the role
+	// revoke the assignment of permissions and inclusions before deleting the role
 	a.DB.Where("role_id=?", role.ID).Delete(RolePerm{})
+	a.DB.Where("role_id=? OR included_id=?", role.ID, role.ID).Delete(RoleInclude{})
 	// delete the role
 	a.DB.Where("name=?", roleName).Delete(Role{})
 
@@ -404,6 +433,8 @@ func (a *Rbac) DeleteRole(roleName string) error {
 
 // DeletePerm deletes a given permission if the permission is assigned to a role it returns an error.
 func (a *Rbac) DeletePerm(permName string) error {
+	defer a.Invalidate()
+
 	// find the permission
 	var perm Perm
 	if r := a.DB.Where("name=?", permName).First(&perm); r.Error != nil {
@@ -430,4 +461,5 @@ func migrateTables(db *gorm.DB) {
 	db.AutoMigrate(&Perm{})
 	db.AutoMigrate(&RolePerm{})
 	db.AutoMigrate(&UserRole{})
+	db.AutoMigrate(&RoleInclude{})
 }
@@ -382,7 +382,21 @@ func TestRevokeRole(t *testing.T) {
 		t.Error("unexpected error while assigning role.", err)
 	}
 
-	// test
+	// the scoped revoking leaves the global assignment
+	if err = auth.AssignRoleOn(1, "role-a", "tenant:1"); err != nil {
+		t.Error("unexpected error while assigning role on resource.", err)
+	}
+	if err = auth.RevokeRoleOn(1, "role-a", "tenant:1"); err != nil {
+		t.Error("unexpected error revoking user role on resource.", err)
+	}
+	if ok, _ := auth.CheckRole(1, "role-a"); !ok {
+		t.Error("expecting the global assignment after revoking the scoped one")
+	}
+
+	// test, all the assignments are revoked
+	if err = auth.AssignRoleOn(1, "role-a", "tenant:1"); err != nil {
+		t.Error("unexpected error while assigning role on resource.", err)
+	}
 	err = auth.RevokeRole(1, "role-a")
 	if err != nil {
 		t.Error("unexpected error revoking user role.", err)
@@ -0,0 +1,414 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bingoohuang/gg/pkg/backoff"
+)
+
+// MaxErrorBodySize is the max size of the non-2xx response body read into StatusError.
+const MaxErrorBodySize = 1 << 20
+
+// StatusError is the error of the non-2xx response.
+type StatusError struct {
+	Status int
+	Header http.Header
+	// Body is the response body, truncated to MaxErrorBodySize.
+	Body []byte
+	// Detail is the body decoded by WithErrorBody, or nil.
+	Detail interface{}
+}
+
+func (e *StatusError) Error() string {
+	if len(e.Body) == 0 {
+		return fmt.Sprintf("rest: status %d", e.Status)
+	}
+	return fmt.Sprintf("rest: status %d: %s", e.Status, e.Body)
+}
+
+// Unwrap returns the Detail if it is an error, so errors.As works on the decoded error body.
+func (e *StatusError) Unwrap() error {
+	err, _ := e.Detail.(error)
+	return err
+}
+
+// Multipart is a multipart/form-data request body of Do, streamed without buffering the files.
+type Multipart struct {
+	Fields map[string]string
+	Files  []MultipartFile
+}
+
+// MultipartFile is a file part of Multipart.
+type MultipartFile struct {
+	// Field is the form field name, default is file.
+	Field string
+	// Filename is the file name, default is the base of Path.
+	Filename string
+	// Path is the file opened for each sending, when Reader is nil.
+	Path string
+	// Reader is the file content, which is replayable on retries only when it is an io.Seeker.
+	Reader io.Reader
+	// ContentType is the content type of the part, default is application/octet-stream.
+	ContentType string
+}
+
+// Option is the option of Do.
+type Option func(*Options)
+
+// Options is the options of Do.
+type Options struct {
+	Client  *http.Client
+	Header  http.Header
+	Timeout time.Duration
+	// NewBackOff creates the backoff for the retries, nil for no retry.
+	NewBackOff func() backoff.BackOff
+	// RetryIf tells whether the error is retryable, default is DefaultRetryIf.
+	RetryIf func(err error) bool
+	// DecodeError decodes the non-2xx response body into StatusError.Detail.
+	DecodeError func(*StatusError)
+}
+
+// WithClient sets the HTTP client, default is Client.
+func WithClient(v *http.Client) Option { return func(o *Options) { o.Client = v } }
+
+// WithHeader sets the request header.
+func WithHeader(k, v string) Option { return func(o *Options) { o.Header.Set(k, v) } }
+
+// WithBasicAuth sets the basic authorization header.
+func WithBasicAuth(user, password string) Option {
+	return WithHeader("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(user+":"+password)))
+}
+
+// WithTimeout sets the timeout of the whole call, including the retries.
+// For the io.ReadCloser response, the timeout also covers reading the body, until it is closed.
+func WithTimeout(v time.Duration) Option { return func(o *Options) { o.Timeout = v } }
+
+// WithRetry retries the call by the backoff created by newBackOff, e.g.
+// func() backoff.BackOff { return backoff.WithMaxRetries(backoff.NewExponentialBackOff(), 3) }.
+func WithRetry(newBackOff func() backoff.BackOff) Option {
+	return func(o *Options) { o.NewBackOff = newBackOff }
+}
+
+// WithRetryIf sets the function to tell whether the error is retryable.
+func WithRetryIf(f func(err error) bool) Option { return func(o *Options) { o.RetryIf = f } }
+
+// WithErrorBody decodes the non-2xx JSON response body into *E as StatusError.Detail,
+// and if *E implements error, it can be got by errors.As from the returned error.
+func WithErrorBody[E any]() Option {
+	return func(o *Options) {
+		o.DecodeError = func(e *StatusError) {
+			detail := new(E)
+			if len(e.Body) > 0 && json.Unmarshal(e.Body, detail) == nil {
+				e.Detail = detail
+			}
+		}
+	}
+}
+
+// DefaultRetryIf retries the transport errors, and the 429 and 5xx responses.
+func DefaultRetryIf(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.Status == http.StatusTooManyRequests || se.Status >= 500
+	}
+	return true
+}
+
+// Get executes the HTTP GET request, and decodes the response into Rsp.
+func Get[Rsp any](ctx context.Context, addr string, options ...Option) (Rsp, error) {
+	return Do[any, Rsp](ctx, http.MethodGet, addr, nil, options...)
+}
+
+// Do executes the HTTP request with the body req, and decodes the 2xx response body into Rsp.
+//
+// The request body is by the type of req:
+// nil for no body, io.Reader streamed as is, []byte or string sent as is,
+// url.Values as the form, Multipart or *Multipart as the multipart form, others encoded in JSON.
+//
+// The response body is by the type of Rsp:
+// []byte or string as is, io.ReadCloser not read and to be closed by the caller, others decoded from JSON.
+//
+// The non-2xx response fails with *StatusError.
+//
+//	type Err struct{ Message string `json:"message"` }
+//	user, err := rest.Do[CreateUser, User](ctx, "POST", addr, CreateUser{Name: "bingoo"},
+//		rest.WithErrorBody[Err](),
+//		rest.WithRetry(func() backoff.BackOff { return backoff.WithMaxRetries(backoff.NewExponentialBackOff(), 3) }))
+func Do[Req, Rsp any](ctx context.Context, method, addr string, req Req, options ...Option) (rsp Rsp, err error) {
+	o := &Options{Header: http.Header{}}
+	for _, f := range options {
+		f(o)
+	}
+	if o.Client == nil {
+		o.Client = Client
+	}
+	if o.RetryIf == nil {
+		o.RetryIf = DefaultRetryIf
+	}
+	if o.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.Timeout)
+		defer func() {
+			// the streaming body is read after Do returns, so the cancel is deferred to its Close.
+			if p, ok := any(&rsp).(*io.ReadCloser); ok && err == nil && *p != nil {
+				*p = &cancelCloser{ReadCloser: *p, cancel: cancel}
+				return
+			}
+			cancel()
+		}()
+	}
+
+	b, err := newBody(req)
+	if err != nil {
+		return rsp, err
+	}
+
+	operation := func(retryTimes int) error {
+		resp, err := o.send(ctx, method, addr, b, retryTimes)
+		if err == nil {
+			err = decodeResponse(resp, &rsp, o)
+		}
+		if err == nil {
+			return nil
+		}
+
+		var pe *backoff.PermanentError
+		if errors.As(err, &pe) || !b.replayable || !o.RetryIf(err) {
+			return backoff.Permanent(err)
+		}
+		return err
+	}
+
+	if o.NewBackOff == nil {
+		err = operation(0)
+		var pe *backoff.PermanentError
+		if errors.As(err, &pe) {
+			err = pe.Err
+		}
+		return rsp, err
+	}
+
+	return rsp, backoff.Retry(operation, backoff.WithContext(o.NewBackOff(), ctx))
+}
+
+// cancelCloser cancels the context of the request when the response body is closed.
+type cancelCloser struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelCloser) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+func (o *Options) send(ctx context.Context, method, addr string, b *body, retryTimes int) (*http.Response, error) {
+	r, contentType, err := b.open(retryTimes)
+	if err != nil {
+		return nil, backoff.Permanent(err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, addr, r)
+	if err != nil {
+		if r != nil {
+			_ = r.Close()
+		}
+		return nil, backoff.Permanent(err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	for k, v := range o.Header {
+		req.Header[k] = v
+	}
+
+	return o.Client.Do(req)
+}
+
+func decodeResponse[Rsp any](resp *http.Response, rsp *Rsp, o *Options) error {
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		data, err := io.ReadAll(io.LimitReader(resp.Body, MaxErrorBodySize))
+		_ = DiscardCloseBody(resp)
+		if err != nil {
+			return err
+		}
+
+		e := &StatusError{Status: resp.StatusCode, Header: resp.Header, Body: data}
+		if o.DecodeError != nil {
+			o.DecodeError(e)
+		}
+		return e
+	}
+
+	switch p := any(rsp).(type) {
+	case *io.ReadCloser:
+		*p = resp.Body
+		return nil
+	case *[]byte:
+		data, err := ReadCloseBody(resp)
+		*p = data
+		return err
+	case *string:
+		data, err := ReadCloseBody(resp)
+		*p = string(data)
+		return err
+	}
+
+	defer DiscardCloseBody(resp)
+
+	if err := json.NewDecoder(resp.Body).Decode(rsp); err != nil && err != io.EOF {
+		return backoff.Permanent(fmt.Errorf("rest: decode response: %w", err))
+	}
+	return nil
+}
+
+// body is the request body, which is opened for each sending.
+type body struct {
+	open       func(retryTimes int) (io.ReadCloser, string, error)
+	replayable bool
+}
+
+func newBody(req interface{}) (*body, error) {
+	switch v := req.(type) {
+	case nil:
+		return &body{replayable: true, open: func(int) (io.ReadCloser, string, error) { return nil, "", nil }}, nil
+	case Multipart:
+		return newMultipartBody(&v), nil
+	case *Multipart:
+		return newMultipartBody(v), nil
+	case io.Reader:
+		return newReaderBody(v, "application/octet-stream"), nil
+	case []byte:
+		return newBytesBody(v, If(IsJSONBytes(v), ContentTypeJSON, "text/plain; charset=utf-8")), nil
+	case string:
+		return newBytesBody([]byte(v), If(IsJSONBytes([]byte(v)), ContentTypeJSON, "text/plain; charset=utf-8")), nil
+	case url.Values:
+		return newBytesBody([]byte(v.Encode()), "application/x-www-form-urlencoded"), nil
+	}
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("rest: encode request: %w", err)
+	}
+	return newBytesBody(data, ContentTypeJSON), nil
+}
+
+func newBytesBody(data []byte, contentType string) *body {
+	return &body{replayable: true, open: func(int) (io.ReadCloser, string, error) {
+		return io.NopCloser(bytes.NewReader(data)), contentType, nil
+	}}
+}
+
+func newReaderBody(r io.Reader, contentType string) *body {
+	seeker, replayable := r.(io.Seeker)
+	return &body{replayable: replayable, open: func(retryTimes int) (io.ReadCloser, string, error) {
+		if retryTimes > 0 {
+			if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+				return nil, "", err
+			}
+		}
+		return io.NopCloser(r), contentType, nil
+	}}
+}
+
+func newMultipartBody(m *Multipart) *body {
+	replayable := true
+	for _, f := range m.Files {
+		if _, ok := f.Reader.(io.Seeker); f.Reader != nil && !ok {
+			replayable = false
+		}
+	}
+
+	return &body{replayable: replayable, open: func(retryTimes int) (io.ReadCloser, string, error) {
+		pr, pw := io.Pipe()
+		w := multipart.NewWriter(pw)
+		go func() { _ = pw.CloseWithError(m.write(w, retryTimes)) }()
+		return pr, w.FormDataContentType(), nil
+	}}
+}
+
+func (m *Multipart) write(w *multipart.Writer, retryTimes int) error {
+	keys := make([]string, 0, len(m.Fields))
+	for k := range m.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := w.WriteField(k, m.Fields[k]); err != nil {
+			return err
+		}
+	}
+
+	for _, f := range m.Files {
+		if err := f.write(w, retryTimes); err != nil {
+			return err
+		}
+	}
+
+	return w.Close()
+}
+
+func (f MultipartFile) write(w *multipart.Writer, retryTimes int) error {
+	r := f.Reader
+	if r == nil {
+		file, err := os.Open(f.Path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	} else if s, ok := r.(io.Seeker); ok && retryTimes > 0 {
+		if _, err := s.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+	}
+
+	field := f.Field
+	if field == "" {
+		field = "file"
+	}
+	filename := f.Filename
+	if filename == "" {
+		filename = filepath.Base(f.Path)
+	}
+	contentType := f.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+		escapeQuotes(field), escapeQuotes(filename)))
+	h.Set("Content-Type", contentType)
+	part, err := w.CreatePart(h)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(part, r)
+	return err
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func escapeQuotes(s string) string { return quoteEscaper.Replace(s) }
@@ -0,0 +1,149 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bingoohuang/gg/pkg/backoff"
+	"github.com/stretchr/testify/assert"
+)
+
+type doUser struct {
+	Name string `json:"name"`
+}
+
+type doError struct {
+	Code string `json:"code"`
+}
+
+func (e *doError) Error() string { return e.Code }
+
+func TestDoJSON(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != ContentTypeJSON {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		var u doUser
+		_ = json.NewDecoder(r.Body).Decode(&u)
+		if u.Name == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"code":"NAME_REQUIRED"}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(doUser{Name: strings.ToUpper(u.Name)})
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	u, err := Do[doUser, doUser](ctx, http.MethodPost, ts.URL, doUser{Name: "bingoo"}, WithClient(ts.Client()))
+	assert.Nil(t, err)
+	assert.Equal(t, "BINGOO", u.Name)
+
+	_, err = Do[doUser, doUser](ctx, http.MethodPost, ts.URL, doUser{}, WithErrorBody[doError]())
+	var se *StatusError
+	assert.True(t, errors.As(err, &se))
+	assert.Equal(t, http.StatusBadRequest, se.Status)
+	var de *doError
+	assert.True(t, errors.As(err, &de))
+	assert.Equal(t, "NAME_REQUIRED", de.Code)
+}
+
+func TestDoStreamAndMultipart(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/stream" {
+			_, _ = io.Copy(w, r.Body)
+			return
+		}
+		if r.URL.Path == "/slow" {
+			_, _ = w.Write([]byte("slow"))
+			w.(http.Flusher).Flush()
+			time.Sleep(50 * time.Millisecond)
+			_, _ = w.Write([]byte("ly"))
+			return
+		}
+
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var parts []string
+		parts = append(parts, r.FormValue("k"))
+		for _, f := range r.MultipartForm.File["file"] {
+			file, _ := f.Open()
+			data, _ := io.ReadAll(file)
+			_ = file.Close()
+			parts = append(parts, f.Filename+"="+string(data))
+		}
+		_, _ = w.Write([]byte(strings.Join(parts, ",")))
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	body, err := Do[io.Reader, io.ReadCloser](ctx, http.MethodPost, ts.URL+"/stream", strings.NewReader("streamed"))
+	assert.Nil(t, err)
+	data, _ := io.ReadAll(body)
+	_ = body.Close()
+	assert.Equal(t, "streamed", string(data))
+
+	// the timeout covers reading the body, which is not canceled when Do returns
+	body, err = Do[any, io.ReadCloser](ctx, http.MethodGet, ts.URL+"/slow", nil, WithTimeout(5*time.Second))
+	assert.Nil(t, err)
+	data, err = io.ReadAll(body)
+	assert.Nil(t, err)
+	assert.Nil(t, body.Close())
+	assert.Equal(t, "slowly", string(data))
+
+	s, err := Do[*Multipart, string](ctx, http.MethodPost, ts.URL+"/upload", &Multipart{
+		Fields: map[string]string{"k": "v"},
+		Files: []MultipartFile{
+			{Filename: "a.txt", Reader: strings.NewReader("aaa")},
+			{Filename: "b.txt", Reader: strings.NewReader("bbb")},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "v,a.txt=aaa,b.txt=bbb", s)
+}
+
+func TestDoRetry(t *testing.T) {
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&hits, 1) {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			data, _ := io.ReadAll(r.Body)
+			_, _ = w.Write(data)
+		}
+	}))
+	defer ts.Close()
+
+	retry := WithRetry(func() backoff.BackOff { return backoff.WithMaxRetries(&backoff.ZeroBackOff{}, 3) })
+	s, err := Do[string, string](context.Background(), http.MethodPut, ts.URL, "replayed", retry)
+	assert.Nil(t, err)
+	assert.Equal(t, "replayed", s)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&hits))
+
+	// the non-seekable reader is not replayable
+	atomic.StoreInt32(&hits, 0)
+	_, err = Do[io.Reader, string](context.Background(), http.MethodPut, ts.URL, io.LimitReader(strings.NewReader("x"), 1), retry)
+	var se *StatusError
+	assert.True(t, errors.As(err, &se))
+	assert.Equal(t, http.StatusServiceUnavailable, se.Status)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&hits))
+
+	// RetryIf stops the retries
+	_, err = Get[string](context.Background(), ts.URL+"/x", retry, WithRetryIf(func(error) bool { return false }))
+	assert.True(t, errors.As(err, &se))
+	assert.Equal(t, http.StatusTooManyRequests, se.Status)
+}
@@ -23,6 +23,8 @@ type Rest struct {
 	Context          context.Context
 	Timeout          time.Duration
 	BasicAuth        string
+	// HTTPClient is the HTTP client to send the request, default is Client.
+	HTTPClient *http.Client
 }
 
 // Post execute HTTP POST request.
@@ -59,6 +61,8 @@ type Rsp struct {
 	Cost   time.Duration
 }
 
+// Client is the default HTTP client of Rest and Do, which skips the TLS verification.
+// Use Rest.HTTPClient or WithClient to send by another one.
 var Client = &http.Client{
 	// Timeout: 10 * time.Second,
 	Transport: &http.Transport{
@@ -102,7 +106,11 @@ func (r Rest) do(method string) (*Rsp, error) {
 	}
 
 	start := time.Now()
-	resp, err := Client.Do(req)
+	client := r.HTTPClient
+	if client == nil {
+		client = Client
+	}
+	resp, err := client.Do(req)
 	cost := time.Since(start)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,362 @@
+package resty
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bingoohuang/gg/pkg/ttlcache"
+)
+
+//‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾
+// Response cache
+//_______________________________________________________________________
+
+// CacheStatus is the status of the response cache for a request.
+type CacheStatus string
+
+const (
+	// CacheBypass means the request is not cacheable, or the cache is not enabled.
+	CacheBypass CacheStatus = ""
+	// CacheMiss means the response is fetched from the server.
+	CacheMiss CacheStatus = "MISS"
+	// CacheHit means the fresh response is served from the cache, without any request to the server.
+	CacheHit CacheStatus = "HIT"
+	// CacheRevalidated means the stale response is served from the cache after the server
+	// responded 304 Not Modified to the conditional request.
+	CacheRevalidated CacheStatus = "REVALIDATED"
+	// CacheStale means the stale response is served from the cache because the server failed,
+	// as allowed by stale-if-error.
+	CacheStale CacheStatus = "STALE"
+)
+
+// CacheStorage is the storage of the cached responses.
+// NewMemoryCacheStorage creates one on pkg/ttlcache, and badgerdb.CacheStorage is one on disk.
+type CacheStorage interface {
+	// Get returns the value of the key, or nil if not found.
+	Get(key string) ([]byte, error)
+	// Set sets the value of the key, which expires after the ttl.
+	Set(key string, value []byte, ttl time.Duration) error
+	// Delete deletes the key.
+	Delete(key string) error
+}
+
+// NewMemoryCacheStorage creates an in-memory CacheStorage on ttlcache, with the options like ttlcache.WithCapacity.
+func NewMemoryCacheStorage(opts ...ttlcache.Option[string, []byte]) CacheStorage {
+	return &memoryCacheStorage{cache: ttlcache.New[string, []byte](opts...)}
+}
+
+type memoryCacheStorage struct {
+	cache *ttlcache.Cache[string, []byte]
+}
+
+func (s *memoryCacheStorage) Get(key string) ([]byte, error) {
+	if item := s.cache.Get(key); item != nil {
+		return item.Value(), nil
+	}
+	return nil, nil
+}
+
+func (s *memoryCacheStorage) Set(key string, value []byte, ttl time.Duration) error {
+	s.cache.Set(key, value, ttl)
+	return nil
+}
+
+func (s *memoryCacheStorage) Delete(key string) error {
+	s.cache.Delete(key)
+	return nil
+}
+
+// ResponseCache caches the responses of GET and HEAD requests as a private HTTP cache,
+// honouring Cache-Control, Expires, ETag/If-None-Match, Last-Modified/If-Modified-Since and stale-if-error.
+type ResponseCache struct {
+	Storage CacheStorage
+	// Retention is how long the expired responses with validators are kept for the revalidation, 24h when zero.
+	Retention time.Duration
+	// StaleIfError is the default duration after expired to serve the stale response when the server fails,
+	// the stale-if-error of the Cache-Control response header takes precedence.
+	StaleIfError time.Duration
+
+	now func() time.Time
+}
+
+// NewResponseCache creates a ResponseCache on the storage.
+func NewResponseCache(storage CacheStorage) *ResponseCache {
+	return &ResponseCache{Storage: storage, Retention: defaultCacheRetention, now: time.Now}
+}
+
+const defaultCacheRetention = 24 * time.Hour
+
+// timeNow returns the current time, the ResponseCache can be created as a literal without now.
+func (c *ResponseCache) timeNow() time.Time {
+	if c.now == nil {
+		return time.Now()
+	}
+	return c.now()
+}
+
+func (c *ResponseCache) retention() time.Duration {
+	if c.Retention == 0 {
+		return defaultCacheRetention
+	}
+	return c.Retention
+}
+
+// cacheEntry is the cached response.
+type cacheEntry struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	// Vary holds the request header values named by the Vary response header.
+	Vary map[string]string
+	// StoredAt is when the response is received or revalidated.
+	StoredAt     time.Time
+	Lifetime     time.Duration
+	StaleIfError time.Duration
+	NoCache      bool
+}
+
+func (e *cacheEntry) fresh(now time.Time) bool {
+	return !e.NoCache && now.Sub(e.StoredAt) < e.Lifetime
+}
+
+func (e *cacheEntry) staleUsable(now time.Time) bool {
+	return now.Sub(e.StoredAt) < e.Lifetime+e.StaleIfError
+}
+
+func (e *cacheEntry) hasValidators() bool {
+	return e.Header.Get(hdrETagKey) != "" || e.Header.Get(hdrLastModifiedKey) != ""
+}
+
+func (e *cacheEntry) matches(req *http.Request) bool {
+	for k, v := range e.Vary {
+		if k == "*" || req.Header.Get(k) != v {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *cacheEntry) response(req *http.Request, now time.Time) *http.Response {
+	header := e.Header.Clone()
+	header.Set(hdrAgeKey, strconv.Itoa(int(now.Sub(e.StoredAt).Seconds())))
+	return &http.Response{
+		Status:        strconv.Itoa(e.StatusCode) + " " + http.StatusText(e.StatusCode),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+var (
+	hdrCacheControlKey    = http.CanonicalHeaderKey("Cache-Control")
+	hdrExpiresKey         = http.CanonicalHeaderKey("Expires")
+	hdrDateKey            = http.CanonicalHeaderKey("Date")
+	hdrAgeKey             = http.CanonicalHeaderKey("Age")
+	hdrETagKey            = http.CanonicalHeaderKey("ETag")
+	hdrLastModifiedKey    = http.CanonicalHeaderKey("Last-Modified")
+	hdrIfNoneMatchKey     = http.CanonicalHeaderKey("If-None-Match")
+	hdrIfModifiedSinceKey = http.CanonicalHeaderKey("If-Modified-Since")
+	hdrVaryKey            = http.CanonicalHeaderKey("Vary")
+)
+
+// parseCacheControl parses the Cache-Control header into the directives and their values.
+func parseCacheControl(h http.Header) map[string]string {
+	cc := map[string]string{}
+	for _, v := range h.Values(hdrCacheControlKey) {
+		for _, part := range strings.Split(v, ",") {
+			if part = strings.TrimSpace(part); part == "" {
+				continue
+			}
+			k, val, _ := strings.Cut(part, "=")
+			cc[strings.ToLower(strings.TrimSpace(k))] = strings.Trim(strings.TrimSpace(val), `"`)
+		}
+	}
+	return cc
+}
+
+func parseSeconds(cc map[string]string, directive string) (time.Duration, bool) {
+	v, ok := cc[directive]
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return time.Duration(n) * time.Second, true
+}
+
+func cacheKey(req *http.Request) string { return req.Method + " " + req.URL.String() }
+
+func (c *ResponseCache) load(key string, req *http.Request) *cacheEntry {
+	data, err := c.Storage.Get(key)
+	if err != nil || data == nil {
+		return nil
+	}
+
+	var e cacheEntry
+	if err := json.Unmarshal(data, &e); err != nil || !e.matches(req) {
+		return nil
+	}
+	return &e
+}
+
+func (c *ResponseCache) store(key string, e *cacheEntry) {
+	ttl := e.Lifetime + e.StaleIfError
+	if retention := c.retention(); e.hasValidators() && ttl < e.Lifetime+retention {
+		ttl = e.Lifetime + retention
+	}
+	if ttl <= 0 {
+		_ = c.Storage.Delete(key)
+		return
+	}
+
+	if data, err := json.Marshal(e); err == nil {
+		_ = c.Storage.Set(key, data, ttl)
+	}
+}
+
+// newEntry creates a cache entry from the response, or nil if it is not cacheable.
+func (c *ResponseCache) newEntry(req *http.Request, resp *http.Response, body []byte, now time.Time) *cacheEntry {
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNonAuthoritativeInfo, http.StatusMovedPermanently, http.StatusNotFound, http.StatusGone:
+	default:
+		return nil
+	}
+
+	cc := parseCacheControl(resp.Header)
+	if _, ok := cc["no-store"]; ok {
+		return nil
+	}
+
+	e := &cacheEntry{StatusCode: resp.StatusCode, Header: resp.Header.Clone(), Body: body, StoredAt: now}
+	_, e.NoCache = cc["no-cache"]
+	if maxAge, ok := parseSeconds(cc, "max-age"); ok {
+		e.Lifetime = maxAge
+	} else if expires, err := http.ParseTime(resp.Header.Get(hdrExpiresKey)); err == nil {
+		date, err := http.ParseTime(resp.Header.Get(hdrDateKey))
+		if err != nil {
+			date = now
+		}
+		e.Lifetime = expires.Sub(date)
+	}
+	if age, err := strconv.Atoi(resp.Header.Get(hdrAgeKey)); err == nil && age > 0 {
+		e.Lifetime -= time.Duration(age) * time.Second
+	}
+	if e.Lifetime < 0 {
+		e.Lifetime = 0
+	}
+
+	e.StaleIfError = c.StaleIfError
+	if d, ok := parseSeconds(cc, "stale-if-error"); ok {
+		e.StaleIfError = d
+	}
+	if _, ok := cc["must-revalidate"]; ok {
+		e.StaleIfError = 0
+	}
+
+	if e.Lifetime == 0 && !e.hasValidators() {
+		return nil
+	}
+
+	for _, v := range resp.Header.Values(hdrVaryKey) {
+		for _, name := range strings.Split(v, ",") {
+			if name = http.CanonicalHeaderKey(strings.TrimSpace(name)); name != "" {
+				if e.Vary == nil {
+					e.Vary = map[string]string{}
+				}
+				e.Vary[name] = req.Header.Get(name)
+			}
+		}
+	}
+
+	return e
+}
+
+// do sends the request through the cache.
+func (c *ResponseCache) do(send func(*http.Request) (*http.Response, error), req *http.Request) (*http.Response, CacheStatus, error) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead ||
+		req.Header.Get(hdrIfNoneMatchKey) != "" || req.Header.Get(hdrIfModifiedSinceKey) != "" {
+		resp, err := send(req)
+		return resp, CacheBypass, err
+	}
+
+	reqCC := parseCacheControl(req.Header)
+	if _, ok := reqCC["no-store"]; ok {
+		resp, err := send(req)
+		return resp, CacheBypass, err
+	}
+
+	key := cacheKey(req)
+	now := c.timeNow()
+	entry := c.load(key, req)
+	if entry != nil {
+		_, noCache := reqCC["no-cache"]
+		if maxAge, ok := parseSeconds(reqCC, "max-age"); ok && now.Sub(entry.StoredAt) >= maxAge {
+			noCache = true
+		}
+		if !noCache && entry.fresh(now) {
+			return entry.response(req, now), CacheHit, nil
+		}
+
+		if etag := entry.Header.Get(hdrETagKey); etag != "" {
+			req.Header.Set(hdrIfNoneMatchKey, etag)
+		}
+		if lm := entry.Header.Get(hdrLastModifiedKey); lm != "" {
+			req.Header.Set(hdrIfModifiedSinceKey, lm)
+		}
+	}
+
+	resp, err := send(req)
+	now = c.timeNow()
+	if err != nil || resp.StatusCode >= 500 {
+		if entry != nil && entry.staleUsable(now) {
+			if resp != nil {
+				closeq(resp.Body)
+			}
+			return entry.response(req, now), CacheStale, nil
+		}
+		return resp, CacheMiss, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && entry != nil {
+		closeq(resp.Body)
+		// the stored headers updated by the 304 ones, without the Age of the stored response,
+		// which is received before and should not shorten the refreshed lifetime.
+		entry.Header.Del(hdrAgeKey)
+		for k, v := range resp.Header {
+			entry.Header[k] = v
+		}
+		revalidated := &http.Response{StatusCode: entry.StatusCode, Header: entry.Header}
+		if updated := c.newEntry(req, revalidated, entry.Body, now); updated != nil {
+			c.store(key, updated)
+			entry = updated
+		}
+		return entry.response(req, now), CacheRevalidated, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	closeq(resp.Body)
+	if err != nil {
+		return resp, CacheMiss, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	if e := c.newEntry(req, resp, body, now); e != nil {
+		c.store(key, e)
+	} else if entry != nil {
+		_ = c.Storage.Delete(key)
+	}
+
+	return resp, CacheMiss, nil
+}
@@ -0,0 +1,217 @@
+package resty
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type cacheTestClock struct{ now time.Time }
+
+func (c *cacheTestClock) Now() time.Time { return c.now }
+
+func newCacheTestClient(t *testing.T, handler http.HandlerFunc) (*Client, *cacheTestClock, *int32) {
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		handler(w, r)
+	}))
+	t.Cleanup(ts.Close)
+
+	clock := &cacheTestClock{now: time.Now()}
+	cache := NewResponseCache(NewMemoryCacheStorage())
+	cache.now = clock.Now
+	return New().SetBaseURL(ts.URL).SetResponseCache(cache), clock, &hits
+}
+
+func TestResponseCacheMaxAge(t *testing.T) {
+	c, clock, hits := newCacheTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		_, _ = w.Write([]byte("config"))
+	})
+
+	resp, err := c.R().Get("/config")
+	assert.Nil(t, err)
+	assert.Equal(t, CacheMiss, resp.CacheStatus())
+
+	resp, err = c.R().SetTrace(true).Get("/config")
+	assert.Nil(t, err)
+	assert.Equal(t, CacheHit, resp.CacheStatus())
+	assert.Equal(t, CacheHit, resp.Request.TraceInfo().CacheStatus)
+	assert.Equal(t, "config", resp.String())
+	assert.Equal(t, int32(1), atomic.LoadInt32(hits))
+
+	// the request no-cache forces the revalidation, which is a full fetch without validators
+	resp, err = c.R().SetHeader("Cache-Control", "no-cache").Get("/config")
+	assert.Nil(t, err)
+	assert.Equal(t, CacheMiss, resp.CacheStatus())
+	assert.Equal(t, int32(2), atomic.LoadInt32(hits))
+
+	clock.now = clock.now.Add(61 * time.Second)
+	resp, err = c.R().Get("/config")
+	assert.Nil(t, err)
+	assert.Equal(t, CacheMiss, resp.CacheStatus())
+	assert.Equal(t, int32(3), atomic.LoadInt32(hits))
+
+	// POST is not cached
+	resp, err = c.R().Post("/config")
+	assert.Nil(t, err)
+	assert.Equal(t, CacheBypass, resp.CacheStatus())
+}
+
+func TestResponseCacheETag(t *testing.T) {
+	c, _, hits := newCacheTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Cache-Control", "no-cache")
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		_, _ = w.Write([]byte("v1"))
+	})
+
+	resp, err := c.R().Get("/etag")
+	assert.Nil(t, err)
+	assert.Equal(t, CacheMiss, resp.CacheStatus())
+
+	resp, err = c.R().Get("/etag")
+	assert.Nil(t, err)
+	assert.Equal(t, CacheRevalidated, resp.CacheStatus())
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	assert.Equal(t, "v1", resp.String())
+	assert.Equal(t, int32(2), atomic.LoadInt32(hits))
+}
+
+func TestResponseCacheRevalidatedFresh(t *testing.T) {
+	c, clock, hits := newCacheTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Cache-Control", "max-age=60")
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		_, _ = w.Write([]byte("v1"))
+	})
+
+	resp, err := c.R().Get("/revalidate")
+	assert.Nil(t, err)
+	assert.Equal(t, CacheMiss, resp.CacheStatus())
+
+	clock.now = clock.now.Add(100 * time.Second)
+	resp, err = c.R().Get("/revalidate")
+	assert.Nil(t, err)
+	assert.Equal(t, CacheRevalidated, resp.CacheStatus())
+
+	// the revalidated response is fresh for another max-age
+	clock.now = clock.now.Add(time.Second)
+	resp, err = c.R().Get("/revalidate")
+	assert.Nil(t, err)
+	assert.Equal(t, CacheHit, resp.CacheStatus())
+	assert.Equal(t, "v1", resp.String())
+	assert.Equal(t, int32(2), atomic.LoadInt32(hits))
+}
+
+func TestResponseCacheLiteral(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		_, _ = w.Write([]byte("literal"))
+	}))
+	defer ts.Close()
+
+	c := New().SetBaseURL(ts.URL).SetResponseCache(&ResponseCache{Storage: NewMemoryCacheStorage()})
+	resp, err := c.R().Get("/literal")
+	assert.Nil(t, err)
+	assert.Equal(t, CacheMiss, resp.CacheStatus())
+
+	resp, err = c.R().Get("/literal")
+	assert.Nil(t, err)
+	assert.Equal(t, CacheHit, resp.CacheStatus())
+}
+
+func TestResponseCacheLastModified(t *testing.T) {
+	lastModified := time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)
+	c, clock, _ := newCacheTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Last-Modified", lastModified)
+		w.Header().Set("Expires", time.Now().Add(10*time.Second).UTC().Format(http.TimeFormat))
+		w.Header().Set("Date", time.Now().UTC().Format(http.TimeFormat))
+		if r.Header.Get("If-Modified-Since") == lastModified {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		_, _ = w.Write([]byte("lm"))
+	})
+
+	resp, err := c.R().Get("/lm")
+	assert.Nil(t, err)
+	assert.Equal(t, CacheMiss, resp.CacheStatus())
+
+	resp, err = c.R().Get("/lm")
+	assert.Nil(t, err)
+	assert.Equal(t, CacheHit, resp.CacheStatus())
+
+	clock.now = clock.now.Add(time.Minute)
+	resp, err = c.R().Get("/lm")
+	assert.Nil(t, err)
+	assert.Equal(t, CacheRevalidated, resp.CacheStatus())
+	assert.Equal(t, "lm", resp.String())
+}
+
+func TestResponseCacheStaleIfError(t *testing.T) {
+	var failing int32
+	c, clock, _ := newCacheTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&failing) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Cache-Control", "max-age=10, stale-if-error=60")
+		_, _ = w.Write([]byte("ok"))
+	})
+
+	_, err := c.R().Get("/stale")
+	assert.Nil(t, err)
+
+	atomic.StoreInt32(&failing, 1)
+	clock.now = clock.now.Add(30 * time.Second)
+	resp, err := c.R().Get("/stale")
+	assert.Nil(t, err)
+	assert.Equal(t, CacheStale, resp.CacheStatus())
+	assert.Equal(t, "ok", resp.String())
+
+	clock.now = clock.now.Add(time.Minute)
+	resp, err = c.R().Get("/stale")
+	assert.Nil(t, err)
+	assert.Equal(t, CacheMiss, resp.CacheStatus())
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode())
+}
+
+func TestResponseCacheNoStoreAndVary(t *testing.T) {
+	c, _, hits := newCacheTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/no-store" {
+			w.Header().Set("Cache-Control", "no-store, max-age=60")
+		} else {
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Header().Set("Vary", "Accept-Language")
+		}
+		_, _ = w.Write([]byte(r.Header.Get("Accept-Language")))
+	})
+
+	for i := 0; i < 2; i++ {
+		resp, err := c.R().Get("/no-store")
+		assert.Nil(t, err)
+		assert.Equal(t, CacheMiss, resp.CacheStatus())
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(hits))
+
+	resp, _ := c.R().SetHeader("Accept-Language", "en").Get("/vary")
+	assert.Equal(t, CacheMiss, resp.CacheStatus())
+	resp, _ = c.R().SetHeader("Accept-Language", "en").Get("/vary")
+	assert.Equal(t, CacheHit, resp.CacheStatus())
+	assert.Equal(t, "en", resp.String())
+	resp, _ = c.R().SetHeader("Accept-Language", "zh").Get("/vary")
+	assert.Equal(t, CacheMiss, resp.CacheStatus())
+	assert.Equal(t, "zh", resp.String())
+}
@@ -117,6 +117,8 @@ type Client struct {
 	requestLog      RequestLogCallback
 	responseLog     ResponseLogCallback
 	errorHooks      []ErrorHook
+	responseCache   *ResponseCache
+	rateLimiter     *RateLimiter
 }
 
 // User type is to hold a username and password information
@@ -732,6 +734,27 @@ func (c *Client) SetOutputDirectory(dirPath string) *Client {
 	return c
 }
 
+// SetResponseCache method enables the response cache of GET and HEAD requests, nil to disable.
+//
+//	client.SetResponseCache(resty.NewResponseCache(resty.NewMemoryCacheStorage()))
+//
+// See `Response.CacheStatus` to know whether the response is served from the cache.
+func (c *Client) SetResponseCache(cache *ResponseCache) *Client {
+	c.responseCache = cache
+	return c
+}
+
+// SetRateLimiter method sets the limiter of the request rate and the concurrent requests, nil to disable.
+//
+//	client.SetRateLimiter(resty.NewRateLimiter(resty.RateLimit{Rate: 10, Burst: 5, MaxInFlight: 4}))
+//
+// The cache hits of `SetResponseCache` are not limited.
+// See `TraceInfo.RateLimitWait` for the wait of a request.
+func (c *Client) SetRateLimiter(limiter *RateLimiter) *Client {
+	c.rateLimiter = limiter
+	return c
+}
+
 // SetTransport method sets custom `*http.Transport` or any `http.RoundTripper`
 // compatible interface implementation in the resty client.
 //
@@ -903,7 +926,17 @@ func (c *Client) execute(req *Request) (*Response, error) {
 	req.RawRequest.Body = newRequestBodyReleaser(req.RawRequest.Body, req.bodyBuf)
 
 	req.Time = time.Now()
-	resp, err := c.httpClient.Do(req.RawRequest)
+	send := c.httpClient.Do
+	if c.rateLimiter != nil {
+		send = func(raw *http.Request) (*http.Response, error) { return c.rateLimiter.do(req, raw, c.httpClient.Do) }
+	}
+
+	var resp *http.Response
+	if c.responseCache != nil {
+		resp, req.cacheStatus, err = c.responseCache.do(send, req.RawRequest)
+	} else {
+		resp, err = send(req.RawRequest)
+	}
 
 	response := &Response{
 		Request:     req,
@@ -0,0 +1,177 @@
+package resty
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+)
+
+// HAR 1.2 (HTTP Archive) types, see http://www.softwareishard.com/blog/har-12-spec/
+// Only the fields needed to inspect the recordings in the browser tools are written.
+type (
+	harLog struct {
+		Log harContent `json:"log"`
+	}
+	harContent struct {
+		Version string     `json:"version"`
+		Creator harCreator `json:"creator"`
+		Entries []harEntry `json:"entries"`
+	}
+	harCreator struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+	harEntry struct {
+		StartedDateTime string      `json:"startedDateTime"`
+		Time            float64     `json:"time"`
+		Request         harRequest  `json:"request"`
+		Response        harResponse `json:"response"`
+		Cache           struct{}    `json:"cache"`
+		Timings         harTimings  `json:"timings"`
+	}
+	harRequest struct {
+		Method      string         `json:"method"`
+		URL         string         `json:"url"`
+		HTTPVersion string         `json:"httpVersion"`
+		Cookies     []harNameValue `json:"cookies"`
+		Headers     []harNameValue `json:"headers"`
+		QueryString []harNameValue `json:"queryString"`
+		PostData    *harPostData   `json:"postData,omitempty"`
+		HeadersSize int            `json:"headersSize"`
+		BodySize    int            `json:"bodySize"`
+	}
+	harResponse struct {
+		Status      int            `json:"status"`
+		StatusText  string         `json:"statusText"`
+		HTTPVersion string         `json:"httpVersion"`
+		Cookies     []harNameValue `json:"cookies"`
+		Headers     []harNameValue `json:"headers"`
+		Content     harBody        `json:"content"`
+		RedirectURL string         `json:"redirectURL"`
+		HeadersSize int            `json:"headersSize"`
+		BodySize    int            `json:"bodySize"`
+	}
+	harNameValue struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+	harPostData struct {
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text"`
+	}
+	harBody struct {
+		Size     int    `json:"size"`
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text,omitempty"`
+		Encoding string `json:"encoding,omitempty"`
+	}
+	harTimings struct {
+		Send    float64 `json:"send"`
+		Wait    float64 `json:"wait"`
+		Receive float64 `json:"receive"`
+	}
+)
+
+func harHeaders(h http.Header) []harNameValue {
+	nvs := make([]harNameValue, 0, len(h))
+	for k, vv := range h {
+		for _, v := range vv {
+			nvs = append(nvs, harNameValue{Name: k, Value: v})
+		}
+	}
+	sort.SliceStable(nvs, func(i, j int) bool { return nvs[i].Name < nvs[j].Name })
+	return nvs
+}
+
+func harQueryString(rawURL string) []harNameValue {
+	nvs := []harNameValue{}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nvs
+	}
+	for k, vv := range u.Query() {
+		for _, v := range vv {
+			nvs = append(nvs, harNameValue{Name: k, Value: v})
+		}
+	}
+	sort.SliceStable(nvs, func(i, j int) bool { return nvs[i].Name < nvs[j].Name })
+	return nvs
+}
+
+// WriteHAR writes the cassette in the HAR 1.2 format, which can be imported into the browser tools.
+func (c *Cassette) WriteHAR(w io.Writer) error {
+	entries := make([]harEntry, 0, len(c.Interactions))
+	for _, i := range c.Interactions {
+		ms := float64(i.Duration.Microseconds()) / 1000
+		proto := i.Response.Proto
+		if proto == "" {
+			proto = "HTTP/1.1"
+		}
+
+		req := harRequest{
+			Method:      i.Request.Method,
+			URL:         i.Request.URL,
+			HTTPVersion: proto,
+			Cookies:     []harNameValue{},
+			Headers:     harHeaders(i.Request.Header),
+			QueryString: harQueryString(i.Request.URL),
+			HeadersSize: -1,
+			BodySize:    len(i.Request.Body.Bytes()),
+		}
+		if req.BodySize > 0 {
+			req.PostData = &harPostData{MimeType: i.Request.Header.Get(hdrContentTypeKey), Text: i.Request.Body.Text}
+		}
+
+		content := harBody{
+			Size:     len(i.Response.Body.Bytes()),
+			MimeType: i.Response.Header.Get(hdrContentTypeKey),
+			Text:     i.Response.Body.Text,
+		}
+		if i.Response.Body.Base64 != "" {
+			content.Text, content.Encoding = i.Response.Body.Base64, "base64"
+		}
+
+		entries = append(entries, harEntry{
+			StartedDateTime: i.RecordedAt.Format("2006-01-02T15:04:05.000Z07:00"),
+			Time:            ms,
+			Request:         req,
+			Response: harResponse{
+				Status:      i.Response.StatusCode,
+				StatusText:  http.StatusText(i.Response.StatusCode),
+				HTTPVersion: proto,
+				Cookies:     []harNameValue{},
+				Headers:     harHeaders(i.Response.Header),
+				Content:     content,
+				RedirectURL: i.Response.Header.Get(hdrLocationKey),
+				HeadersSize: -1,
+				BodySize:    content.Size,
+			},
+			Timings: harTimings{Wait: ms},
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(harLog{Log: harContent{
+		Version: "1.2",
+		Creator: harCreator{Name: "resty", Version: Version},
+		Entries: entries,
+	}})
+}
+
+// SaveHAR saves the cassette to the file in the HAR 1.2 format.
+func (c *Cassette) SaveHAR(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := c.WriteHAR(f); err != nil {
+		closeq(f)
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,289 @@
+package resty
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+//‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾
+// Rate limiter
+//_______________________________________________________________________
+
+// ErrRateLimited is the error when the request is not allowed by the RateLimiter,
+// in the fail-fast mode, or before the deadline of the request context.
+var ErrRateLimited = errors.New("resty: rate limited")
+
+// RateLimit is the limit of the requests.
+type RateLimit struct {
+	// Rate is the requests per second by the token bucket, 0 for unlimited.
+	Rate float64
+	// Burst is the max requests at once, default is 1.
+	Burst int
+	// MaxInFlight is the max number of the concurrent requests, 0 for unlimited.
+	MaxInFlight int
+}
+
+// RateLimiter limits the outgoing requests of a client, and of each host.
+//
+// A request waits for the tokens and the in-flight slots, or fails with ErrRateLimited at once
+// when FailFast, or when the wait exceeds the deadline of its context.
+// The in-flight slot is released when the response body is closed.
+//
+// On 429 Too Many Requests, the host is paused until Retry-After,
+// and its rate is halved when Adaptive, then recovered by the succeeded responses.
+type RateLimiter struct {
+	// Client is the limit of all the requests of the client.
+	Client RateLimit
+	// Host is the default limit of each host.
+	Host RateLimit
+	// Hosts are the limits of the specified hosts, keyed by the URL host, e.g. api.example.com or 127.0.0.1:8080.
+	Hosts map[string]RateLimit
+	// FailFast fails the requests with ErrRateLimited at once, instead of waiting.
+	FailFast bool
+	// Adaptive halves the rate of the host on 429, and recovers it by 10% on each succeeded response.
+	Adaptive bool
+	// DefaultRetryAfter is the pause of the host on 429 without the Retry-After header, 1s when zero.
+	DefaultRetryAfter time.Duration
+
+	mu     sync.Mutex
+	client *limiter
+	hosts  map[string]*limiter
+	now    func() time.Time
+}
+
+// NewRateLimiter creates a RateLimiter with the default limit of each host.
+//
+//	client.SetRateLimiter(resty.NewRateLimiter(resty.RateLimit{Rate: 10, Burst: 5, MaxInFlight: 4}))
+func NewRateLimiter(host RateLimit) *RateLimiter {
+	return &RateLimiter{Host: host, DefaultRetryAfter: time.Second, now: time.Now}
+}
+
+// timeNow returns the current time, the RateLimiter can be created as a literal without now.
+func (r *RateLimiter) timeNow() time.Time {
+	if r.now == nil {
+		return time.Now()
+	}
+	return r.now()
+}
+
+// limiter is a token bucket with a semaphore.
+type limiter struct {
+	baseRate float64
+	rate     float64
+	burst    float64
+	tokens   float64
+	last     time.Time
+	// pausedUntil is set by the 429 responses.
+	pausedUntil time.Time
+	inFlight    chan struct{}
+}
+
+func newLimiter(l RateLimit, now time.Time) *limiter {
+	burst := float64(l.Burst)
+	if burst <= 0 {
+		burst = 1
+	}
+
+	lim := &limiter{baseRate: l.Rate, rate: l.Rate, burst: burst, tokens: burst, last: now}
+	if l.MaxInFlight > 0 {
+		lim.inFlight = make(chan struct{}, l.MaxInFlight)
+	}
+	return lim
+}
+
+// reserve takes a token, and returns the wait duration before using it.
+func (l *limiter) reserve(now time.Time) time.Duration {
+	var wait time.Duration
+	if l.rate > 0 {
+		l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+		l.last = now
+		if l.tokens--; l.tokens < 0 {
+			wait = time.Duration(-l.tokens / l.rate * float64(time.Second))
+		}
+	}
+	if paused := l.pausedUntil.Sub(now); paused > wait {
+		wait = paused
+	}
+	return wait
+}
+
+// cancel gives back the token taken by reserve.
+func (l *limiter) cancel() {
+	if l.rate > 0 {
+		l.tokens++
+	}
+}
+
+func (r *RateLimiter) limiters(host string) []*limiter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.timeNow()
+	if r.client == nil {
+		r.client = newLimiter(r.Client, now)
+		r.hosts = map[string]*limiter{}
+	}
+
+	hl, ok := r.hosts[host]
+	if !ok {
+		limit, ok := r.Hosts[host]
+		if !ok {
+			limit = r.Host
+		}
+		hl = newLimiter(limit, now)
+		r.hosts[host] = hl
+	}
+
+	return []*limiter{r.client, hl}
+}
+
+// acquire waits for the tokens and the in-flight slots of the limiters, and returns the release function.
+func (r *RateLimiter) acquire(ctx context.Context, limiters []*limiter) (func(), error) {
+	r.mu.Lock()
+	now := r.timeNow()
+	var wait time.Duration
+	for _, l := range limiters {
+		if w := l.reserve(now); w > wait {
+			wait = w
+		}
+	}
+
+	deadline, hasDeadline := ctx.Deadline()
+	if wait > 0 && (r.FailFast || hasDeadline && deadline.Before(now.Add(wait))) {
+		for _, l := range limiters {
+			l.cancel()
+		}
+		r.mu.Unlock()
+		return nil, ErrRateLimited
+	}
+	r.mu.Unlock()
+
+	if wait > 0 {
+		t := time.NewTimer(wait)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			r.mu.Lock()
+			for _, l := range limiters {
+				l.cancel()
+			}
+			r.mu.Unlock()
+			return nil, ctx.Err()
+		}
+	}
+
+	var acquired []*limiter
+	release := func() {
+		for _, l := range acquired {
+			<-l.inFlight
+		}
+	}
+	for _, l := range limiters {
+		if l.inFlight == nil {
+			continue
+		}
+
+		if r.FailFast {
+			select {
+			case l.inFlight <- struct{}{}:
+			default:
+				release()
+				return nil, ErrRateLimited
+			}
+		} else {
+			select {
+			case l.inFlight <- struct{}{}:
+			case <-ctx.Done():
+				release()
+				return nil, ctx.Err()
+			}
+		}
+		acquired = append(acquired, l)
+	}
+
+	return release, nil
+}
+
+// observe adapts the host limiter to the response.
+func (r *RateLimiter) observe(hl *limiter, resp *http.Response) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.timeNow()
+	if resp.StatusCode == http.StatusTooManyRequests {
+		pause := parseRetryAfter(resp.Header.Get("Retry-After"), now)
+		if pause <= 0 {
+			pause = r.DefaultRetryAfter
+		}
+		if pause <= 0 {
+			pause = time.Second
+		}
+		if until := now.Add(pause); until.After(hl.pausedUntil) {
+			hl.pausedUntil = until
+		}
+		if r.Adaptive && hl.rate > 0 {
+			hl.rate = math.Max(hl.rate/2, hl.baseRate/10)
+		}
+		return
+	}
+
+	if r.Adaptive && resp.StatusCode < 400 && hl.rate < hl.baseRate {
+		hl.rate = math.Min(hl.baseRate, hl.rate+hl.baseRate/10)
+	}
+}
+
+// do sends the request within the limits, and records the wait in the request.
+func (r *RateLimiter) do(req *Request, raw *http.Request, send func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	limiters := r.limiters(raw.URL.Host)
+
+	start := time.Now()
+	release, err := r.acquire(raw.Context(), limiters)
+	req.rateLimitWait += time.Since(start)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := send(raw)
+	if err != nil || resp == nil || resp.Body == nil {
+		release()
+		return resp, err
+	}
+
+	r.observe(limiters[1], resp)
+	resp.Body = &releaseBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// releaseBody releases the in-flight slots when closed.
+type releaseBody struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
+
+// parseRetryAfter parses the Retry-After header in seconds or in the HTTP date.
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(v); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return t.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,129 @@
+package resty
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiterRate(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	c := New().SetBaseURL(ts.URL).SetRateLimiter(NewRateLimiter(RateLimit{Rate: 20}))
+	start := time.Now()
+	var resp *Response
+	for i := 0; i < 5; i++ {
+		var err error
+		resp, err = c.R().Get("/")
+		assert.Nil(t, err)
+	}
+	// the first one by the burst, and the others every 50ms
+	assert.True(t, time.Since(start) >= 190*time.Millisecond)
+	assert.True(t, resp.Request.TraceInfo().RateLimitWait > 0)
+
+	// fail fast by the deadline of the context
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, _ = c.R().Get("/")
+	_, err := c.R().SetContext(ctx).Get("/")
+	assert.True(t, errors.Is(err, ErrRateLimited))
+}
+
+func TestRateLimiterLiteral(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	c := New().SetBaseURL(ts.URL).SetRateLimiter(&RateLimiter{Host: RateLimit{Rate: 10}})
+	resp, err := c.R().Get("/")
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+}
+
+func TestRateLimiterFailFast(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	limiter := NewRateLimiter(RateLimit{})
+	limiter.Client = RateLimit{Rate: 1, Burst: 2}
+	limiter.FailFast = true
+	c := New().SetBaseURL(ts.URL).SetRateLimiter(limiter)
+
+	for i := 0; i < 2; i++ {
+		_, err := c.R().Get("/")
+		assert.Nil(t, err)
+	}
+	_, err := c.R().Get("/")
+	assert.True(t, errors.Is(err, ErrRateLimited))
+}
+
+func TestRateLimiterMaxInFlight(t *testing.T) {
+	var inFlight, maxInFlight int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			m := atomic.LoadInt32(&maxInFlight)
+			if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer ts.Close()
+
+	c := New().SetBaseURL(ts.URL).SetRateLimiter(NewRateLimiter(RateLimit{MaxInFlight: 2}))
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.R().Get("/")
+			assert.Nil(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(2), atomic.LoadInt32(&maxInFlight))
+}
+
+func TestRateLimiterTooManyRequests(t *testing.T) {
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer ts.Close()
+
+	limiter := NewRateLimiter(RateLimit{Rate: 100, Burst: 10})
+	limiter.Adaptive = true
+	limiter.DefaultRetryAfter = 100 * time.Millisecond
+	c := New().SetBaseURL(ts.URL).SetRateLimiter(limiter)
+
+	resp, err := c.R().Get("/")
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode())
+
+	hl := limiter.hosts[resp.Request.RawRequest.URL.Host]
+	assert.Equal(t, float64(50), hl.rate)
+
+	// paused by the 429
+	resp, err = c.R().Get("/")
+	assert.Nil(t, err)
+	assert.True(t, resp.Request.TraceInfo().RateLimitWait >= 80*time.Millisecond)
+	assert.Equal(t, float64(60), hl.rate)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Now()
+	assert.Equal(t, 3*time.Second, parseRetryAfter("3", now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("", now))
+	assert.Equal(t, 10*time.Second, parseRetryAfter(now.Add(10*time.Second).UTC().Format(http.TimeFormat), now.Truncate(time.Second)))
+}
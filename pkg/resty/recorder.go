@@ -0,0 +1,372 @@
+package resty
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+//‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾
+// Record/Replay transport
+//_______________________________________________________________________
+
+// ErrInteractionNotFound is the error when no recorded interaction matches the request in the replay mode.
+var ErrInteractionNotFound = errors.New("resty: recorded interaction not found")
+
+// RecorderMode is the mode of the Recorder.
+type RecorderMode int
+
+const (
+	// ModeRecord proxies the requests to the real transport, and records the interactions into the cassette.
+	ModeRecord RecorderMode = iota
+	// ModeReplay serves the recorded responses offline, the unmatched requests fail with ErrInteractionNotFound.
+	ModeReplay
+	// ModeReplayOrRecord serves the recorded responses, and records the unmatched ones.
+	ModeReplayOrRecord
+)
+
+// RecordedBody is a recorded request or response body,
+// the text one is kept as is, and the binary one is in base64, for the readability of the cassette file.
+type RecordedBody struct {
+	Text   string `json:"text,omitempty"`
+	Base64 string `json:"base64,omitempty"`
+}
+
+func newRecordedBody(b []byte) RecordedBody {
+	if utf8.Valid(b) {
+		return RecordedBody{Text: string(b)}
+	}
+	return RecordedBody{Base64: base64.StdEncoding.EncodeToString(b)}
+}
+
+// Bytes returns the raw body.
+func (b RecordedBody) Bytes() []byte {
+	if b.Base64 != "" {
+		v, _ := base64.StdEncoding.DecodeString(b.Base64)
+		return v
+	}
+	return []byte(b.Text)
+}
+
+// RecordedRequest is a recorded request.
+type RecordedRequest struct {
+	Method string       `json:"method"`
+	URL    string       `json:"url"`
+	Header http.Header  `json:"header,omitempty"`
+	Body   RecordedBody `json:"body"`
+}
+
+// RecordedResponse is a recorded response.
+type RecordedResponse struct {
+	StatusCode int          `json:"statusCode"`
+	Proto      string       `json:"proto,omitempty"`
+	Header     http.Header  `json:"header,omitempty"`
+	Body       RecordedBody `json:"body"`
+}
+
+// Interaction is a recorded request/response pair.
+type Interaction struct {
+	Request    RecordedRequest  `json:"request"`
+	Response   RecordedResponse `json:"response"`
+	RecordedAt time.Time        `json:"recordedAt"`
+	Duration   time.Duration    `json:"duration"`
+}
+
+// Cassette is the recorded interactions, saved as a JSON file.
+type Cassette struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// LoadCassette loads the cassette from the JSON file.
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Cassette{}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("resty: parse cassette %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Save saves the cassette to the JSON file.
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// Matcher tells whether the recorded request matches the request with the body.
+type Matcher func(r *http.Request, body []byte, recorded *RecordedRequest) bool
+
+// MatchMethod matches the request method.
+func MatchMethod(r *http.Request, _ []byte, recorded *RecordedRequest) bool {
+	return r.Method == recorded.Method
+}
+
+// MatchURL matches the full request URL, including the query string.
+func MatchURL(r *http.Request, _ []byte, recorded *RecordedRequest) bool {
+	return r.URL.String() == recorded.URL
+}
+
+// MatchBody matches the request body.
+func MatchBody(_ *http.Request, body []byte, recorded *RecordedRequest) bool {
+	return bytes.Equal(body, recorded.Body.Bytes())
+}
+
+// MatchHeaders creates a Matcher which matches the values of the named request headers.
+func MatchHeaders(names ...string) Matcher {
+	return func(r *http.Request, _ []byte, recorded *RecordedRequest) bool {
+		for _, name := range names {
+			if fmt.Sprint(r.Header.Values(name)) != fmt.Sprint(recorded.Header.Values(name)) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// MatchAll creates a Matcher which matches when all the matchers match.
+func MatchAll(matchers ...Matcher) Matcher {
+	return func(r *http.Request, body []byte, recorded *RecordedRequest) bool {
+		for _, m := range matchers {
+			if !m(r, body, recorded) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// DefaultRedactHeaders are the request headers stripped before recording by default.
+var DefaultRedactHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "X-Api-Key"}
+
+// Recorder is a http.RoundTripper which records the interactions into a cassette file,
+// or replays them offline, for the deterministic tests.
+//
+//	rec, err := resty.NewRecorder("testdata/fixtures/partner.json", resty.ModeReplay)
+//	client := resty.New().SetTransport(rec)
+//	defer rec.Stop()
+type Recorder struct {
+	Mode     RecorderMode
+	Path     string
+	Cassette *Cassette
+	// Transport is the real transport in the record mode, default is http.DefaultTransport.
+	Transport http.RoundTripper
+	// Matcher matches the requests in the replay mode, default is MatchAll(MatchMethod, MatchURL).
+	Matcher Matcher
+	// RedactHeaders are the request headers stripped before recording, default (nil) is DefaultRedactHeaders,
+	// set an empty slice to strip none.
+	RedactHeaders []string
+	// Redact is called to redact the interaction before recording, after RedactHeaders stripped.
+	Redact func(*Interaction)
+
+	mu       sync.Mutex
+	replayed map[*Interaction]bool
+	modified bool
+}
+
+// NewRecorder creates a Recorder on the cassette file, which is loaded if it exists.
+func NewRecorder(path string, mode RecorderMode) (*Recorder, error) {
+	r := &Recorder{
+		Mode:          mode,
+		Path:          path,
+		Cassette:      &Cassette{},
+		Matcher:       MatchAll(MatchMethod, MatchURL),
+		RedactHeaders: DefaultRedactHeaders,
+		replayed:      map[*Interaction]bool{},
+	}
+
+	if mode != ModeRecord {
+		c, err := LoadCassette(path)
+		if err != nil && !(mode == ModeReplayOrRecord && errors.Is(err, os.ErrNotExist)) {
+			return nil, err
+		}
+		if c != nil {
+			r.Cassette = c
+		}
+	}
+
+	return r, nil
+}
+
+// Stop saves the cassette file when anything recorded.
+func (r *Recorder) Stop() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.modified {
+		return nil
+	}
+
+	r.modified = false
+	return r.Cassette.Save(r.Path)
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Mode != ModeRecord {
+		if i := r.find(req, body); i != nil {
+			return i.Response.toResponse(req), nil
+		}
+		if r.Mode == ModeReplay {
+			return nil, fmt.Errorf("%w: %s %s", ErrInteractionNotFound, req.Method, req.URL)
+		}
+	}
+
+	return r.record(req, body)
+}
+
+// initDefaults initializes the unset fields, the Recorder can be created as a literal.
+// r.mu should be held.
+func (r *Recorder) initDefaults() {
+	if r.Cassette == nil {
+		r.Cassette = &Cassette{}
+	}
+	if r.Matcher == nil {
+		r.Matcher = MatchAll(MatchMethod, MatchURL)
+	}
+	if r.RedactHeaders == nil {
+		r.RedactHeaders = DefaultRedactHeaders
+	}
+	if r.replayed == nil {
+		r.replayed = map[*Interaction]bool{}
+	}
+}
+
+func (r *Recorder) find(req *http.Request, body []byte) *Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.initDefaults()
+
+	// the unreplayed interactions first, so the repeated requests are replayed in the recorded order
+	var matched *Interaction
+	for _, i := range r.Cassette.Interactions {
+		if r.Matcher(req, body, &i.Request) {
+			if !r.replayed[i] {
+				r.replayed[i] = true
+				return i
+			}
+			if matched == nil {
+				matched = i
+			}
+		}
+	}
+
+	return matched
+}
+
+func (r *Recorder) record(req *http.Request, body []byte) (*http.Response, error) {
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	start := time.Now()
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	closeq(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	i := &Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			URL:    req.URL.String(),
+			Header: req.Header.Clone(),
+			Body:   newRecordedBody(body),
+		},
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Proto:      resp.Proto,
+			Header:     resp.Header.Clone(),
+			Body:       newRecordedBody(respBody),
+		},
+		RecordedAt: start,
+		Duration:   time.Since(start),
+	}
+
+	r.mu.Lock()
+	r.initDefaults()
+	redactHeaders := r.RedactHeaders
+	r.mu.Unlock()
+
+	for _, h := range redactHeaders {
+		i.Request.Header.Del(h)
+	}
+	if r.Redact != nil {
+		r.Redact(i)
+	}
+
+	r.mu.Lock()
+	r.Cassette.Interactions = append(r.Cassette.Interactions, i)
+	r.replayed[i] = true
+	r.modified = true
+	r.mu.Unlock()
+
+	return resp, nil
+}
+
+func (r RecordedResponse) toResponse(req *http.Request) *http.Response {
+	body := r.Body.Bytes()
+	proto := r.Proto
+	if proto == "" {
+		proto = "HTTP/1.1"
+	}
+	major, minor, _ := http.ParseHTTPVersion(proto)
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode)),
+		StatusCode:    r.StatusCode,
+		Proto:         proto,
+		ProtoMajor:    major,
+		ProtoMinor:    minor,
+		Header:        r.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// readRequestBody reads the request body, and restores it for the later reading.
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	closeq(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
@@ -0,0 +1,126 @@
+package resty
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecorder(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte(r.Method + " " + r.URL.Path + " " + string(body)))
+	}))
+
+	path := filepath.Join(t.TempDir(), "fixtures", "cassette.json")
+	rec, err := NewRecorder(path, ModeRecord)
+	assert.Nil(t, err)
+
+	c := New().SetTransport(rec).SetBaseURL(ts.URL).SetAuthToken("secret")
+	resp, err := c.R().Get("/a")
+	assert.Nil(t, err)
+	assert.Equal(t, "GET /a", resp.String())
+	resp, err = c.R().SetBody("b1").Post("/b")
+	assert.Nil(t, err)
+	assert.Equal(t, "POST /b b1", resp.String())
+	_, _ = c.R().SetBody("b2").Post("/b")
+	assert.Nil(t, rec.Stop())
+	ts.Close()
+
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.False(t, strings.Contains(string(data), "secret"))
+
+	// replay offline
+	rec, err = NewRecorder(path, ModeReplay)
+	assert.Nil(t, err)
+	rec.Matcher = MatchAll(MatchMethod, MatchURL, MatchBody)
+	c = New().SetTransport(rec).SetBaseURL(ts.URL)
+
+	resp, err = c.R().SetBody("b2").Post("/b")
+	assert.Nil(t, err)
+	assert.Equal(t, "POST /b b2", resp.String())
+	assert.Equal(t, "text/plain", resp.Header().Get("Content-Type"))
+	resp, err = c.R().Get("/a")
+	assert.Nil(t, err)
+	assert.Equal(t, "GET /a", resp.String())
+
+	_, err = c.R().SetBody("b3").Post("/b")
+	assert.True(t, errors.Is(err, ErrInteractionNotFound))
+
+	// HAR export
+	var buf bytes.Buffer
+	assert.Nil(t, rec.Cassette.WriteHAR(&buf))
+	var har map[string]interface{}
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &har))
+	entries := har["log"].(map[string]interface{})["entries"].([]interface{})
+	assert.Len(t, entries, 3)
+	assert.Equal(t, "POST /b b1", entries[1].(map[string]interface{})["response"].(map[string]interface{})["content"].(map[string]interface{})["text"])
+}
+
+func TestRecorderReplayOrRecord(t *testing.T) {
+	hits := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		_, _ = w.Write([]byte{0xff, 0xfe, byte(hits)})
+	}))
+	defer ts.Close()
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	rec, err := NewRecorder(path, ModeReplayOrRecord)
+	assert.Nil(t, err)
+	rec.Redact = func(i *Interaction) { i.Request.Header.Set("X-Redacted", "1") }
+
+	c := New().SetTransport(rec).SetBaseURL(ts.URL)
+	for i := 0; i < 2; i++ {
+		resp, err := c.R().Get("/bin")
+		assert.Nil(t, err)
+		assert.Equal(t, []byte{0xff, 0xfe, 1}, resp.Body())
+	}
+	assert.Equal(t, 1, hits)
+	assert.Nil(t, rec.Stop())
+
+	cassette, err := LoadCassette(path)
+	assert.Nil(t, err)
+	assert.Len(t, cassette.Interactions, 1)
+	assert.Equal(t, "1", cassette.Interactions[0].Request.Header.Get("X-Redacted"))
+	assert.NotEmpty(t, cassette.Interactions[0].Response.Body.Base64)
+}
+
+func TestRecorderZeroValue(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Method + " " + r.URL.Path))
+	}))
+	defer ts.Close()
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	rec := &Recorder{Mode: ModeReplayOrRecord, Path: path}
+	c := New().SetTransport(rec).SetBaseURL(ts.URL).SetAuthToken("secret")
+	for i := 0; i < 2; i++ {
+		resp, err := c.R().Get("/a")
+		assert.Nil(t, err)
+		assert.Equal(t, "GET /a", resp.String())
+	}
+	assert.Nil(t, rec.Stop())
+
+	cassette, err := LoadCassette(path)
+	assert.Nil(t, err)
+	assert.Len(t, cassette.Interactions, 1)
+	assert.Empty(t, cassette.Interactions[0].Request.Header.Get("Authorization"))
+
+	// replay offline
+	rec = &Recorder{Mode: ModeReplay, Cassette: cassette}
+	resp, err := New().SetTransport(rec).SetBaseURL(ts.URL).R().Get("/a")
+	assert.Nil(t, err)
+	assert.Equal(t, "GET /a", resp.String())
+}
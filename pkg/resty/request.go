@@ -63,6 +63,8 @@ type Request struct {
 	client              *Client
 	bodyBuf             *bytes.Buffer
 	clientTrace         *clientTrace
+	cacheStatus         CacheStatus
+	rateLimitWait       time.Duration
 	multipartFiles      []*File
 	multipartFields     []*MultipartField
 	retryConditions     []RetryConditionFunc
@@ -664,7 +666,17 @@ func (r *Request) TraceInfo() TraceInfo {
 	ct := r.clientTrace
 
 	if ct == nil {
-		return TraceInfo{}
+		return TraceInfo{CacheStatus: r.cacheStatus, RateLimitWait: r.rateLimitWait}
+	}
+
+	// no connection is made, when the response is served from the cache
+	if ct.getConn.IsZero() {
+		return TraceInfo{
+			TotalTime:      ct.endTime.Sub(r.Time),
+			RequestAttempt: r.Attempt,
+			CacheStatus:    r.cacheStatus,
+			RateLimitWait:  r.rateLimitWait,
+		}
 	}
 
 	ti := TraceInfo{
@@ -675,6 +687,8 @@ func (r *Request) TraceInfo() TraceInfo {
 		IsConnWasIdle:  ct.gotConnInfo.WasIdle,
 		ConnIdleTime:   ct.gotConnInfo.IdleTime,
 		RequestAttempt: r.Attempt,
+		CacheStatus:    r.cacheStatus,
+		RateLimitWait:  r.rateLimitWait,
 	}
 
 	// Calculate the total time accordingly,
@@ -95,6 +95,9 @@ func (r *Response) String() string {
 	return strings.TrimSpace(string(r.body))
 }
 
+// CacheStatus method returns the status of the response cache, see `Client.SetResponseCache`.
+func (r *Response) CacheStatus() CacheStatus { return r.Request.cacheStatus }
+
 // Time method returns the time of HTTP response time that from request we sent and received a request.
 //
 // See `Response.ReceivedAt` to know when client received response and see `Response.Request.Time` to know
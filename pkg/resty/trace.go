@@ -60,6 +60,12 @@ type TraceInfo struct {
 
 	// RemoteAddr returns the remote network address.
 	RemoteAddr net.Addr
+
+	// CacheStatus is the status of the response cache, see `Client.SetResponseCache`.
+	CacheStatus CacheStatus
+
+	// RateLimitWait is the duration waited for the rate limits, see `Client.SetRateLimiter`.
+	RateLimitWait time.Duration
 }
 
 //‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾‾
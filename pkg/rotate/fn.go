@@ -5,11 +5,14 @@ import (
 	"compress/gzip"
 	"fmt"
 	"io"
+	"log"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/bingoohuang/gg/pkg/timex"
@@ -36,6 +39,23 @@ type FileWriter struct {
 	maxIndex    int
 	timedFn     string
 	MaxKeepDays int
+
+	// RotateInterval rotates the file every interval, like time.Hour for hourly or 10*time.Minute,
+	// aligned to the interval boundaries.
+	RotateInterval time.Duration
+	// MaxFiles is the max number of the rotated files to keep, 0 for unlimited.
+	MaxFiles int
+	// MaxTotalSize is the max total size of the rotated files to keep, 0 for unlimited.
+	MaxTotalSize uint64
+	// Compress gzips the rotated file in the background, instead of writing through gzip.
+	Compress bool
+	// OnRotate is called in the background after the rotation, with the finished file path,
+	// which is the .gz one when Compress, and the new file path.
+	OnRotate func(oldPath, newPath string)
+
+	period time.Time
+	bg     sync.WaitGroup
+	bgLock sync.Mutex
 }
 
 func NewFileWriter(fnTemplate string, maxSize uint64, append bool, maxKeepDays int) *FileWriter {
@@ -61,16 +81,110 @@ func NewFileWriter(fnTemplate string, maxSize uint64, append bool, maxKeepDays i
 	return r
 }
 
-func (w *FileWriter) daysKeeping() {
+// cleanup removes the rotated files exceeding MaxKeepDays, MaxFiles or MaxTotalSize.
+func (w *FileWriter) cleanup(current string) {
+	if w.MaxKeepDays <= 0 && w.MaxFiles <= 0 && w.MaxTotalSize <= 0 {
+		return
+	}
+
+	type rotatedFile struct {
+		path string
+		stat os.FileInfo
+	}
+
+	patterns := []string{matchExpiredFiles(w.FnTemplate, w.DotGz)}
+	if w.Compress && w.DotGz == "" {
+		patterns = append(patterns, matchExpiredFiles(w.FnTemplate, ".gz"))
+	}
+
+	var files []rotatedFile
+	for _, pattern := range patterns {
+		matches, _ := filepath.Glob(pattern)
+		for _, f := range matches {
+			if f == current {
+				continue
+			}
+			if stat, _ := os.Stat(f); stat != nil && stat.Mode().IsRegular() {
+				files = append(files, rotatedFile{path: f, stat: stat})
+			}
+		}
+	}
+
+	// the newest first
+	sort.Slice(files, func(i, j int) bool { return files[i].stat.ModTime().After(files[j].stat.ModTime()) })
+
 	expired := time.Now().Add(time.Duration(w.MaxKeepDays) * -24 * time.Hour)
-	matches, _ := filepath.Glob(matchExpiredFiles(w.FnTemplate, w.DotGz))
-	for _, f := range matches {
-		if stat, _ := os.Stat(f); stat != nil && stat.ModTime().Before(expired) {
-			_ = os.Remove(f)
+	var totalSize uint64
+	for i, f := range files {
+		totalSize += uint64(f.stat.Size())
+		if w.MaxKeepDays > 0 && f.stat.ModTime().Before(expired) ||
+			w.MaxFiles > 0 && i >= w.MaxFiles ||
+			w.MaxTotalSize > 0 && totalSize > w.MaxTotalSize {
+			_ = os.Remove(f.path)
 		}
 	}
 }
 
+// rotated compresses the old file, calls the OnRotate and cleans up in the background.
+func (w *FileWriter) rotated(oldPath, newPath string) {
+	w.bg.Add(1)
+	go func() {
+		defer w.bg.Done()
+		w.bgLock.Lock()
+		defer w.bgLock.Unlock()
+
+		if oldPath != "" {
+			if w.Compress && !strings.HasSuffix(oldPath, ".gz") {
+				if gz, err := gzipFile(oldPath); err != nil {
+					log.Printf("W! failed to compress %s: %v", oldPath, err)
+				} else {
+					oldPath = gz
+				}
+			}
+
+			if w.OnRotate != nil {
+				w.OnRotate(oldPath, newPath)
+			}
+		}
+
+		w.cleanup(newPath)
+	}()
+}
+
+// gzipFile compresses the file to file.gz and removes the file.
+func gzipFile(path string) (string, error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	gz, tmp := path+".gz", path+".gz.tmp"
+	dst, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o660)
+	if err != nil {
+		return "", err
+	}
+
+	gw := gzip.NewWriter(dst)
+	_, err = io.Copy(gw, src)
+	if err1 := gw.Close(); err == nil {
+		err = err1
+	}
+	if err1 := dst.Close(); err == nil {
+		err = err1
+	}
+	if err == nil {
+		err = os.Rename(tmp, gz)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return "", err
+	}
+
+	_ = src.Close()
+	return gz, os.Remove(path)
+}
+
 func matchExpiredFiles(fnTemplate, dotGz string) string {
 	fn := timex.GlobName(fnTemplate)
 	fn = filepath.Clean(fn)
@@ -79,8 +193,18 @@ func matchExpiredFiles(fnTemplate, dotGz string) string {
 }
 
 func (w *FileWriter) Write(p []byte) (int, error) {
+	lastTimedFn := w.timedFn
 	timedFn := w.NewTimedFilename(w.FnTemplate, w.DotGz)
 
+	if w.RotateInterval > 0 {
+		// the changed timed filename rotates already
+		period := time.Now().Truncate(w.RotateInterval)
+		if !w.period.IsZero() && !period.Equal(w.period) && lastTimedFn == timedFn && w.curSize > 0 {
+			w.maxIndex++
+		}
+		w.period = period
+	}
+
 	for {
 		fn := w.RotateFilename(timedFn)
 		if fn == w.curFn {
@@ -115,9 +239,12 @@ func (b *bufioWriter) Close() error { return b.Writer.Flush() }
 func (b *bufioWriter) Flush() error { return b.Writer.Flush() }
 
 func (w *FileWriter) openFile(fn string) (ok bool, err error) {
-	_ = w.Close()
-	if w.maxIndex == 2 { // rename bbb-2021-05-27-18-26.http to bbb-2021-05-27-18-26_00001.http
-		_ = os.Rename(w.curFn+w.DotGz, SetFileIndex(w.curFn, 1)+w.DotGz)
+	w.closeFile()
+	oldFn := w.curFn
+	if w.maxIndex == 2 && oldFn != "" { // rename bbb-2021-05-27-18-26.http to bbb-2021-05-27-18-26_00001.http
+		if os.Rename(oldFn+w.DotGz, SetFileIndex(oldFn, 1)+w.DotGz) == nil {
+			oldFn = SetFileIndex(oldFn, 1)
+		}
 	}
 
 	w.file, err = os.OpenFile(fn+w.DotGz, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o660)
@@ -126,6 +253,9 @@ func (w *FileWriter) openFile(fn string) (ok bool, err error) {
 	}
 
 	w.curFn = fn
+	if oldFn != "" && oldFn != fn {
+		w.rotated(oldFn+w.DotGz, fn+w.DotGz)
+	}
 
 	if w.DotGz != "" {
 		gw := gzip.NewWriter(w.file)
@@ -157,20 +287,28 @@ func (w *FileWriter) Flush() error {
 	return nil
 }
 
+// Close closes the current file, and waits the background compressing and cleaning up.
 func (w *FileWriter) Close() error {
-	if w.writer != nil && w.file != nil {
-		_ = w.writer.Close()
-		_ = w.file.Close()
-		w.writer = nil
-		w.file = nil
-
-		if w.MaxKeepDays > 0 {
-			go w.daysKeeping()
-		}
+	if w.closeFile() {
+		w.rotated("", w.curFn+w.DotGz)
 	}
+
+	w.bg.Wait()
 	return nil
 }
 
+func (w *FileWriter) closeFile() bool {
+	if w.writer == nil || w.file == nil {
+		return false
+	}
+
+	_ = w.writer.Close()
+	_ = w.file.Close()
+	w.writer = nil
+	w.file = nil
+	return true
+}
+
 func (w *FileWriter) NewTimedFilename(template, dotGz string) string {
 	fn := timex.FormatTime(time.Now(), template)
 	fn = filepath.Clean(fn)
@@ -182,6 +320,13 @@ func (w *FileWriter) NewTimedFilename(template, dotGz string) string {
 
 	if w.curFn == "" { // 只有第一次检查最大文件索引号
 		w.maxIndex, fn = FindMaxFileIndex(fn, dotGz)
+		if w.Compress && dotGz == "" {
+			// the compressed ones are rotated, so write to the next index
+			if gzIndex := findMaxGzIndex(fn); gzIndex >= w.maxIndex {
+				w.maxIndex = gzIndex + 1
+				fn = SetFileIndex(fn, w.maxIndex)
+			}
+		}
 	}
 
 	return fn
@@ -230,6 +375,24 @@ func FindMaxFileIndex(path string, dotGz string) (int, string) {
 	return maxIndex, maxFn
 }
 
+// findMaxGzIndex finds the max index of the compressed files like log-2021-05-27_00001.log.gz, 0 for none.
+func findMaxGzIndex(path string) int {
+	base, _, ext := SplitBaseIndexExt(path)
+	matches, _ := filepath.Glob(base + "*" + ext + ".gz")
+	maxIndex := 0
+	for _, fn := range matches {
+		index := GetFileIndex(strings.TrimSuffix(fn, ".gz"))
+		if index < 0 {
+			index = 1
+		}
+		if index > maxIndex {
+			maxIndex = index
+		}
+	}
+
+	return maxIndex
+}
+
 var idx = regexp.MustCompile(`_\d{5,}`)
 
 func SplitBaseIndexExt(path string) (base, index, ext string) {
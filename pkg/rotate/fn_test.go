@@ -1,7 +1,9 @@
 package rotate
 
 import (
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -27,3 +29,51 @@ func TestGzip(t *testing.T) {
 	writer.Write([]byte("hello world!"))
 	writer.Close()
 }
+
+func TestRotateInterval(t *testing.T) {
+	dir := t.TempDir()
+	var rotated []string
+	writer := NewFileWriter(dir+"/xx.log", 0, true, 0)
+	writer.RotateInterval = 100 * time.Millisecond
+	writer.Compress = true
+	writer.MaxFiles = 2
+	writer.OnRotate = func(oldPath, newPath string) { rotated = append(rotated, oldPath) }
+
+	for i := 0; i < 4; i++ {
+		_, _ = writer.Write([]byte("hello world!"))
+		_ = writer.Flush()
+		time.Sleep(110 * time.Millisecond)
+	}
+	_, _ = writer.Write([]byte("hello world!"))
+	assert.Nil(t, writer.Close())
+
+	assert.Equal(t, []string{
+		dir + "/xx_00001.log.gz", dir + "/xx_00002.log.gz",
+		dir + "/xx_00003.log.gz", dir + "/xx_00004.log.gz",
+	}, rotated)
+
+	matches, _ := filepath.Glob(dir + "/*")
+	assert.Equal(t, []string{dir + "/xx_00003.log.gz", dir + "/xx_00004.log.gz", dir + "/xx_00005.log"}, matches)
+
+	// restart after the compressed ones
+	writer = NewFileWriter(dir+"/xx.log", 0, true, 0)
+	writer.Compress = true
+	_, _ = writer.Write([]byte("hello world!"))
+	assert.Nil(t, writer.Close())
+	assert.Equal(t, dir+"/xx_00005.log", writer.curFn)
+}
+
+func TestMaxTotalSize(t *testing.T) {
+	dir := t.TempDir()
+	writer := NewFileWriter(dir+"/xx.log", 10, true, 0)
+	writer.MaxTotalSize = 25
+
+	for i := 0; i < 5; i++ {
+		_, _ = writer.Write([]byte("0123456789"))
+		time.Sleep(10 * time.Millisecond) // distinct mod times
+	}
+	assert.Nil(t, writer.Close())
+
+	matches, _ := filepath.Glob(dir + "/*")
+	assert.Equal(t, []string{dir + "/xx_00003.log", dir + "/xx_00004.log", dir + "/xx_00005.log"}, matches)
+}
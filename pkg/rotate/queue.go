@@ -22,7 +22,7 @@ import (
 // QueueWriter output parsed http messages
 type QueueWriter struct {
 	queue  chan string
-	writer io.Writer
+	writer *iox.MaxLatencyWriter
 
 	discarded      uint32
 	config         *Config
@@ -39,18 +39,29 @@ type Config struct {
 	MaxSize        uint64        // 单个文件最大大小
 	KeepDays       int           // 保留多少天的日志，过期删除， 0全部, 默认10天
 	FlushLatency   time.Duration // 刷新延迟
+
+	RotateInterval time.Duration                 // 按时间滚动的间隔，例如 time.Hour 每小时，10*time.Minute 每10分钟，0 不按时间滚动
+	MaxFiles       int                           // 最多保留多少个已滚动的文件，0 不限
+	MaxTotalSize   uint64                        // 已滚动文件的总大小上限，超过时删除最旧的，0 不限
+	Compress       bool                          // 滚动后在后台 gzip 压缩上一个文件，不再边写边压缩
+	OnRotate       func(oldPath, newPath string) // 滚动后的回调，例如上传或者计算校验和
 }
 
 type Option func(*Config)
 
-func WithContext(v context.Context) Option    { return func(c *Config) { c.Context = v } }
-func WithConfig(v *Config) Option             { return func(c *Config) { *c = *v } }
-func WithAllowDiscard(v bool) Option          { return func(c *Config) { c.AllowDiscarded = v } }
-func WithAppend(v bool) Option                { return func(c *Config) { c.Append = v } }
-func WithOutChanSize(v int) Option            { return func(c *Config) { c.OutChanSize = v } }
-func WithMaxSize(v uint64) Option             { return func(c *Config) { c.MaxSize = v } }
-func WithKeepDays(v int) Option               { return func(c *Config) { c.KeepDays = v } }
-func WithFlushLatency(v time.Duration) Option { return func(c *Config) { c.FlushLatency = v } }
+func WithContext(v context.Context) Option                { return func(c *Config) { c.Context = v } }
+func WithConfig(v *Config) Option                         { return func(c *Config) { *c = *v } }
+func WithAllowDiscard(v bool) Option                      { return func(c *Config) { c.AllowDiscarded = v } }
+func WithAppend(v bool) Option                            { return func(c *Config) { c.Append = v } }
+func WithOutChanSize(v int) Option                        { return func(c *Config) { c.OutChanSize = v } }
+func WithMaxSize(v uint64) Option                         { return func(c *Config) { c.MaxSize = v } }
+func WithKeepDays(v int) Option                           { return func(c *Config) { c.KeepDays = v } }
+func WithFlushLatency(v time.Duration) Option             { return func(c *Config) { c.FlushLatency = v } }
+func WithRotateInterval(v time.Duration) Option           { return func(c *Config) { c.RotateInterval = v } }
+func WithMaxFiles(v int) Option                           { return func(c *Config) { c.MaxFiles = v } }
+func WithMaxTotalSize(v uint64) Option                    { return func(c *Config) { c.MaxTotalSize = v } }
+func WithCompress(v bool) Option                          { return func(c *Config) { c.Compress = v } }
+func WithOnRotate(v func(oldPath, newPath string)) Option { return func(c *Config) { c.OnRotate = v } }
 
 // NewQueueWriter creates a new QueueWriter.
 // outputPath:
@@ -159,7 +170,15 @@ func createWriter(outputPath string, c *Config) iox.WriteFlusher {
 			return &LfStdout{Writer: os.Stderr}
 		}
 	default:
-		return NewFileWriter(outputPath, c.MaxSize, c.Append, c.KeepDays)
+		w := NewFileWriter(outputPath, c.MaxSize, c.Append, c.KeepDays)
+		w.RotateInterval = c.RotateInterval
+		w.MaxFiles = c.MaxFiles
+		w.MaxTotalSize = c.MaxTotalSize
+		w.OnRotate = c.OnRotate
+		if w.Compress = c.Compress; w.Compress {
+			w.DotGz = "" // compress after rotation instead of writing through gzip
+		}
+		return w
 	}
 }
 
@@ -190,9 +209,13 @@ func (p *QueueWriter) Send(msg string, countDiscards bool) {
 
 func (p *QueueWriter) flushing() {
 	defer p.wg.Done()
-	if c, ok := p.writer.(io.Closer); ok {
-		defer c.Close()
-	}
+	defer func() {
+		p.writer.Stop()
+		_ = p.writer.Dst.Flush()
+		if c, ok := p.writer.Dst.(io.Closer); ok {
+			_ = c.Close()
+		}
+	}()
 
 	ctx := p.config.Context
 	for {
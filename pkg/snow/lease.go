@@ -0,0 +1,191 @@
+package snow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNoNodeID is the error when all the node IDs are leased by others.
+	ErrNoNodeID = errors.New("snow: no free node ID")
+	// ErrLeaseLost is the error when the lease is expired or taken over by others.
+	ErrLeaseLost = errors.New("snow: node ID lease lost")
+)
+
+// Lease is a node ID leased to an owner until Expires.
+type Lease struct {
+	NodeID  int64
+	Owner   string
+	Expires time.Time
+	// LastTime is the last timestamp generated by the previous holder, or by the owner when renewing.
+	LastTime int64
+}
+
+// NodeLeaser grants unique node IDs among the nodes by leases with heartbeats.
+type NodeLeaser interface {
+	// Acquire leases a node ID in [0, maxNodeID] to the owner for ttl.
+	// The owner's own lease is preferred, then the expired ones, then the never leased ones.
+	// It returns ErrNoNodeID when all the node IDs are leased by others.
+	Acquire(ctx context.Context, owner string, maxNodeID int64, ttl time.Duration) (*Lease, error)
+	// Renew extends the lease for ttl, with the last timestamp generated by the node,
+	// it returns ErrLeaseLost when the lease is taken over by others.
+	Renew(ctx context.Context, lease *Lease, lastTime int64, ttl time.Duration) error
+	// Release releases the lease, with the last timestamp generated by the node.
+	Release(ctx context.Context, lease *Lease, lastTime int64) error
+}
+
+// LeasedNode is a Node whose node ID is leased from a NodeLeaser, and renewed in the background.
+// It stops generating IDs with ErrLeaseLost after the lease is expired, until it is renewed again.
+type LeasedNode struct {
+	*Node
+
+	leaser NodeLeaser
+	ttl    time.Duration
+
+	mu      sync.Mutex
+	lease   *Lease
+	lost    bool
+	cancel  context.CancelFunc
+	stopped chan struct{}
+}
+
+// NewLeasedNode leases a node ID from the leaser, and creates a Node with it.
+// The owner identifies the node, like the pod name, default is hostname:pid.
+// The lease is renewed every ttl/3, and the previous holder's last timestamp is checked
+// by the RollbackPolicy, so the nodes should share the Epoch and TimestampUnit.
+func NewLeasedNode(leaser NodeLeaser, owner string, ttl time.Duration, optionFns ...OptionFn) (*LeasedNode, error) {
+	if owner == "" {
+		hostname, _ := os.Hostname()
+		owner = fmt.Sprintf("%s:%d", hostname, os.Getpid())
+	}
+
+	option := Option{NodeBits: -1, StepBits: -1, NodeID: 0}
+	option.Apply(optionFns...)
+	var nodeMax int64 = -1 ^ (-1 << option.NodeBits)
+
+	lease, err := leaser.Acquire(context.Background(), owner, nodeMax, ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	fns := append(append([]OptionFn(nil), optionFns...), WithNodeID(lease.NodeID), WithLastTime(lease.LastTime))
+	node, err := NewNode(fns...)
+	if err != nil {
+		_ = leaser.Release(context.Background(), lease, lease.LastTime)
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	n := &LeasedNode{
+		Node:    node,
+		leaser:  leaser,
+		ttl:     ttl,
+		lease:   lease,
+		cancel:  cancel,
+		stopped: make(chan struct{}),
+	}
+	go n.heartbeat(ctx)
+
+	return n, nil
+}
+
+// Lease returns a copy of the current lease.
+func (n *LeasedNode) Lease() Lease {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return *n.lease
+}
+
+// Next creates and returns a unique snowflake ID, it panics when the lease is lost.
+func (n *LeasedNode) Next() ID {
+	id, err := n.NextID()
+	if err != nil {
+		panic(err)
+	}
+
+	return id
+}
+
+// NextID creates and returns a unique snowflake ID, or ErrLeaseLost when the lease is lost.
+func (n *LeasedNode) NextID() (ID, error) {
+	n.mu.Lock()
+	lost := n.lost || time.Now().After(n.lease.Expires)
+	n.mu.Unlock()
+
+	if lost {
+		return 0, ErrLeaseLost
+	}
+
+	return n.Node.NextID()
+}
+
+func (n *LeasedNode) lastTime() int64 {
+	n.Node.mu.Lock()
+	defer n.Node.mu.Unlock()
+
+	return n.Node.time
+}
+
+func (n *LeasedNode) heartbeat(ctx context.Context) {
+	defer close(n.stopped)
+
+	ticker := time.NewTicker(n.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// not canceled by Close, or the lease in the database may be renewed without knowing
+			n.renew(context.Background())
+		}
+	}
+}
+
+func (n *LeasedNode) renew(ctx context.Context) {
+	n.mu.Lock()
+	if n.lost {
+		n.mu.Unlock()
+		return
+	}
+	lease := *n.lease
+	n.mu.Unlock()
+
+	err := n.leaser.Renew(ctx, &lease, n.lastTime(), n.ttl)
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if err != nil {
+		if errors.Is(err, ErrLeaseLost) {
+			n.lost = true
+		}
+		log.Printf("W! failed to renew node ID %d lease: %v", lease.NodeID, err)
+		return
+	}
+
+	n.lease = &lease
+}
+
+// Close stops the renewing and releases the lease.
+func (n *LeasedNode) Close() error {
+	n.cancel()
+	<-n.stopped
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.lost {
+		return nil
+	}
+
+	n.lost = true
+	return n.leaser.Release(context.Background(), n.lease, n.lastTime())
+}
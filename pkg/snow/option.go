@@ -25,8 +25,40 @@ type Option struct {
 
 	// TimestampUnit for the time goes unit, default is 1ms.
 	TimestampUnit time.Duration
+
+	// Rollback is the behaviour when the clock moves backwards, default is RollbackWait.
+	Rollback RollbackPolicy
+
+	// MaxRollbackWait is the max duration to wait for the clock catching up for RollbackWait, default is 1s.
+	MaxRollbackWait time.Duration
+
+	// ReservedStepBits holds the number of the high step bits reserved for RollbackBorrow, default is 1.
+	ReservedStepBits int8
+
+	// Clock returns the current time, default is the monotonic clock since the node created,
+	// which never moves backwards in the process. Use a wall clock like func() time.Time { return time.Now().Round(0) }
+	// to follow the system time adjustments.
+	Clock func() time.Time
+
+	// LastTime is the last timestamp (in TimestampUnit since Epoch) generated by the previous node with the same NodeID,
+	// like the one recorded in a Lease, the earlier clock is treated as a rollback.
+	LastTime int64
 }
 
+// RollbackPolicy is the behaviour when the clock moves backwards.
+type RollbackPolicy int
+
+const (
+	// RollbackWait waits for the clock catching up, up to MaxRollbackWait, or returns ErrClockRollback.
+	RollbackWait RollbackPolicy = iota
+	// RollbackBorrow generates IDs with the rolled back time from the reserved high step space,
+	// which is never used by the normal generation. It returns ErrClockRollback when rolled back again
+	// before the last borrowed time.
+	RollbackBorrow
+	// RollbackError returns ErrClockRollback immediately.
+	RollbackError
+)
+
 // Apply applies the option functions to the option.
 // nolint gomnd
 func (o *Option) Apply(fns ...OptionFn) {
@@ -46,6 +78,14 @@ func (o *Option) Apply(fns ...OptionFn) {
 		o.StepBits = 12
 	}
 
+	if o.MaxRollbackWait == 0 {
+		o.MaxRollbackWait = time.Second
+	}
+
+	if o.ReservedStepBits <= 0 && o.Rollback == RollbackBorrow {
+		o.ReservedStepBits = 1
+	}
+
 	if o.NodeID < 0 {
 		o.NodeID = defaultIPNodeID()
 		var nodeMax int64 = -1 ^ (-1 << o.NodeBits)
@@ -79,3 +119,17 @@ func WithStepBits(n int8) OptionFn { return func(o *Option) { o.StepBits = n } }
 
 // WithTimestampUnit set the customized TimestampUnit n.
 func WithTimestampUnit(n time.Duration) OptionFn { return func(o *Option) { o.TimestampUnit = n } }
+
+// WithRollback set the behaviour when the clock moves backwards, and the max wait duration for RollbackWait.
+func WithRollback(policy RollbackPolicy, maxWait time.Duration) OptionFn {
+	return func(o *Option) { o.Rollback, o.MaxRollbackWait = policy, maxWait }
+}
+
+// WithReservedStepBits set the number of the high step bits reserved for RollbackBorrow.
+func WithReservedStepBits(n int8) OptionFn { return func(o *Option) { o.ReservedStepBits = n } }
+
+// WithClock set the customized clock.
+func WithClock(clock func() time.Time) OptionFn { return func(o *Option) { o.Clock = clock } }
+
+// WithLastTime set the last timestamp generated by the previous node with the same NodeID.
+func WithLastTime(t int64) OptionFn { return func(o *Option) { o.LastTime = t } }
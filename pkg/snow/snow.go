@@ -2,11 +2,15 @@
 package snow
 
 import (
+	"errors"
 	"fmt"
 	"sync"
 	"time"
 )
 
+// ErrClockRollback is the error when the clock moves backwards and the RollbackPolicy gives up.
+var ErrClockRollback = errors.New("snow: clock moved backwards")
+
 // A Node struct holds the basic information needed for a snowflake generator node
 type Node struct {
 	mu sync.Mutex
@@ -20,6 +24,11 @@ type Node struct {
 	time int64
 	step int64
 
+	// seqMask is the step mask for the normal generation, excluding the reserved high bits.
+	seqMask    int64
+	borrowTime int64
+	borrowStep int64
+
 	// epoch is snowflake epoch in milliseconds.
 	epoch    int64
 	nodeMask int64
@@ -53,6 +62,14 @@ func (n *Node) applyOption(o Option) error {
 	n.stepMask = -1 ^ (-1 << o.StepBits)
 	n.timeShift = uint8(o.NodeBits + o.StepBits)
 	n.nodeShift = uint8(o.StepBits)
+	n.seqMask = n.stepMask
+	if o.Rollback == RollbackBorrow {
+		if o.ReservedStepBits >= o.StepBits {
+			return fmt.Errorf("ReservedStepBits %d must be less than StepBits %d", o.ReservedStepBits, o.StepBits)
+		}
+		n.seqMask = n.stepMask >> o.ReservedStepBits
+	}
+	n.time = o.LastTime
 
 	curTime := time.Now()
 	// add time.Duration to curTime to make sure we use the monotonic clock if available
@@ -88,17 +105,43 @@ func NewNode(optionFns ...OptionFn) (*Node, error) {
 // To help guarantee uniqueness
 // - Make sure your system is keeping accurate system time
 // - Make sure you never have multiple nodes running with the same node ID
+// It panics when the clock moves backwards and the RollbackPolicy gives up, use NextID to get the error instead.
 func (n *Node) Next() ID {
+	id, err := n.NextID()
+	if err != nil {
+		panic(err)
+	}
+
+	return id
+}
+
+// NextID creates and returns a unique snowflake ID,
+// or ErrClockRollback when the clock moves backwards and the RollbackPolicy gives up.
+func (n *Node) NextID() (ID, error) {
 	n.mu.Lock()
 	defer n.mu.Unlock()
 
 	return n.next()
 }
 
-func (n *Node) next() ID {
+func (n *Node) next() (ID, error) {
 	now := n.now()
+	if now < n.time {
+		switch n.option.Rollback {
+		case RollbackBorrow:
+			return n.borrow(now)
+		case RollbackError:
+			return 0, n.rollbackError(now)
+		default:
+			var err error
+			if now, err = n.waitRollback(now); err != nil {
+				return 0, err
+			}
+		}
+	}
+
 	if now == n.time {
-		if n.step = (n.step + 1) & n.stepMask; n.step == 0 {
+		if n.step = (n.step + 1) & n.seqMask; n.step == 0 {
 			for now <= n.time {
 				n.sleep()
 				now = n.now()
@@ -110,7 +153,59 @@ func (n *Node) next() ID {
 
 	n.time = now
 
-	return ID(now<<n.timeShift | n.nodeID<<n.nodeShift | n.step)
+	return n.id(now, n.step), nil
+}
+
+func (n *Node) id(now, step int64) ID {
+	return ID(now<<n.timeShift | n.nodeID<<n.nodeShift | step)
+}
+
+func (n *Node) rollbackError(now int64) error {
+	return fmt.Errorf("%w: %s behind", ErrClockRollback, n.behind(now))
+}
+
+func (n *Node) behind(now int64) time.Duration {
+	return time.Duration(n.time-now) * n.unit * time.Millisecond
+}
+
+// waitRollback waits for the clock catching up the last time, up to MaxRollbackWait.
+func (n *Node) waitRollback(now int64) (int64, error) {
+	deadline := time.Now().Add(n.option.MaxRollbackWait)
+	for now < n.time {
+		behind := n.behind(now)
+		if time.Now().Add(behind).After(deadline) {
+			return 0, n.rollbackError(now)
+		}
+
+		time.Sleep(behind)
+		now = n.now()
+	}
+
+	return now, nil
+}
+
+// borrow generates the ID with the rolled back time from the reserved step space.
+func (n *Node) borrow(now int64) (ID, error) {
+	if now < n.borrowTime {
+		return 0, fmt.Errorf("%w: rolled back again before the borrowed time", ErrClockRollback)
+	}
+
+	if now == n.borrowTime {
+		if n.borrowStep++; n.borrowStep > n.stepMask-n.seqMask-1 {
+			// the reserved space of the time is exhausted, wait for the next time
+			for now <= n.borrowTime {
+				time.Sleep(n.unit * time.Millisecond)
+				now = n.now()
+			}
+			return n.next()
+		}
+	} else {
+		n.borrowStep = 0
+	}
+
+	n.borrowTime = now
+
+	return n.id(now, n.seqMask+1+n.borrowStep), nil
 }
 
 func (n *Node) sleep() {
@@ -120,5 +215,9 @@ func (n *Node) sleep() {
 }
 
 func (n *Node) now() int64 {
+	if n.option.Clock != nil {
+		return n.option.Clock().Sub(n.epochTime).Nanoseconds() / 1e6 / int64(n.unit)
+	}
+
 	return time.Since(n.epochTime).Nanoseconds() / 1e6 / int64(n.unit)
 }
@@ -527,3 +527,87 @@ func BenchmarkMarshal(b *testing.B) {
 		_, _ = id.MarshalJSON()
 	}
 }
+
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) Now() time.Time { return c.t }
+
+func TestRollbackError(t *testing.T) {
+	clock := &fakeClock{t: time.Now().Round(0)}
+	node, _ := NewNode(WithNodeID(1), WithClock(clock.Now), WithRollback(RollbackError, 0))
+
+	id1 := node.Next()
+	clock.t = clock.t.Add(-10 * time.Millisecond)
+	_, err := node.NextID()
+	assert.ErrorIs(t, err, ErrClockRollback)
+
+	clock.t = clock.t.Add(11 * time.Millisecond)
+	id2, err := node.NextID()
+	assert.Nil(t, err)
+	assert.True(t, id2 > id1)
+}
+
+func TestRollbackWait(t *testing.T) {
+	clock := &fakeClock{t: time.Now().Round(0)}
+	node, _ := NewNode(WithNodeID(1), WithClock(clock.Now), WithRollback(RollbackWait, 50*time.Millisecond))
+
+	node.Next()
+	clock.t = clock.t.Add(-time.Second)
+	_, err := node.NextID()
+	assert.ErrorIs(t, err, ErrClockRollback)
+
+	// wall clock rollback within the bound is waited
+	node, _ = NewNode(WithNodeID(1), WithClock(func() time.Time { return time.Now().Round(0) }),
+		WithRollback(RollbackWait, time.Second))
+	id1 := node.Next()
+	node.time += 20 // pretend the last time is 20ms ahead
+	start := time.Now()
+	id2, err := node.NextID()
+	assert.Nil(t, err)
+	assert.True(t, time.Since(start) >= 15*time.Millisecond)
+	assert.True(t, id2 > id1)
+}
+
+func TestRollbackBorrow(t *testing.T) {
+	clock := &fakeClock{t: time.Now().Round(0)}
+	node, _ := NewNode(WithNodeID(1), WithStepBits(3), WithClock(clock.Now), WithRollback(RollbackBorrow, 0))
+
+	ids := map[ID]bool{}
+	for i := 0; i < 4; i++ {
+		ids[node.Next()] = true
+	}
+
+	// rolled back, the reserved high steps are used
+	clock.t = clock.t.Add(-time.Millisecond)
+	for i := 0; i < 4; i++ {
+		id, err := node.NextID()
+		assert.Nil(t, err)
+		assert.False(t, ids[id])
+		assert.True(t, node.StepOf(id) >= 4)
+		ids[id] = true
+	}
+
+	// rolled back again before the borrowed time
+	clock.t = clock.t.Add(-time.Millisecond)
+	_, err := node.NextID()
+	assert.ErrorIs(t, err, ErrClockRollback)
+
+	// back to normal after the clock catching up
+	clock.t = clock.t.Add(10 * time.Millisecond)
+	id, err := node.NextID()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), node.StepOf(id))
+}
+
+func TestLastTime(t *testing.T) {
+	clock := &fakeClock{t: time.Now().Round(0)}
+	node, _ := NewNode(WithNodeID(1), WithClock(clock.Now))
+	id := node.Next()
+
+	// the next holder of the node ID with a clock 1 hour behind
+	clock.t = clock.t.Add(-time.Hour)
+	node2, _ := NewNode(WithNodeID(1), WithClock(clock.Now), WithLastTime(node.TimeOf(id)-node.GetEpoch()),
+		WithRollback(RollbackError, 0))
+	_, err := node2.NextID()
+	assert.ErrorIs(t, err, ErrClockRollback)
+}
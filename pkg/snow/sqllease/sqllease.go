@@ -0,0 +1,168 @@
+// Package sqllease implements snow.NodeLeaser on a SQL table by pkg/sqx.
+package sqllease
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bingoohuang/gg/pkg/snow"
+	"github.com/bingoohuang/gg/pkg/sqx"
+)
+
+// DefaultTable is the default table name of the leases.
+const DefaultTable = "snow_node_lease"
+
+// CreateTableSQL is the DDL to create the lease table, %s is the table name.
+// expires and last_time are in unix milliseconds and snowflake timestamp units.
+const CreateTableSQL = `create table %s (
+	node_id bigint primary key,
+	owner varchar(100) not null,
+	expires bigint not null,
+	last_time bigint not null
+)`
+
+type Config struct {
+	DB sqx.SqxDB
+	// Table is the table name of the leases, default is DefaultTable.
+	Table string
+	// Now returns the current time for the leases, default is time.Now,
+	// the clock skew among the nodes should be much less than the ttl.
+	Now func() time.Time
+}
+
+// Leaser is a snow.NodeLeaser on a SQL table, one row per leased node ID.
+// The leases are taken over by compare-and-swap on owner and expires, so it needs no transactions or locks.
+type Leaser struct {
+	Config
+}
+
+var _ snow.NodeLeaser = (*Leaser)(nil)
+
+// NewLeaser creates a Leaser.
+func NewLeaser(c Config) *Leaser {
+	if c.Table == "" {
+		c.Table = DefaultTable
+	}
+	if c.Now == nil {
+		c.Now = time.Now
+	}
+
+	return &Leaser{Config: c}
+}
+
+// CreateTable creates the lease table if not exists.
+func (l *Leaser) CreateTable(ctx context.Context) error {
+	q := fmt.Sprintf(`select count(*) from %s`, l.Table)
+	if _, err := (sqx.SQL{Q: q, Ctx: ctx, NoLog: true}).QueryAsNumber(l.DB); err == nil {
+		return nil
+	}
+
+	_, err := sqx.SQL{Q: fmt.Sprintf(CreateTableSQL, l.Table), Ctx: ctx, NoLog: true}.Update(l.DB)
+	return err
+}
+
+type leaseRow struct {
+	NodeID   int64  `col:"node_id"`
+	Owner    string `col:"owner"`
+	Expires  int64  `col:"expires"`
+	LastTime int64  `col:"last_time"`
+}
+
+// Acquire leases a node ID in [0, maxNodeID] to the owner for ttl.
+func (l *Leaser) Acquire(ctx context.Context, owner string, maxNodeID int64, ttl time.Duration) (*snow.Lease, error) {
+	var rows []leaseRow
+	q := fmt.Sprintf(`select node_id, owner, expires, last_time from %s order by node_id`, l.Table)
+	if err := (sqx.SQL{Q: q, Ctx: ctx, NoLog: true}).Query(l.DB, &rows); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	now := l.Now()
+	var candidates []leaseRow
+	used := make(map[int64]bool, len(rows))
+	for _, r := range rows {
+		used[r.NodeID] = true
+		if r.NodeID <= maxNodeID && (r.Owner == owner || r.Expires < now.UnixMilli()) {
+			candidates = append(candidates, r)
+		}
+	}
+	// the owner's own lease first, then the expired ones
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Owner == owner && candidates[j].Owner != owner
+	})
+
+	expires := now.Add(ttl)
+	for _, r := range candidates {
+		q := fmt.Sprintf(`update %s set owner = ?, expires = ? where node_id = ? and owner = ? and expires = ?`, l.Table)
+		n, err := sqx.SQL{Q: q, Vars: sqx.Vars(owner, expires.UnixMilli(), r.NodeID, r.Owner, r.Expires), Ctx: ctx, NoLog: true}.Update(l.DB)
+		if err != nil {
+			return nil, err
+		}
+		if n == 1 {
+			return &snow.Lease{NodeID: r.NodeID, Owner: owner, Expires: expires, LastTime: r.LastTime}, nil
+		}
+	}
+
+	for nodeID := int64(0); nodeID <= maxNodeID; nodeID++ {
+		if used[nodeID] {
+			continue
+		}
+
+		q := fmt.Sprintf(`insert into %s(node_id, owner, expires, last_time) values(?, ?, ?, 0)`, l.Table)
+		if _, err := (sqx.SQL{Q: q, Vars: sqx.Vars(nodeID, owner, expires.UnixMilli()), Ctx: ctx, NoLog: true}).Update(l.DB); err != nil {
+			if isDuplicate(err) { // inserted by others concurrently
+				continue
+			}
+			return nil, err
+		}
+
+		return &snow.Lease{NodeID: nodeID, Owner: owner, Expires: expires}, nil
+	}
+
+	return nil, snow.ErrNoNodeID
+}
+
+// Renew extends the lease for ttl, with the last timestamp generated by the node.
+func (l *Leaser) Renew(ctx context.Context, lease *snow.Lease, lastTime int64, ttl time.Duration) error {
+	expires := l.Now().Add(ttl)
+	if err := l.update(ctx, lease, expires.UnixMilli(), lastTime); err != nil {
+		return err
+	}
+
+	lease.Expires, lease.LastTime = expires, lastTime
+	return nil
+}
+
+// Release releases the lease, with the last timestamp generated by the node.
+func (l *Leaser) Release(ctx context.Context, lease *snow.Lease, lastTime int64) error {
+	if err := l.update(ctx, lease, 0, lastTime); err != nil {
+		return err
+	}
+
+	lease.Expires, lease.LastTime = time.UnixMilli(0), lastTime
+	return nil
+}
+
+func (l *Leaser) update(ctx context.Context, lease *snow.Lease, expires, lastTime int64) error {
+	q := fmt.Sprintf(`update %s set expires = ?, last_time = ? where node_id = ? and owner = ? and expires = ?`, l.Table)
+	vars := sqx.Vars(expires, lastTime, lease.NodeID, lease.Owner, lease.Expires.UnixMilli())
+	n, err := sqx.SQL{Q: q, Vars: vars, Ctx: ctx, NoLog: true}.Update(l.DB)
+	if err != nil {
+		return err
+	}
+	if n != 1 {
+		return snow.ErrLeaseLost
+	}
+
+	return nil
+}
+
+// isDuplicate tells whether the error is a primary key violation, in MySQL, PostgreSQL, SQLite or others.
+func isDuplicate(err error) bool {
+	s := strings.ToLower(err.Error())
+	return strings.Contains(s, "duplicate") || strings.Contains(s, "unique")
+}
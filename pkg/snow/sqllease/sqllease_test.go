@@ -0,0 +1,108 @@
+package sqllease_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bingoohuang/gg/pkg/snow"
+	"github.com/bingoohuang/gg/pkg/snow/sqllease"
+	"github.com/bingoohuang/gg/pkg/sqx"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeaser(t *testing.T) {
+	_, db, err := sqx.Open("sqlite3", filepath.Join(t.TempDir(), "lease.db"))
+	assert.Nil(t, err)
+	defer db.Close()
+
+	now := time.Now()
+	l := sqllease.NewLeaser(sqllease.Config{DB: db, Now: func() time.Time { return now }})
+	ctx := context.Background()
+	assert.Nil(t, l.CreateTable(ctx))
+
+	a, err := l.Acquire(ctx, "a", 1, time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), a.NodeID)
+
+	b, err := l.Acquire(ctx, "b", 1, time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), b.NodeID)
+
+	_, err = l.Acquire(ctx, "c", 1, time.Minute)
+	assert.Equal(t, snow.ErrNoNodeID, err)
+
+	// the owner gets its own lease again
+	now = now.Add(time.Second)
+	a2, err := l.Acquire(ctx, "a", 1, time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), a2.NodeID)
+	// the previous lease of a is replaced
+	assert.Equal(t, snow.ErrLeaseLost, l.Renew(ctx, a, 100, time.Minute))
+
+	assert.Nil(t, l.Renew(ctx, b, 200, time.Minute))
+
+	// b expires and c takes it over with the last time
+	now = now.Add(2 * time.Minute)
+	assert.Nil(t, l.Renew(ctx, a2, 300, time.Minute))
+	c, err := l.Acquire(ctx, "c", 1, time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), c.NodeID)
+	assert.Equal(t, int64(200), c.LastTime)
+	assert.Equal(t, snow.ErrLeaseLost, l.Renew(ctx, b, 400, time.Minute))
+
+	// released lease is free immediately
+	assert.Nil(t, l.Release(ctx, a2, 500))
+	d, err := l.Acquire(ctx, "d", 1, time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), d.NodeID)
+	assert.Equal(t, int64(500), d.LastTime)
+}
+
+func TestLeasedNode(t *testing.T) {
+	_, db, err := sqx.Open("sqlite3", filepath.Join(t.TempDir(), "lease.db"))
+	assert.Nil(t, err)
+	defer db.Close()
+
+	l := sqllease.NewLeaser(sqllease.Config{DB: db})
+	assert.Nil(t, l.CreateTable(context.Background()))
+
+	n1, err := snow.NewLeasedNode(l, "n1", 300*time.Millisecond, snow.WithNodeBits(1))
+	assert.Nil(t, err)
+	n2, err := snow.NewLeasedNode(l, "n2", 300*time.Millisecond, snow.WithNodeBits(1))
+	assert.Nil(t, err)
+	assert.NotEqual(t, n1.GetNodeID(), n2.GetNodeID())
+
+	_, err = snow.NewLeasedNode(l, "n3", 300*time.Millisecond, snow.WithNodeBits(1))
+	assert.Equal(t, snow.ErrNoNodeID, err)
+
+	// renewed by the heartbeats
+	time.Sleep(500 * time.Millisecond)
+	id, err := n1.NextID()
+	assert.Nil(t, err)
+	assert.Equal(t, n1.GetNodeID(), n1.NodeIDOf(id))
+
+	assert.Nil(t, n1.Close())
+	_, err = n1.NextID()
+	assert.Equal(t, snow.ErrLeaseLost, err)
+
+	// n3 takes over the released node ID with the last time of n1
+	n3, err := snow.NewLeasedNode(l, "n3", 300*time.Millisecond, snow.WithNodeBits(1))
+	assert.Nil(t, err)
+	assert.Equal(t, n1.GetNodeID(), n3.GetNodeID())
+	assert.Equal(t, n1.TimeOf(id)-n1.GetEpoch(), n3.Lease().LastTime)
+	assert.True(t, n3.Next() > id)
+
+	assert.Nil(t, n2.Close())
+	assert.Nil(t, n3.Close())
+}
+
+func TestCreateTableTwice(t *testing.T) {
+	_, db, _ := sqx.Open("sqlite3", filepath.Join(t.TempDir(), "lease.db"))
+	defer db.Close()
+	l := sqllease.NewLeaser(sqllease.Config{DB: db})
+	assert.Nil(t, l.CreateTable(context.Background()))
+	assert.Nil(t, l.CreateTable(context.Background()))
+}
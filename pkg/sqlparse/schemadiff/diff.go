@@ -0,0 +1,368 @@
+package schemadiff
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Diff is the difference between the old and the new schemas.
+type Diff struct {
+	CreatedTables []*Table
+	DroppedTables []*Table
+	AlteredTables []*TableDiff
+}
+
+// TableDiff is the difference of a table in both schemas.
+// The order of the existing columns is not compared.
+type TableDiff struct {
+	Old, New *Table
+
+	AddedColumns    []*Column
+	DroppedColumns  []*Column
+	ModifiedColumns []ColumnChange
+	AddedIndexes    []*Index
+	DroppedIndexes  []*Index
+	ModifiedIndexes []IndexChange
+	Options         []OptionChange
+}
+
+// ColumnChange is a column changed.
+type ColumnChange struct {
+	Old, New *Column
+}
+
+// IndexChange is an index changed, which is dropped and added again.
+type IndexChange struct {
+	Old, New *Index
+}
+
+// OptionChange is a table option changed, like engine, charset, collate and comment.
+type OptionChange struct {
+	Name     string
+	Old, New string
+}
+
+// Compare compares the old and the new schemas.
+func Compare(old, new *Schema) *Diff {
+	d := &Diff{}
+	for _, t := range new.Tables {
+		if o := old.Table(t.Name); o == nil {
+			d.CreatedTables = append(d.CreatedTables, t)
+		} else if td := compareTable(o, t); !td.Empty() {
+			d.AlteredTables = append(d.AlteredTables, td)
+		}
+	}
+	for _, t := range old.Tables {
+		if new.Table(t.Name) == nil {
+			d.DroppedTables = append(d.DroppedTables, t)
+		}
+	}
+	return d
+}
+
+// CompareSQL parses the old and the new CREATE statements, and compares them.
+func CompareSQL(oldDDL, newDDL string) (*Diff, error) {
+	old, err := Parse(oldDDL)
+	if err != nil {
+		return nil, fmt.Errorf("parse old schema: %w", err)
+	}
+	n, err := Parse(newDDL)
+	if err != nil {
+		return nil, fmt.Errorf("parse new schema: %w", err)
+	}
+	return Compare(old, n), nil
+}
+
+func compareTable(old, new *Table) *TableDiff {
+	td := &TableDiff{Old: old, New: new}
+	for _, c := range new.Columns {
+		if o := old.Column(c.Name); o == nil {
+			td.AddedColumns = append(td.AddedColumns, c)
+		} else if o.Definition() != c.Definition() {
+			td.ModifiedColumns = append(td.ModifiedColumns, ColumnChange{Old: o, New: c})
+		}
+	}
+	for _, c := range old.Columns {
+		if new.Column(c.Name) == nil {
+			td.DroppedColumns = append(td.DroppedColumns, c)
+		}
+	}
+
+	for _, i := range new.Indexes {
+		if o := old.Index(i.Name); o == nil {
+			td.AddedIndexes = append(td.AddedIndexes, i)
+		} else if o.Definition() != i.Definition() {
+			td.ModifiedIndexes = append(td.ModifiedIndexes, IndexChange{Old: o, New: i})
+		}
+	}
+	for _, i := range old.Indexes {
+		if new.Index(i.Name) == nil {
+			td.DroppedIndexes = append(td.DroppedIndexes, i)
+		}
+	}
+
+	// the options absent in the new table are left as they are, except the comment.
+	option := func(name, o, n string) {
+		if n != "" && !strings.EqualFold(o, n) {
+			td.Options = append(td.Options, OptionChange{Name: name, Old: o, New: n})
+		}
+	}
+	option("engine", old.Engine, new.Engine)
+	option("charset", old.Charset, new.Charset)
+	option("collate", old.Collate, new.Collate)
+	if old.Comment != new.Comment {
+		td.Options = append(td.Options, OptionChange{Name: "comment", Old: old.Comment, New: new.Comment})
+	}
+	return td
+}
+
+// Empty tells whether there is no difference.
+func (d *Diff) Empty() bool {
+	return len(d.CreatedTables) == 0 && len(d.DroppedTables) == 0 && len(d.AlteredTables) == 0
+}
+
+// Empty tells whether there is no difference of the table.
+func (td *TableDiff) Empty() bool {
+	return len(td.AddedColumns) == 0 && len(td.DroppedColumns) == 0 && len(td.ModifiedColumns) == 0 &&
+		len(td.AddedIndexes) == 0 && len(td.DroppedIndexes) == 0 && len(td.ModifiedIndexes) == 0 &&
+		len(td.Options) == 0
+}
+
+// Changed returns the changed attributes of the column, like type, charset, null and default.
+func (c ColumnChange) Changed() []string {
+	var changed []string
+	add := func(name string, diff bool) {
+		if diff {
+			changed = append(changed, name)
+		}
+	}
+	o, n := c.Old, c.New
+	add("name", o.Name != n.Name)
+	add("type", o.Type != n.Type)
+	add("charset", o.Charset != n.Charset)
+	add("collate", o.Collate != n.Collate)
+	add("null", o.NotNull != n.NotNull)
+	add("default", o.Default != n.Default)
+	add("on_update", o.OnUpdate != n.OnUpdate)
+	add("auto_increment", o.AutoIncrement != n.AutoIncrement)
+	add("generated", o.Generated != n.Generated || o.Stored != n.Stored)
+	add("comment", o.Comment != n.Comment)
+	return changed
+}
+
+// ErrUnsafe tells that the migration drops the tables, columns or indexes in the safe mode.
+var ErrUnsafe = errors.New("unsafe migration")
+
+type statementOption struct {
+	safe bool
+}
+
+// StatementOption is the option of Statements.
+type StatementOption func(*statementOption)
+
+// WithSafe refuses to drop the tables, columns and indexes, the redefined indexes are still allowed.
+func WithSafe() StatementOption { return func(o *statementOption) { o.safe = true } }
+
+// Statements works out the DDL statements to migrate the old schema to the new one, in the order of:
+//  1. dropping the foreign keys changed, which may refer to the columns or the tables to be dropped,
+//  2. altering the tables, with the clauses of drop index, drop column, modify column, add column, add index and options,
+//  3. creating the tables, with the referred ones first,
+//  4. adding the foreign keys to the altered tables, which may refer to the created tables,
+//  5. dropping the tables, with the referring ones first.
+func (d *Diff) Statements(options ...StatementOption) ([]string, error) {
+	o := &statementOption{}
+	for _, f := range options {
+		f(o)
+	}
+	if o.safe {
+		if drops := d.drops(); len(drops) > 0 {
+			return nil, fmt.Errorf("%w: drop %s", ErrUnsafe, strings.Join(drops, ", "))
+		}
+	}
+
+	var stmts []string
+	alter := func(table string, clauses []string) {
+		if len(clauses) > 0 {
+			stmts = append(stmts, "ALTER TABLE "+quote(table)+" "+strings.Join(clauses, ", "))
+		}
+	}
+	for _, td := range d.AlteredTables {
+		alter(td.New.Name, td.dropForeignKeys())
+	}
+	for _, td := range d.AlteredTables {
+		alter(td.New.Name, td.clauses())
+	}
+	for _, t := range sortByReferences(d.CreatedTables) {
+		stmts = append(stmts, t.Definition())
+	}
+	for _, td := range d.AlteredTables {
+		alter(td.New.Name, td.addForeignKeys())
+	}
+	dropped := sortByReferences(d.DroppedTables)
+	for i := len(dropped) - 1; i >= 0; i-- {
+		stmts = append(stmts, "DROP TABLE "+quote(dropped[i].Name))
+	}
+	return stmts, nil
+}
+
+// drops returns the tables, columns and indexes to be dropped.
+func (d *Diff) drops() []string {
+	var drops []string
+	for _, t := range d.DroppedTables {
+		drops = append(drops, "table "+t.Name)
+	}
+	for _, td := range d.AlteredTables {
+		for _, c := range td.DroppedColumns {
+			drops = append(drops, "column "+td.New.Name+"."+c.Name)
+		}
+		for _, i := range td.DroppedIndexes {
+			drops = append(drops, "index "+td.New.Name+"."+i.Name)
+		}
+	}
+	return drops
+}
+
+func (td *TableDiff) dropForeignKeys() []string {
+	var clauses []string
+	for _, i := range td.DroppedIndexes {
+		if i.Kind == ForeignKey {
+			clauses = append(clauses, dropIndex(i))
+		}
+	}
+	for _, c := range td.ModifiedIndexes {
+		if c.Old.Kind == ForeignKey {
+			clauses = append(clauses, dropIndex(c.Old))
+		}
+	}
+	return clauses
+}
+
+func (td *TableDiff) addForeignKeys() []string {
+	var clauses []string
+	for _, c := range td.ModifiedIndexes {
+		if c.New.Kind == ForeignKey {
+			clauses = append(clauses, "ADD "+c.New.Definition())
+		}
+	}
+	for _, i := range td.AddedIndexes {
+		if i.Kind == ForeignKey {
+			clauses = append(clauses, "ADD "+i.Definition())
+		}
+	}
+	return clauses
+}
+
+// clauses returns the alter clauses of the table except the ones of the foreign keys.
+func (td *TableDiff) clauses() []string {
+	var clauses []string
+	for _, i := range td.DroppedIndexes {
+		if i.Kind != ForeignKey {
+			clauses = append(clauses, dropIndex(i))
+		}
+	}
+	for _, c := range td.ModifiedIndexes {
+		if c.Old.Kind != ForeignKey {
+			clauses = append(clauses, dropIndex(c.Old))
+		}
+	}
+	for _, c := range td.DroppedColumns {
+		clauses = append(clauses, "DROP COLUMN "+quote(c.Name))
+	}
+	for _, c := range td.ModifiedColumns {
+		if c.Old.Name != c.New.Name { // the case of the name changed
+			clauses = append(clauses, "CHANGE COLUMN "+quote(c.Old.Name)+" "+c.New.Definition())
+		} else {
+			clauses = append(clauses, "MODIFY COLUMN "+c.New.Definition())
+		}
+	}
+	for _, c := range td.AddedColumns {
+		clauses = append(clauses, "ADD COLUMN "+c.Definition()+td.position(c))
+	}
+	for _, c := range td.ModifiedIndexes {
+		if c.New.Kind != ForeignKey {
+			clauses = append(clauses, "ADD "+c.New.Definition())
+		}
+	}
+	for _, i := range td.AddedIndexes {
+		if i.Kind != ForeignKey {
+			clauses = append(clauses, "ADD "+i.Definition())
+		}
+	}
+	for _, o := range td.Options {
+		switch o.Name {
+		case "engine":
+			clauses = append(clauses, "ENGINE="+o.New)
+		case "charset":
+			clauses = append(clauses, "DEFAULT CHARSET="+o.New)
+		case "collate":
+			clauses = append(clauses, "COLLATE="+o.New)
+		case "comment":
+			clauses = append(clauses, "COMMENT="+quoteString(o.New))
+		}
+	}
+	return clauses
+}
+
+// position returns the position of the added column, empty when it is appended to the end.
+func (td *TableDiff) position(c *Column) string {
+	columns := td.New.Columns
+	i := 0
+	for columns[i] != c {
+		i++
+	}
+	appended := true
+	for _, after := range columns[i+1:] {
+		if td.Old.Column(after.Name) != nil {
+			appended = false
+			break
+		}
+	}
+	switch {
+	case appended:
+		return ""
+	case i == 0:
+		return " FIRST"
+	default:
+		return " AFTER " + quote(columns[i-1].Name)
+	}
+}
+
+func dropIndex(i *Index) string {
+	switch i.Kind {
+	case PrimaryKey:
+		return "DROP PRIMARY KEY"
+	case ForeignKey:
+		return "DROP FOREIGN KEY " + quote(i.Name)
+	default:
+		return "DROP INDEX " + quote(i.Name)
+	}
+}
+
+// sortByReferences sorts the tables so that the referred ones come before the referring ones.
+func sortByReferences(tables []*Table) []*Table {
+	byName := map[string]*Table{}
+	for _, t := range tables {
+		byName[strings.ToLower(t.Name)] = t
+	}
+
+	sorted := make([]*Table, 0, len(tables))
+	visited := map[*Table]bool{}
+	var visit func(t *Table)
+	visit = func(t *Table) {
+		if visited[t] {
+			return
+		}
+		visited[t] = true
+		for _, i := range t.Indexes {
+			if r := byName[strings.ToLower(i.RefTable)]; i.Kind == ForeignKey && r != nil {
+				visit(r)
+			}
+		}
+		sorted = append(sorted, t)
+	}
+	for _, t := range tables {
+		visit(t)
+	}
+	return sorted
+}
@@ -0,0 +1,135 @@
+package schemadiff_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bingoohuang/gg/pkg/sqlparse/schemadiff"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	s, err := schemadiff.Parse("create table t (id bigint unsigned auto_increment primary key comment 'the id', " +
+		"name varchar(64) character set utf8mb4 not null default 'x', n int default null, " +
+		"created datetime(3) default current_timestamp(3) on update current_timestamp(3), " +
+		"unique key (name(10)), key idx_c (created, n) comment 'ic', foreign key (n) references o(id) on delete cascade" +
+		") engine=InnoDB default charset=utf8mb4 comment='tt';" +
+		"create index idx_n on t (n)")
+	require.Nil(t, err)
+
+	tb := s.Table("T")
+	require.NotNil(t, tb)
+	assert.Equal(t, "CREATE TABLE `t` (\n"+
+		"  `id` bigint(20) UNSIGNED NOT NULL AUTO_INCREMENT COMMENT 'the id',\n"+
+		"  `name` varchar(64) CHARACTER SET utf8mb4 NOT NULL DEFAULT 'x',\n"+
+		"  `n` int(11),\n"+
+		"  `created` datetime(3) DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3),\n"+
+		"  PRIMARY KEY (`id`),\n"+
+		"  UNIQUE KEY `name` (`name`(10)),\n"+
+		"  KEY `idx_c` (`created`,`n`) COMMENT 'ic',\n"+
+		"  CONSTRAINT `t_ibfk_1` FOREIGN KEY (`n`) REFERENCES `o` (`id`) ON DELETE CASCADE,\n"+
+		"  KEY `idx_n` (`n`)\n"+
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='tt'", tb.Definition())
+
+	_, err = schemadiff.Parse("create index idx on absent (n)")
+	assert.NotNil(t, err)
+}
+
+func TestStatements(t *testing.T) {
+	cases := []struct {
+		name     string
+		old, new string
+		want     []string
+	}{
+		{
+			name: "same",
+			old:  "create table t (id int primary key, name varchar(10) default null)",
+			new:  "create table T (id int not null, name varchar(10), primary key (id))",
+		},
+		{
+			name: "columns",
+			old:  "create table t (id int primary key, name varchar(10), age int, addr varchar(10))",
+			new: "create table t (id int primary key, code varchar(5) not null default '', " +
+				"name varchar(20) not null default 'it''s', addr varchar(10) charset utf8mb4, memo text)",
+			want: []string{"ALTER TABLE `t` DROP COLUMN `age`, " +
+				"MODIFY COLUMN `name` varchar(20) NOT NULL DEFAULT 'it''s', " +
+				"MODIFY COLUMN `addr` varchar(10) CHARACTER SET utf8mb4, " +
+				"ADD COLUMN `code` varchar(5) NOT NULL DEFAULT '' AFTER `id`, " +
+				"ADD COLUMN `memo` text"},
+		},
+		{
+			name: "indexes and options",
+			old:  "create table t (id int, a int, b int, primary key (id), key ia (a), key ib (b)) engine=MyISAM",
+			new: "create table t (id int, a int, b int, primary key (id, a), key ia (a, b), unique key ub (b)) " +
+				"engine=InnoDB default charset=utf8mb4 comment='t'",
+			want: []string{"ALTER TABLE `t` DROP INDEX `ib`, DROP PRIMARY KEY, DROP INDEX `ia`, " +
+				"MODIFY COLUMN `a` int(11) NOT NULL, " +
+				"ADD PRIMARY KEY (`id`,`a`), ADD KEY `ia` (`a`,`b`), ADD UNIQUE KEY `ub` (`b`), " +
+				"ENGINE=InnoDB, DEFAULT CHARSET=utf8mb4, COMMENT='t'"},
+		},
+		{
+			name: "tables and foreign keys",
+			old: "create table a (id int primary key, bid int, constraint fk_b foreign key (bid) references b (id));" +
+				"create table b (id int primary key);" +
+				"create table c (id int primary key, did int, constraint fk_d foreign key (did) references d (id));" +
+				"create table d (id int primary key)",
+			new: "create table f (id int primary key, eid int, constraint fk_e foreign key (eid) references e (id));" +
+				"create table e (id int primary key);" +
+				"create table a (id int primary key, bid int, eid int, constraint fk_e foreign key (eid) references e (id))",
+			want: []string{
+				"ALTER TABLE `a` DROP FOREIGN KEY `fk_b`",
+				"ALTER TABLE `a` ADD COLUMN `eid` int(11)",
+				"CREATE TABLE `e` (\n  `id` int(11) NOT NULL,\n  PRIMARY KEY (`id`)\n)",
+				"CREATE TABLE `f` (\n  `id` int(11) NOT NULL,\n  `eid` int(11),\n  PRIMARY KEY (`id`),\n" +
+					"  CONSTRAINT `fk_e` FOREIGN KEY (`eid`) REFERENCES `e` (`id`)\n)",
+				"ALTER TABLE `a` ADD CONSTRAINT `fk_e` FOREIGN KEY (`eid`) REFERENCES `e` (`id`)",
+				"DROP TABLE `c`",
+				"DROP TABLE `d`",
+				"DROP TABLE `b`",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, err := schemadiff.CompareSQL(c.old, c.new)
+			require.Nil(t, err)
+			assert.Equal(t, len(c.want) == 0, d.Empty())
+			stmts, err := d.Statements()
+			require.Nil(t, err)
+			assert.Equal(t, c.want, stmts)
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	d, err := schemadiff.CompareSQL(
+		"create table t (id int, name varchar(10) charset latin1 default 'a') charset=latin1",
+		"create table t (id int, name varchar(10) charset utf8mb4 default 'b') charset=utf8mb4")
+	require.Nil(t, err)
+	require.Len(t, d.AlteredTables, 1)
+
+	td := d.AlteredTables[0]
+	require.Len(t, td.ModifiedColumns, 1)
+	assert.Equal(t, []string{"charset", "default"}, td.ModifiedColumns[0].Changed())
+	assert.Equal(t, []schemadiff.OptionChange{{Name: "charset", Old: "latin1", New: "utf8mb4"}}, td.Options)
+}
+
+func TestStatementsSafe(t *testing.T) {
+	d, err := schemadiff.CompareSQL(
+		"create table t (id int, a int, key ia (a), key ib (id)); create table o (id int)",
+		"create table t (id int, a int, key ib (id, a))")
+	require.Nil(t, err)
+	_, err = d.Statements(schemadiff.WithSafe())
+	assert.True(t, errors.Is(err, schemadiff.ErrUnsafe))
+	assert.Equal(t, "unsafe migration: drop table o, index t.ia", err.Error())
+
+	// the redefined index is allowed.
+	d, err = schemadiff.CompareSQL("create table t (id int, a int, key ib (id))",
+		"create table t (id int, a int, key ib (id, a))")
+	require.Nil(t, err)
+	stmts, err := d.Statements(schemadiff.WithSafe())
+	require.Nil(t, err)
+	assert.Equal(t, []string{"ALTER TABLE `t` DROP INDEX `ib`, ADD KEY `ib` (`id`,`a`)"}, stmts)
+}
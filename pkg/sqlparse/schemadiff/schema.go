@@ -0,0 +1,452 @@
+// Package schemadiff compares two schemas of the CREATE statements parsed by tidbparser,
+// and works out the ordered DDL statements to migrate the old one to the new one.
+package schemadiff
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bingoohuang/gg/pkg/sqlparse/tidbparser/ast"
+	"github.com/bingoohuang/gg/pkg/sqlparse/tidbparser/dependency/types"
+	"github.com/bingoohuang/gg/pkg/sqlparse/tidbparser/dependency/util/charset"
+	tidbparser "github.com/bingoohuang/gg/pkg/sqlparse/tidbparser/parser"
+)
+
+// Schema is the tables defined by the CREATE statements.
+type Schema struct {
+	Tables []*Table
+}
+
+// Table is a table of the schema.
+type Table struct {
+	Name    string
+	Columns []*Column
+	// Indexes are the primary key, the unique keys, the keys, the fulltext keys and the foreign keys.
+	Indexes []*Index
+	Engine  string
+	Charset string
+	Collate string
+	Comment string
+}
+
+// Column is a column of the table.
+type Column struct {
+	Name string
+	// Type is the type without the charset and collate, like varchar(64) or bigint(20) UNSIGNED.
+	Type    string
+	Charset string
+	Collate string
+	NotNull bool
+	// Default is the default value in SQL, like 'abc', 0 or CURRENT_TIMESTAMP, empty for no default.
+	Default       string
+	OnUpdate      string
+	AutoIncrement bool
+	// Generated is the expression of the generated column, empty for the normal column.
+	Generated string
+	Stored    bool
+	Comment   string
+}
+
+// IndexKind is the kind of the index.
+type IndexKind int
+
+const (
+	// PrimaryKey is the primary key.
+	PrimaryKey IndexKind = iota + 1
+	// UniqueKey is the unique key.
+	UniqueKey
+	// Key is the normal key.
+	Key
+	// FulltextKey is the fulltext key.
+	FulltextKey
+	// ForeignKey is the foreign key constraint.
+	ForeignKey
+)
+
+// IndexColumn is a column of the index.
+type IndexColumn struct {
+	Name string
+	// Length is the prefix length, like 10 of name(10), 0 for the whole column.
+	Length int
+}
+
+// Index is an index or a foreign key of the table.
+type Index struct {
+	// Name is PRIMARY for the primary key, and is generated like MySQL when unnamed.
+	Name    string
+	Kind    IndexKind
+	Columns []IndexColumn
+	Comment string
+
+	// RefTable, RefColumns, OnDelete and OnUpdate are only for the foreign key.
+	RefTable   string
+	RefColumns []IndexColumn
+	OnDelete   string
+	OnUpdate   string
+}
+
+// Parse parses the schema from the CREATE TABLE and CREATE INDEX statements, the other statements are ignored.
+func Parse(ddl string) (*Schema, error) {
+	stmts, err := tidbparser.New().Parse(ddl, "", "")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Schema{}
+	for _, stmt := range stmts {
+		switch st := stmt.(type) {
+		case *ast.CreateTableStmt:
+			if s.Table(st.Table.Name.O) != nil {
+				return nil, fmt.Errorf("duplicate table %s", st.Table.Name.O) // nolint:goerr113
+			}
+			t, err := s.parseTable(st)
+			if err != nil {
+				return nil, err
+			}
+			s.Tables = append(s.Tables, t)
+		case *ast.CreateIndexStmt:
+			t := s.Table(st.Table.Name.O)
+			if t == nil {
+				return nil, fmt.Errorf("create index %s on unknown table %s", st.IndexName, st.Table.Name.O) // nolint:goerr113
+			}
+			kind := Key
+			if st.Unique {
+				kind = UniqueKey
+			}
+			index := &Index{Name: st.IndexName, Kind: kind, Columns: indexColumns(st.IndexColNames)}
+			if st.IndexOption != nil {
+				index.Comment = st.IndexOption.Comment
+			}
+			t.addIndex(index)
+		}
+	}
+	return s, nil
+}
+
+// Table finds the table by the name case-insensitively, or nil when absent.
+func (s *Schema) Table(name string) *Table {
+	for _, t := range s.Tables {
+		if strings.EqualFold(t.Name, name) {
+			return t
+		}
+	}
+	return nil
+}
+
+func (s *Schema) parseTable(st *ast.CreateTableStmt) (*Table, error) {
+	t := &Table{Name: st.Table.Name.O}
+	if st.ReferTable != nil { // create table t like o
+		refer := s.Table(st.ReferTable.Name.O)
+		if refer == nil {
+			return nil, fmt.Errorf("create table %s like unknown table %s", t.Name, st.ReferTable.Name.O) // nolint:goerr113
+		}
+		// the foreign keys are not copied, like MySQL.
+		t.Engine, t.Charset, t.Collate, t.Comment = refer.Engine, refer.Charset, refer.Collate, refer.Comment
+		t.Columns = append(t.Columns, refer.Columns...)
+		for _, i := range refer.Indexes {
+			if i.Kind != ForeignKey {
+				t.Indexes = append(t.Indexes, i)
+			}
+		}
+		return t, nil
+	}
+
+	for _, col := range st.Cols {
+		t.Columns = append(t.Columns, t.parseColumn(col))
+	}
+	for _, c := range st.Constraints {
+		t.addIndex(parseConstraint(c))
+	}
+	for _, o := range st.Options {
+		switch o.Tp {
+		case ast.TableOptionEngine:
+			t.Engine = o.StrValue
+		case ast.TableOptionCharset:
+			t.Charset = o.StrValue
+		case ast.TableOptionCollate:
+			t.Collate = o.StrValue
+		case ast.TableOptionComment:
+			t.Comment = o.StrValue
+		}
+	}
+
+	// the columns of the primary key are always not null.
+	if pk := t.Index("PRIMARY"); pk != nil {
+		for _, ic := range pk.Columns {
+			if c := t.Column(ic.Name); c != nil {
+				c.NotNull = true
+			}
+		}
+	}
+	return t, nil
+}
+
+func (t *Table) parseColumn(def *ast.ColumnDef) *Column {
+	c := &Column{Name: def.Name.Name.O}
+	ft := *def.Tp
+	if ft.Charset != charset.CharsetBin {
+		c.Charset, c.Collate = ft.Charset, ft.Collate
+		ft.Charset, ft.Collate = "", ""
+	}
+	c.Type = ft.String()
+
+	for _, o := range def.Options {
+		switch o.Tp {
+		case ast.ColumnOptionNotNull:
+			c.NotNull = true
+		case ast.ColumnOptionNull:
+			c.NotNull = false
+		case ast.ColumnOptionDefaultValue:
+			c.Default = formatExpr(o.Expr, def.Tp.Decimal)
+		case ast.ColumnOptionOnUpdate:
+			c.OnUpdate = formatExpr(o.Expr, def.Tp.Decimal)
+		case ast.ColumnOptionAutoIncrement:
+			c.AutoIncrement = true
+		case ast.ColumnOptionGenerated:
+			c.Generated, c.Stored = formatExpr(o.Expr, 0), o.Stored
+		case ast.ColumnOptionComment:
+			c.Comment = o.Expr.GetDatum().GetString()
+		case ast.ColumnOptionPrimaryKey:
+			t.addIndex(&Index{Name: "PRIMARY", Kind: PrimaryKey, Columns: []IndexColumn{{Name: c.Name}}})
+		case ast.ColumnOptionUniqKey:
+			t.addIndex(&Index{Kind: UniqueKey, Columns: []IndexColumn{{Name: c.Name}}})
+		}
+	}
+
+	// default null is the same as no default for the nullable column.
+	if !c.NotNull && strings.EqualFold(c.Default, "NULL") {
+		c.Default = ""
+	}
+	return c
+}
+
+func parseConstraint(c *ast.Constraint) *Index {
+	index := &Index{Name: c.Name, Columns: indexColumns(c.Keys)}
+	switch c.Tp {
+	case ast.ConstraintPrimaryKey:
+		index.Kind, index.Name = PrimaryKey, "PRIMARY"
+	case ast.ConstraintUniq, ast.ConstraintUniqKey, ast.ConstraintUniqIndex:
+		index.Kind = UniqueKey
+	case ast.ConstraintFulltext:
+		index.Kind = FulltextKey
+	case ast.ConstraintForeignKey:
+		index.Kind = ForeignKey
+		if r := c.Refer; r != nil {
+			index.RefTable, index.RefColumns = r.Table.Name.O, indexColumns(r.IndexColNames)
+			if r.OnDelete != nil {
+				index.OnDelete = r.OnDelete.ReferOpt.String()
+			}
+			if r.OnUpdate != nil {
+				index.OnUpdate = r.OnUpdate.ReferOpt.String()
+			}
+		}
+	default:
+		index.Kind = Key
+	}
+	if c.Option != nil {
+		index.Comment = c.Option.Comment
+	}
+	return index
+}
+
+func indexColumns(keys []*ast.IndexColName) []IndexColumn {
+	columns := make([]IndexColumn, 0, len(keys))
+	for _, k := range keys {
+		ic := IndexColumn{Name: k.Column.Name.O}
+		if k.Length > 0 {
+			ic.Length = k.Length
+		}
+		columns = append(columns, ic)
+	}
+	return columns
+}
+
+// addIndex adds the index, naming the unnamed one like MySQL does.
+func (t *Table) addIndex(index *Index) {
+	if index.Name == "" {
+		if index.Kind == ForeignKey {
+			n := 1
+			for _, i := range t.Indexes {
+				if i.Kind == ForeignKey {
+					n++
+				}
+			}
+			index.Name = fmt.Sprintf("%s_ibfk_%d", t.Name, n)
+		} else {
+			index.Name = index.Columns[0].Name
+			for n := 2; t.Index(index.Name) != nil; n++ {
+				index.Name = fmt.Sprintf("%s_%d", index.Columns[0].Name, n)
+			}
+		}
+	}
+	t.Indexes = append(t.Indexes, index)
+}
+
+// Column finds the column by the name case-insensitively, or nil when absent.
+func (t *Table) Column(name string) *Column {
+	for _, c := range t.Columns {
+		if strings.EqualFold(c.Name, name) {
+			return c
+		}
+	}
+	return nil
+}
+
+// Index finds the index by the name case-insensitively, or nil when absent.
+func (t *Table) Index(name string) *Index {
+	for _, i := range t.Indexes {
+		if strings.EqualFold(i.Name, name) {
+			return i
+		}
+	}
+	return nil
+}
+
+// Definition returns the CREATE TABLE statement of the table.
+func (t *Table) Definition() string {
+	var b strings.Builder
+	b.WriteString("CREATE TABLE " + quote(t.Name) + " (\n")
+	for i, c := range t.Columns {
+		b.WriteString("  " + c.Definition())
+		if i < len(t.Columns)-1 || len(t.Indexes) > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n")
+	}
+	for i, index := range t.Indexes {
+		b.WriteString("  " + index.Definition())
+		if i < len(t.Indexes)-1 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(")")
+	if options := t.options(); options != "" {
+		b.WriteString(" " + options)
+	}
+	return b.String()
+}
+
+// options returns the table options, like ENGINE=InnoDB DEFAULT CHARSET=utf8mb4.
+func (t *Table) options() string {
+	var options []string
+	if t.Engine != "" {
+		options = append(options, "ENGINE="+t.Engine)
+	}
+	if t.Charset != "" {
+		options = append(options, "DEFAULT CHARSET="+t.Charset)
+	}
+	if t.Collate != "" {
+		options = append(options, "COLLATE="+t.Collate)
+	}
+	if t.Comment != "" {
+		options = append(options, "COMMENT="+quoteString(t.Comment))
+	}
+	return strings.Join(options, " ")
+}
+
+// Definition returns the definition of the column, like `name` varchar(64) NOT NULL DEFAULT 'x'.
+func (c *Column) Definition() string {
+	s := quote(c.Name) + " " + c.Type
+	if c.Charset != "" {
+		s += " CHARACTER SET " + c.Charset
+	}
+	if c.Collate != "" {
+		s += " COLLATE " + c.Collate
+	}
+	if c.Generated != "" {
+		s += " GENERATED ALWAYS AS (" + c.Generated + ")"
+		if c.Stored {
+			s += " STORED"
+		} else {
+			s += " VIRTUAL"
+		}
+	}
+	if c.NotNull {
+		s += " NOT NULL"
+	}
+	if c.Default != "" {
+		s += " DEFAULT " + c.Default
+	}
+	if c.OnUpdate != "" {
+		s += " ON UPDATE " + c.OnUpdate
+	}
+	if c.AutoIncrement {
+		s += " AUTO_INCREMENT"
+	}
+	if c.Comment != "" {
+		s += " COMMENT " + quoteString(c.Comment)
+	}
+	return s
+}
+
+// Definition returns the definition of the index, like UNIQUE KEY `uk_name` (`name`).
+func (i *Index) Definition() string {
+	var s string
+	switch i.Kind {
+	case PrimaryKey:
+		s = "PRIMARY KEY"
+	case UniqueKey:
+		s = "UNIQUE KEY " + quote(i.Name)
+	case FulltextKey:
+		s = "FULLTEXT KEY " + quote(i.Name)
+	case ForeignKey:
+		s = "CONSTRAINT " + quote(i.Name) + " FOREIGN KEY"
+	default:
+		s = "KEY " + quote(i.Name)
+	}
+	s += " " + formatIndexColumns(i.Columns)
+	if i.Kind == ForeignKey {
+		s += " REFERENCES " + quote(i.RefTable) + " " + formatIndexColumns(i.RefColumns)
+		if i.OnDelete != "" {
+			s += " ON DELETE " + i.OnDelete
+		}
+		if i.OnUpdate != "" {
+			s += " ON UPDATE " + i.OnUpdate
+		}
+	}
+	if i.Comment != "" {
+		s += " COMMENT " + quoteString(i.Comment)
+	}
+	return s
+}
+
+func formatIndexColumns(columns []IndexColumn) string {
+	names := make([]string, len(columns))
+	for j, c := range columns {
+		names[j] = quote(c.Name)
+		if c.Length > 0 {
+			names[j] += fmt.Sprintf("(%d)", c.Length)
+		}
+	}
+	return "(" + strings.Join(names, ",") + ")"
+}
+
+// formatExpr formats the expression of the default value or the generated column in MySQL.
+func formatExpr(expr ast.ExprNode, fsp int) string {
+	switch e := expr.(type) {
+	case *ast.ValueExpr:
+		if k := e.Kind(); k == types.KindString || k == types.KindBytes {
+			return quoteString(e.GetString())
+		}
+	case *ast.FuncCallExpr:
+		switch e.FnName.L {
+		case ast.CurrentTimestamp, ast.Now, ast.LocalTime, ast.LocalTimestamp:
+			// the parser drops the fraction like current_timestamp(3), which is the same as the column's.
+			if fsp > 0 {
+				return fmt.Sprintf("CURRENT_TIMESTAMP(%d)", fsp)
+			}
+			return "CURRENT_TIMESTAMP"
+		}
+	}
+
+	var b strings.Builder
+	expr.Format(&b)
+	return b.String()
+}
+
+func quote(name string) string { return "`" + strings.ReplaceAll(name, "`", "``") + "`" }
+
+func quoteString(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `''`).Replace(s) + "'"
+}
@@ -0,0 +1,125 @@
+package sqllint
+
+import (
+	"strings"
+
+	"github.com/bingoohuang/gg/pkg/sqlparse/tidbparser/ast"
+	tidbparser "github.com/bingoohuang/gg/pkg/sqlparse/tidbparser/parser"
+)
+
+// Schema is the indexed columns of the tables, which is used by the non-sargable rule.
+type Schema struct {
+	indexes map[string]map[string]bool // lowered table -> lowered columns
+}
+
+// NewSchema creates an empty Schema.
+func NewSchema() *Schema { return &Schema{indexes: map[string]map[string]bool{}} }
+
+// ParseSchema parses the schema from the CREATE TABLE and CREATE INDEX statements.
+func ParseSchema(ddl string) (*Schema, error) {
+	stmts, err := tidbparser.New().Parse(ddl, "", "")
+	if err != nil {
+		return nil, err
+	}
+
+	s := NewSchema()
+	for _, stmt := range stmts {
+		switch st := stmt.(type) {
+		case *ast.CreateTableStmt:
+			table := st.Table.Name.O
+			for _, col := range st.Cols {
+				for _, o := range col.Options {
+					if o.Tp == ast.ColumnOptionPrimaryKey || o.Tp == ast.ColumnOptionUniqKey {
+						s.AddIndex(table, col.Name.Name.O)
+					}
+				}
+			}
+			for _, c := range st.Constraints {
+				if c.Tp != ast.ConstraintFulltext {
+					s.AddIndex(table, indexColumns(c.Keys)...)
+				}
+			}
+		case *ast.CreateIndexStmt:
+			s.AddIndex(st.Table.Name.O, indexColumns(st.IndexColNames)...)
+		}
+	}
+	return s, nil
+}
+
+func indexColumns(keys []*ast.IndexColName) []string {
+	columns := make([]string, 0, len(keys))
+	for _, k := range keys {
+		columns = append(columns, k.Column.Name.O)
+	}
+	return columns
+}
+
+// AddIndex adds the columns of an index of the table.
+func (s *Schema) AddIndex(table string, columns ...string) *Schema {
+	table = strings.ToLower(table)
+	m := s.indexes[table]
+	if m == nil {
+		m = map[string]bool{}
+		s.indexes[table] = m
+	}
+	for _, c := range columns {
+		m[strings.ToLower(c)] = true
+	}
+	return s
+}
+
+// Indexed tells whether the column of the table is in an index.
+func (s *Schema) Indexed(table, column string) bool {
+	return s.indexes[strings.ToLower(table)][strings.ToLower(column)]
+}
+
+// checkDDL checks the DDL which rewrites the table in MySQL, which locks or slows down the table for a long time.
+func (c *checker) checkDDL(stmt ast.StmtNode) {
+	alter, ok := stmt.(*ast.AlterTableStmt)
+	if !ok {
+		return
+	}
+
+	table := alter.Table.Name.O
+	for _, spec := range alter.Specs {
+		if reason := rewriteReason(spec); reason != "" {
+			c.report(RuleTableRewrite, 0, "%s rewrites the table %s", reason, table)
+		}
+	}
+}
+
+// rewriteReason returns the reason of the alter table spec which rewrites the table, or empty.
+func rewriteReason(spec *ast.AlterTableSpec) string {
+	switch spec.Tp {
+	case ast.AlterTableModifyColumn:
+		return "modify column"
+	case ast.AlterTableChangeColumn:
+		return "change column"
+	case ast.AlterTableDropColumn:
+		return "drop column"
+	case ast.AlterTableDropPrimaryKey:
+		return "drop primary key"
+	case ast.AlterTableAddConstraint:
+		if spec.Constraint != nil && spec.Constraint.Tp == ast.ConstraintPrimaryKey {
+			return "add primary key"
+		}
+	case ast.AlterTableAddColumns:
+		if spec.Position != nil && spec.Position.Tp != ast.ColumnPositionNone {
+			return "add column with first or after"
+		}
+	case ast.AlterTableOption:
+		for _, o := range spec.Options {
+			switch o.Tp {
+			case ast.TableOptionEngine:
+				return "engine"
+			case ast.TableOptionRowFormat:
+				return "row_format"
+			case ast.TableOptionKeyBlockSize:
+				return "key_block_size"
+			case ast.TableOptionCompression:
+				return "compression"
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,225 @@
+// Package sqllint reports the risky SQL statements by walking the ASTs of sqlparser and tidbparser.
+package sqllint
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bingoohuang/gg/pkg/sqlparse/sqlparser"
+)
+
+// Severity is the severity of a finding.
+type Severity int
+
+const (
+	// Warning is for the statements which are risky but can be intended.
+	Warning Severity = iota + 1
+	// Error is for the statements which are almost always wrong.
+	Error
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Warning:
+		return "warning"
+	case Error:
+		return "error"
+	default:
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+}
+
+// The rules reported by the linter.
+const (
+	RuleSyntax             = "syntax"
+	RuleUpdateWithoutWhere = "update-without-where"
+	RuleDeleteWithoutWhere = "delete-without-where"
+	RuleSelectStar         = "select-star"
+	RuleCrossJoin          = "cross-join"
+	RuleLeadingWildcard    = "leading-wildcard-like"
+	RuleMissingLimit       = "missing-limit"
+	RuleNonSargable        = "non-sargable"
+	RuleTableRewrite       = "table-rewrite"
+)
+
+var defaultSeverities = map[string]Severity{
+	RuleSyntax:             Error,
+	RuleUpdateWithoutWhere: Error,
+	RuleDeleteWithoutWhere: Error,
+}
+
+// Position is the position in the linted SQL.
+type Position struct {
+	Offset int // 0-based byte offset
+	Line   int // 1-based
+	Column int // 1-based, in bytes
+}
+
+func (p Position) String() string { return fmt.Sprintf("%d:%d", p.Line, p.Column) }
+
+// Finding is a risky statement reported by the linter.
+type Finding struct {
+	Rule     string
+	Severity Severity
+	Message  string
+	Pos      Position
+	// SQL is the statement of the finding.
+	SQL string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s: %s [%s]", f.Pos, f.Severity, f.Message, f.Rule)
+}
+
+// Findings is the findings of the linter.
+type Findings []Finding
+
+// Max returns the max severity of the findings, 0 when empty.
+func (f Findings) Max() Severity {
+	var max Severity
+	for _, v := range f {
+		if v.Severity > max {
+			max = v.Severity
+		}
+	}
+	return max
+}
+
+// Linter lints the SQL statements.
+type Linter struct {
+	Schema     *Schema
+	Disabled   map[string]bool
+	Severities map[string]Severity
+}
+
+// Option is the option of the Linter.
+type Option func(*Linter)
+
+// WithSchema supplies the schema for the rules requiring the indexes, like non-sargable.
+func WithSchema(s *Schema) Option { return func(l *Linter) { l.Schema = s } }
+
+// WithDisabled disables the rules.
+func WithDisabled(rules ...string) Option {
+	return func(l *Linter) {
+		for _, r := range rules {
+			l.Disabled[r] = true
+		}
+	}
+}
+
+// WithSeverity overrides the severity of the rule.
+func WithSeverity(rule string, s Severity) Option {
+	return func(l *Linter) { l.Severities[rule] = s }
+}
+
+// New creates a Linter.
+func New(options ...Option) *Linter {
+	l := &Linter{Disabled: map[string]bool{}, Severities: map[string]Severity{}}
+	for _, f := range options {
+		f(l)
+	}
+	return l
+}
+
+// Lint lints the SQL statements separated by ; with a new Linter.
+func Lint(sql string, options ...Option) Findings {
+	return New(options...).Lint(sql)
+}
+
+// Lint lints the SQL statements separated by ;.
+func (l *Linter) Lint(sql string) Findings {
+	var findings Findings
+	for _, s := range splitStatements(sql) {
+		c := &checker{linter: l, sql: sql, stmt: s}
+		c.check()
+		findings = append(findings, c.findings...)
+	}
+	return findings
+}
+
+func (l *Linter) severity(rule string) Severity {
+	if s, ok := l.Severities[rule]; ok {
+		return s
+	}
+	if s, ok := defaultSeverities[rule]; ok {
+		return s
+	}
+	return Warning
+}
+
+// token is a token of the SQL with its offset.
+type token struct {
+	typ int
+	val string
+	pos int
+}
+
+func tokenize(sql string) []token {
+	var tokens []token
+	tkn := sqlparser.NewStringTokenizer(sql)
+	tkn.AllowComments = true
+	end := 0
+	for {
+		typ, val := tkn.Scan()
+		if typ == 0 || typ == sqlparser.LEX_ERROR {
+			return tokens
+		}
+		// the tokenizer reads one char ahead, and there are only blanks between the tokens.
+		pos := end
+		for pos < len(sql) && isBlank(sql[pos]) {
+			pos++
+		}
+		tokens = append(tokens, token{typ: typ, val: string(val), pos: pos})
+		end = tkn.Position - 1
+	}
+}
+
+func isBlank(c byte) bool { return c == ' ' || c == '\t' || c == '\n' || c == '\r' }
+
+// statement is a statement in the linted SQL.
+type statement struct {
+	text   string
+	pos    int
+	tokens []token // the offsets are relative to the statement
+}
+
+// splitStatements splits the SQL to the statements by the ; tokens.
+func splitStatements(sql string) []statement {
+	var stmts []statement
+	start := 0
+	add := func(end int) {
+		text := sql[start:end]
+		trimmed := strings.TrimLeft(text, " \t\r\n")
+		pos := start + len(text) - len(trimmed)
+		trimmed = strings.TrimRight(trimmed, " \t\r\n")
+		tokens := tokenize(trimmed)
+		for _, t := range tokens {
+			if t.typ != sqlparser.COMMENT { // skip the statements of the comments only
+				stmts = append(stmts, statement{text: trimmed, pos: pos, tokens: tokens})
+				return
+			}
+		}
+	}
+	for _, t := range tokenize(sql) {
+		if t.typ == ';' {
+			add(t.pos)
+			start = t.pos + 1
+		}
+	}
+	add(len(sql))
+	return stmts
+}
+
+// position returns the position of the offset in the sql.
+func position(sql string, offset int) Position {
+	p := Position{Offset: offset, Line: 1, Column: 1}
+	for i := 0; i < offset && i < len(sql); i++ {
+		if sql[i] == '\n' {
+			p.Line++
+			p.Column = 1
+		} else {
+			p.Column++
+		}
+	}
+	return p
+}
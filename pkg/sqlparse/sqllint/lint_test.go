@@ -0,0 +1,74 @@
+package sqllint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLint(t *testing.T) {
+	cases := []struct {
+		sql    string
+		expect []string
+	}{
+		{"update t set a = 1", []string{"1:1: error: update without where changes all the rows [update-without-where]"}},
+		{"delete from t where id = ?", nil},
+		{"delete from t", []string{"1:1: error: delete without where removes all the rows [delete-without-where]"}},
+		{"select id, count(*) from t where a > ? group by id", nil},
+		{"select count(*) from t", nil},
+		{"select a, b from t", []string{"1:1: warning: select without where and limit reads all the rows [missing-limit]"}},
+		{"select * from t where id = ?", []string{"1:8: warning: select * depends on the columns order of the table [select-star]"}},
+		{"select a, (select count(*) from s where s.tid = t.id) from t where t.a * 2 > 1 and t.b in (select x.* from x limit 1)",
+			[]string{"1:101: warning: select * depends on the columns order of the table [select-star]"}},
+		{"select a from t, s where t.id = s.tid and t.a = 1", nil},
+		{"select a from t, s where t.a = 1", []string{"1:1: warning: s is cross joined without a join condition [cross-join]"}},
+		{"select a from t join s where t.a = 1", []string{"1:1: warning: s is cross joined without a join condition [cross-join]"}},
+		{"select a from t where name like 'a%' and b not like concat('%', ?) limit 1",
+			[]string{"1:48: warning: like concat('%', ?) with the leading wildcard can not use the index [leading-wildcard-like]"}},
+		{"alter table t add column c int, modify column b bigint, engine = innodb", []string{
+			"1:1: warning: modify column rewrites the table t [table-rewrite]",
+			"1:1: warning: engine rewrites the table t [table-rewrite]",
+		}},
+		{"alter table t add column c int after b", []string{
+			"1:1: warning: add column with first or after rewrites the table t [table-rewrite]",
+		}},
+		{"alter table t add index idx_a(a)", nil},
+		{"select from", []string{"1:1: error: syntax error at position 12 near 'from' [syntax]"}},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.expect, findingStrings(Lint(c.sql)), c.sql)
+	}
+}
+
+func TestLintMultiStatements(t *testing.T) {
+	findings := Lint("-- name: update\nupdate t set a = 1;\n\n/* all */ delete from t;\n-- end\n",
+		WithDisabled(RuleUpdateWithoutWhere), WithSeverity(RuleDeleteWithoutWhere, Warning))
+	assert.Equal(t, []string{"4:11: warning: delete without where removes all the rows [delete-without-where]"}, findingStrings(findings))
+	assert.Equal(t, "/* all */ delete from t", findings[0].SQL)
+	assert.Equal(t, Warning, findings.Max())
+}
+
+func TestLintNonSargable(t *testing.T) {
+	schema, err := ParseSchema("create table t(id int primary key, created datetime, name varchar(10), key idx_created(created));" +
+		"create index idx_name on t(name)")
+	assert.Nil(t, err)
+	assert.True(t, schema.Indexed("T", "Name"))
+
+	findings := Lint("select id from t x where date(x.created) = ? and upper(note) = ? and ? = id + 1 limit 10", WithSchema(schema))
+	assert.Equal(t, []string{
+		"1:33: warning: date(x.created) on the indexed column x.created can not use the index [non-sargable]",
+		"1:74: warning: id + 1 on the indexed column id can not use the index [non-sargable]",
+	}, findingStrings(findings))
+}
+
+func findingStrings(findings Findings) []string {
+	if len(findings) == 0 {
+		return nil
+	}
+	s := make([]string, len(findings))
+	for i, f := range findings {
+		s[i] = f.String()
+	}
+	return s
+}
@@ -0,0 +1,343 @@
+package sqllint
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bingoohuang/gg/pkg/sqlparse/sqlparser"
+	tidbparser "github.com/bingoohuang/gg/pkg/sqlparse/tidbparser/parser"
+)
+
+// checker checks a statement.
+type checker struct {
+	linter   *Linter
+	sql      string
+	stmt     statement
+	findings Findings
+}
+
+// report reports the finding at the offset of the statement.
+func (c *checker) report(rule string, offset int, format string, args ...interface{}) {
+	if c.linter.Disabled[rule] {
+		return
+	}
+	c.findings = append(c.findings, Finding{
+		Rule:     rule,
+		Severity: c.linter.severity(rule),
+		Message:  fmt.Sprintf(format, args...),
+		Pos:      position(c.sql, c.stmt.pos+offset),
+		SQL:      c.stmt.text,
+	})
+}
+
+// nth returns the offset of the n-th (0-based) token matched, or the statement start when absent.
+func (c *checker) nth(n int, match func(i int, t token) bool) int {
+	for i, t := range c.stmt.tokens {
+		if match(i, t) {
+			if n == 0 {
+				return t.pos
+			}
+			n--
+		}
+	}
+	return 0
+}
+
+// keyword returns the offset of the n-th token of the type.
+func (c *checker) keyword(n, typ int) int {
+	return c.nth(n, func(_ int, t token) bool { return t.typ == typ })
+}
+
+func (c *checker) check() {
+	stmt, err := sqlparser.Parse(c.stmt.text)
+	if err != nil {
+		// the DDL not supported by sqlparser
+		if ddl, tidbErr := tidbparser.New().ParseOneStmt(c.stmt.text, "", ""); tidbErr == nil {
+			c.checkDDL(ddl)
+			return
+		}
+		c.report(RuleSyntax, 0, "%v", err)
+		return
+	}
+
+	switch s := stmt.(type) {
+	case *sqlparser.Update:
+		if s.Where == nil {
+			c.report(RuleUpdateWithoutWhere, c.keyword(0, sqlparser.UPDATE), "update without where changes all the rows")
+		}
+	case *sqlparser.Delete:
+		if s.Where == nil {
+			c.report(RuleDeleteWithoutWhere, c.keyword(0, sqlparser.DELETE), "delete without where removes all the rows")
+		}
+	case *sqlparser.Select:
+		if s.Limit == nil && s.Where == nil && readsTable(s) && !isAggregateOnly(s) {
+			c.report(RuleMissingLimit, c.keyword(0, sqlparser.SELECT), "select without where and limit reads all the rows")
+		}
+	case *sqlparser.Union, *sqlparser.ParenSelect, *sqlparser.Insert:
+	default:
+		if ddl, err := tidbparser.New().ParseOneStmt(c.stmt.text, "", ""); err == nil {
+			c.checkDDL(ddl)
+		}
+		return
+	}
+
+	c.checkSelects(stmt)
+	c.checkLikes(stmt)
+	if c.linter.Schema != nil {
+		c.checkSargable(stmt)
+	}
+}
+
+// readsTable tells whether the select reads from a table other than dual.
+func readsTable(s *sqlparser.Select) bool {
+	for _, te := range s.From {
+		if t, ok := te.(*sqlparser.AliasedTableExpr); ok {
+			if n, ok := t.Expr.(sqlparser.TableName); ok && n.Name.String() == "dual" {
+				continue
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// isAggregateOnly tells whether the select returns a single row of the aggregates, like select count(*) from t.
+func isAggregateOnly(s *sqlparser.Select) bool {
+	if len(s.GroupBy) > 0 {
+		return false
+	}
+	for _, e := range s.SelectExprs {
+		ae, ok := e.(*sqlparser.AliasedExpr)
+		if !ok {
+			return false
+		}
+		if f, ok := ae.Expr.(*sqlparser.FuncExpr); !ok || !f.IsAggregate() {
+			if _, ok := ae.Expr.(*sqlparser.GroupConcatExpr); !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// checkSelects checks select * and the cross joins in all the selects, including the sub queries.
+func (c *checker) checkSelects(stmt sqlparser.Statement) {
+	// the walk is in the order of the text, so the n-th select node is at the n-th SELECT token.
+	selects, stars := 0, 0
+	_ = sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+		switch n := node.(type) {
+		case *sqlparser.Select:
+			if joined := crossJoined(n); joined != "" {
+				c.report(RuleCrossJoin, c.keyword(selects, sqlparser.SELECT), "%s is cross joined without a join condition", joined)
+			}
+			for _, e := range n.SelectExprs {
+				if _, ok := e.(*sqlparser.StarExpr); ok {
+					c.report(RuleSelectStar, c.nth(stars, c.isSelectStar), "select * depends on the columns order of the table")
+					stars++
+				}
+			}
+			selects++
+		}
+		return true, nil
+	}, stmt)
+}
+
+// isSelectStar tells whether the i-th token is the * of the select list, but not the one of count(*) or a multiplication.
+func (c *checker) isSelectStar(i int, t token) bool {
+	if t.typ != '*' || i == 0 {
+		return false
+	}
+	switch p := c.stmt.tokens[i-1]; p.typ {
+	case sqlparser.SELECT, sqlparser.DISTINCT, sqlparser.COMMENT, ',', '.':
+		return true
+	default:
+		return false
+	}
+}
+
+// crossJoined returns the table which is cross joined in the select, or empty.
+func crossJoined(s *sqlparser.Select) string {
+	for _, te := range s.From {
+		if j, ok := te.(*sqlparser.JoinTableExpr); ok && j.On == nil &&
+			(j.Join == sqlparser.JoinStr || j.Join == sqlparser.StraightJoinStr) {
+			return sqlparser.String(j.RightExpr)
+		}
+	}
+	if len(s.From) < 2 {
+		return ""
+	}
+
+	// from a, b where a.id = b.aid
+	linked := map[string]bool{tableAlias(s.From[0]): true}
+	if s.Where != nil {
+		for changed := true; changed; {
+			changed = false
+			for _, pair := range joinConditions(s.Where.Expr) {
+				if linked[pair[0]] != linked[pair[1]] {
+					linked[pair[0]], linked[pair[1]] = true, true
+					changed = true
+				}
+			}
+		}
+	}
+	for _, te := range s.From[1:] {
+		if alias := tableAlias(te); !linked[alias] {
+			return sqlparser.String(te)
+		}
+	}
+	return ""
+}
+
+// tableAlias returns the lowered alias or name of the table expression.
+func tableAlias(te sqlparser.TableExpr) string {
+	if t, ok := te.(*sqlparser.AliasedTableExpr); ok {
+		if !t.As.IsEmpty() {
+			return strings.ToLower(t.As.String())
+		}
+		if n, ok := t.Expr.(sqlparser.TableName); ok {
+			return strings.ToLower(n.Name.String())
+		}
+	}
+	return sqlparser.String(te)
+}
+
+// joinConditions returns the pairs of the table qualifiers compared in the condition, like a.id = b.aid.
+func joinConditions(expr sqlparser.Expr) [][2]string {
+	var pairs [][2]string
+	_ = sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+		if _, ok := node.(*sqlparser.Subquery); ok {
+			return false, nil
+		}
+		if cmp, ok := node.(*sqlparser.ComparisonExpr); ok {
+			l, lok := cmp.Left.(*sqlparser.ColName)
+			r, rok := cmp.Right.(*sqlparser.ColName)
+			if lok && rok && !l.Qualifier.IsEmpty() && !r.Qualifier.IsEmpty() {
+				pairs = append(pairs, [2]string{
+					strings.ToLower(l.Qualifier.Name.String()), strings.ToLower(r.Qualifier.Name.String()),
+				})
+			}
+		}
+		return true, nil
+	}, expr)
+	return pairs
+}
+
+// checkLikes checks the like patterns starting with a wildcard, which can not use the index.
+func (c *checker) checkLikes(stmt sqlparser.Statement) {
+	likes := 0
+	_ = sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+		cmp, ok := node.(*sqlparser.ComparisonExpr)
+		if !ok || cmp.Operator != sqlparser.LikeStr && cmp.Operator != sqlparser.NotLikeStr {
+			return true, nil
+		}
+		if pattern := leadingPattern(cmp.Right); strings.HasPrefix(pattern, "%") || strings.HasPrefix(pattern, "_") {
+			c.report(RuleLeadingWildcard, c.keyword(likes, sqlparser.LIKE),
+				"like %s with the leading wildcard can not use the index", sqlparser.String(cmp.Right))
+		}
+		likes++
+		return true, nil
+	}, stmt)
+}
+
+// leadingPattern returns the leading string literal of the like pattern, like '%a' or concat('%', ?).
+func leadingPattern(expr sqlparser.Expr) string {
+	switch e := expr.(type) {
+	case *sqlparser.SQLVal:
+		if e.Type == sqlparser.StrVal {
+			return string(e.Val)
+		}
+	case *sqlparser.FuncExpr:
+		if e.Name.Lowered() == "concat" && len(e.Exprs) > 0 {
+			if ae, ok := e.Exprs[0].(*sqlparser.AliasedExpr); ok {
+				return leadingPattern(ae.Expr)
+			}
+		}
+	}
+	return ""
+}
+
+// checkSargable checks the functions or the calculations on the indexed columns in the conditions.
+func (c *checker) checkSargable(stmt sqlparser.Statement) {
+	tables := map[string]string{} // alias -> table
+	_ = sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+		if t, ok := node.(*sqlparser.AliasedTableExpr); ok {
+			if n, ok := t.Expr.(sqlparser.TableName); ok {
+				name := strings.ToLower(n.Name.String())
+				tables[name] = name
+				if !t.As.IsEmpty() {
+					tables[strings.ToLower(t.As.String())] = name
+				}
+			}
+		}
+		return true, nil
+	}, stmt)
+
+	indexed := func(col *sqlparser.ColName) bool {
+		if !col.Qualifier.IsEmpty() {
+			return c.linter.Schema.Indexed(tables[strings.ToLower(col.Qualifier.Name.String())], col.Name.String())
+		}
+		for _, table := range tables {
+			if c.linter.Schema.Indexed(table, col.Name.String()) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// the walk is in the order of the text, so the n-th column of the name is at the n-th identifier of the name.
+	seen := map[string]int{}
+	_ = sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+		switch n := node.(type) {
+		case *sqlparser.ColName:
+			seen[n.Name.Lowered()]++
+		case *sqlparser.ComparisonExpr:
+			c.checkOperand(n.Left, indexed, seen)
+			c.checkOperand(n.Right, indexed, addColumns(seen, n.Left))
+		case *sqlparser.RangeCond:
+			c.checkOperand(n.Left, indexed, seen)
+		}
+		return true, nil
+	}, stmt)
+}
+
+// checkOperand checks whether the operand of the condition wraps an indexed column in the function or the calculation.
+func (c *checker) checkOperand(expr sqlparser.Expr, indexed func(*sqlparser.ColName) bool, seen map[string]int) {
+	switch expr.(type) {
+	case *sqlparser.FuncExpr, *sqlparser.ConvertExpr, *sqlparser.BinaryExpr, *sqlparser.UnaryExpr:
+	default:
+		return
+	}
+
+	seen = addColumns(seen, nil)
+	_ = sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+		if n, ok := node.(*sqlparser.ColName); ok {
+			name := n.Name.Lowered()
+			if indexed(n) {
+				offset := c.nth(seen[name], func(_ int, t token) bool {
+					return t.typ == sqlparser.ID && strings.EqualFold(t.val, name)
+				})
+				c.report(RuleNonSargable, offset,
+					"%s on the indexed column %s can not use the index", sqlparser.String(expr), sqlparser.String(n))
+			}
+			seen[name]++
+		}
+		return true, nil
+	}, expr)
+}
+
+// addColumns returns a copy of the counts of the column names, adding the columns in the expression.
+func addColumns(counts map[string]int, expr sqlparser.Expr) map[string]int {
+	m := make(map[string]int, len(counts))
+	for k, v := range counts {
+		m[k] = v
+	}
+	if expr != nil {
+		_ = sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+			if n, ok := node.(*sqlparser.ColName); ok {
+				m[n.Name.Lowered()]++
+			}
+			return true, nil
+		}, expr)
+	}
+	return m
+}
@@ -0,0 +1,88 @@
+package sqlparser
+
+import (
+	"errors"
+)
+
+// ErrNotSingleRowInsert tells that the query is not an insert statement with a single values row.
+var ErrNotSingleRowInsert = errors.New("not a single row insert ... values statement")
+
+// DefaultMaxPlaceholders returns the default max number of placeholders in a single statement of the db type.
+func (t DBType) DefaultMaxPlaceholders() int {
+	switch t {
+	case Mssql:
+		return 2000 // sqlserver allows at most 2100 parameters
+	case Sqlite3:
+		return 32766 // SQLITE_MAX_VARIABLE_NUMBER since 3.32.0
+	case Shentong:
+		return 32767
+	default: // mysql, postgresql, kingbase, oracle, dm use a 16-bit parameter count
+		return 65535
+	}
+}
+
+// BatchRows returns how many rows of columns columns fit in a single multi-row insert statement,
+// with maxPlaceholders placeholders at most, 0 for the default of the db type.
+func (t DBType) BatchRows(columns, maxPlaceholders int) int {
+	if maxPlaceholders <= 0 {
+		maxPlaceholders = t.DefaultMaxPlaceholders()
+	}
+
+	rows := 1
+	if columns > 0 && maxPlaceholders > columns {
+		rows = maxPlaceholders / columns
+	}
+
+	if t == Mssql && rows > 1000 { // the table value constructor allows at most 1000 rows
+		rows = 1000
+	}
+
+	return rows
+}
+
+// ConvertBatchInsert expands a single row insert statement to insert rows rows in one statement,
+// and converts it to the target db type.
+// It produces INSERT INTO t(a, b) VALUES (?, ?), (?, ?) ... for most databases,
+// and INSERT ALL INTO t(a, b) VALUES (:1, :2) INTO t(a, b) VALUES (:3, :4) SELECT 1 FROM DUAL for oracle.
+func (t DBType) ConvertBatchInsert(query string, rows int) (string, error) {
+	stmt, err := Parse(query)
+	if err != nil {
+		return "", err
+	}
+
+	insertStmt, ok := stmt.(*Insert)
+	if !ok {
+		return "", ErrNotSingleRowInsert
+	}
+
+	values, ok := insertStmt.Rows.(Values)
+	if !ok || len(values) != 1 {
+		return "", ErrNotSingleRowInsert
+	}
+
+	if err := t.checkMySQLOnDuplicateKey(insertStmt); err != nil {
+		return "", err
+	}
+
+	fixInsertPlaceholders(insertStmt)
+	row := values[0]
+
+	buf := t.newTrackedBuffer()
+	if t == Oracle {
+		buf.Myprintf("insert all")
+		for i := 0; i < rows; i++ {
+			buf.Myprintf(" into %v%v values %v", insertStmt.Table, insertStmt.Columns, row)
+		}
+		buf.Myprintf(" select 1 from dual")
+		return buf.String(), nil
+	}
+
+	batchRows := make(Values, rows)
+	for i := range batchRows {
+		batchRows[i] = row
+	}
+
+	insertStmt.Rows = batchRows
+	buf.Myprintf("%v", insertStmt)
+	return buf.String(), nil
+}
@@ -0,0 +1,37 @@
+package sqlparser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertBatchInsert(t *testing.T) {
+	const q = `insert into person(id, age) values(?, ?)`
+
+	cases := []struct {
+		DBType
+		Q string
+	}{
+		{Mysql, "insert into `person`(`id`, `age`) values (?, ?), (?, ?), (?, ?)"},
+		{Postgresql, `insert into "person"("id", "age") values ($1, $2), ($3, $4), ($5, $6)`},
+		{Kingbase, `insert into "person"("id", "age") values ($1, $2), ($3, $4), ($5, $6)`},
+		{Mssql, `insert into "person"("id", "age") values (@p1, @p2), (@p3, @p4), (@p5, @p6)`},
+		{Dm, `insert into "person"("id", "age") values (?, ?), (?, ?), (?, ?)`},
+		{Oracle, `insert all into person(id, age) values (:1, :2) into person(id, age) values (:3, :4) into person(id, age) values (:5, :6) select 1 from dual`},
+	}
+
+	for _, c := range cases {
+		s, err := c.DBType.ConvertBatchInsert(q, 3)
+		assert.Nil(t, err, c.DBType)
+		assert.Equal(t, c.Q, s, c.DBType)
+	}
+
+	_, err := Mysql.ConvertBatchInsert(`update person set age = ?`, 3)
+	assert.ErrorIs(t, err, ErrNotSingleRowInsert)
+
+	assert.Equal(t, 32767, Mysql.BatchRows(2, 0))
+	assert.Equal(t, 1000, Mssql.BatchRows(2, 0))
+	assert.Equal(t, 3, Postgresql.BatchRows(2, 7))
+	assert.Equal(t, 1, Postgresql.BatchRows(5, 3))
+}
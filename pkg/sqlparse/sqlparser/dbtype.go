@@ -259,7 +259,9 @@ func (p *PrefixPlaceholderFormatter) FormatPlaceholder() string {
 
 type ConvertConfig struct {
 	Paging             *Paging
+	Keyset             *Keyset
 	AutoIncrementField string
+	ConflictKeys       []string
 }
 
 type ConvertOption func(*ConvertConfig)
@@ -350,8 +352,8 @@ func (r *ConvertResult) PickArgs(args []interface{}) (q string, bindArgs []inter
 		}
 	}
 
+	query := r.ConvertQuery() // ExtraArgs are collected while converting query
 	resultArgs := append(bindArgs, r.ExtraArgs...)
-	query := r.ConvertQuery()
 	if r.SwapArgs != nil {
 		r.SwapArgs(resultArgs)
 	}
@@ -608,6 +610,8 @@ func RegisterQuoteNames(dbType DBType, names []string) {
 // Convert converts query to target db type.
 // 1. adjust the SQL variable symbols by different type, such as ?,? $1,$2.
 // 1. quote table name, field names.
+// 1. translate the MySQL functions and the types of CAST/CONVERT, see RegisterFunc and RegisterType.
+// 1. rewrite the MySQL INSERT ... ON DUPLICATE KEY UPDATE to the upsert of the target db type, see WithConflictKeys.
 func (t DBType) Convert(query string, options ...ConvertOption) (*ConvertResult, error) {
 	stmt, err := Parse(query)
 	if err != nil {
@@ -615,10 +619,6 @@ func (t DBType) Convert(query string, options ...ConvertOption) (*ConvertResult,
 	}
 
 	insertStmt, _ := stmt.(*Insert)
-	if err := t.checkMySQLOnDuplicateKey(insertStmt); err != nil {
-		return nil, fmt.Errorf("on duplicate key is not supported directly in SQL, error %w", ErrSyntax)
-	}
-
 	fixInsertPlaceholders(insertStmt)
 	cr := &ConvertResult{}
 
@@ -665,34 +665,19 @@ func (t DBType) Convert(query string, options ...ConvertOption) (*ConvertResult,
 		return nil, fmt.Errorf("mixed bind modes are not supported, error %w", ErrSyntax)
 	}
 
-	buf := &TrackedBuffer{Buffer: new(bytes.Buffer)}
-
-	switch t {
-	case Postgresql, Kingbase:
-		buf.PlaceholderFormatter = &PrefixPlaceholderFormatter{Prefix: "$"}
-	case Mssql:
-		buf.PlaceholderFormatter = &PrefixPlaceholderFormatter{Prefix: "@p"}
-	case Oracle, Shentong:
-		buf.PlaceholderFormatter = &PrefixPlaceholderFormatter{Prefix: ":"}
-	default:
-		buf.PlaceholderFormatter = &QuestionPlaceholderFormatter{}
-	}
-
-	switch t {
-	case Mysql, Sqlite3, Gbase, Clickhouse:
-		// https://www.sqlite.org/lang_keywords.html
-		buf.IdQuoter = &MySQLIdQuoter{}
-	case Oracle:
-		buf.IdQuoter = &KeywordQuoter{DBType: Oracle, Upper: true}
-	default:
-		buf.IdQuoter = &DoubleQuoteIdQuoter{}
-	}
+	buf := t.newTrackedBuffer()
 
 	config := &ConvertConfig{}
 	for _, f := range options {
 		f(config)
 	}
 
+	if insertStmt != nil && len(insertStmt.OnDup) > 0 && t != Mysql {
+		if stmt, err = t.newUpsert(insertStmt, config.ConflictKeys); err != nil {
+			return nil, err
+		}
+	}
+
 	selectStmt, _ := stmt.(*Select)
 	var limit *Limit
 	if selectStmt != nil {
@@ -700,14 +685,26 @@ func (t DBType) Convert(query string, options ...ConvertOption) (*ConvertResult,
 		selectStmt.SetLimit(nil)
 	}
 
-	var compatibleLimit *CompatibleLimit
+	var keysetArgs []interface{}
 	p := config.Paging
+	isKeyset := selectStmt != nil && config.Keyset != nil
+	if isKeyset {
+		if keysetArgs, err = t.applyKeyset(selectStmt, config.Keyset); err != nil {
+			return nil, err
+		}
+		// fetch one more row to tell whether there is a next page
+		p = &Paging{PageSeq: 1, PageSize: config.Keyset.PageSize + 1}
+	}
+
+	var compatibleLimit *CompatibleLimit
 	isPaging := selectStmt != nil && p != nil
 	if !isPaging && limit != nil {
 		compatibleLimit = &CompatibleLimit{Limit: limit, DBType: t}
 		selectStmt.SetLimitSQLNode(compatibleLimit)
 	}
 
+	// ConvertQuery can be called more than once, like by PickArgs on every execution,
+	// so ExtraArgs are collected again instead of appended.
 	cr.ConvertQuery = func() string {
 		buf.Myprintf("%v", stmt)
 		q := buf.String()
@@ -717,8 +714,11 @@ func (t DBType) Convert(query string, options ...ConvertOption) (*ConvertResult,
 			cr.SwapArgs = compatibleLimit.SwapArgs
 		}
 
+		cr.ExtraArgs = append([]interface{}(nil), keysetArgs...)
+
 		if isPaging {
-			pagingClause, bindArgs := t.createPagingClause(buf.PlaceholderFormatter, p, cr.BindMode > 0)
+			placeholder := cr.BindMode > 0 || len(keysetArgs) > 0
+			pagingClause, bindArgs := t.createPagingClause(buf.PlaceholderFormatter, p, placeholder)
 			cr.ExtraArgs = append(cr.ExtraArgs, bindArgs...)
 			if p.RowsCount == CreateCountingQuery {
 				cr.CountingQuery = t.createCountingQuery(stmt, buf, q)
@@ -736,6 +736,38 @@ func (t DBType) Convert(query string, options ...ConvertOption) (*ConvertResult,
 	return cr, nil
 }
 
+// newTrackedBuffer creates a TrackedBuffer with the placeholder formatter and identifier quoter of the db type.
+func (t DBType) newTrackedBuffer() *TrackedBuffer {
+	buf := &TrackedBuffer{Buffer: new(bytes.Buffer)}
+
+	switch t {
+	case Postgresql, Kingbase:
+		buf.PlaceholderFormatter = &PrefixPlaceholderFormatter{Prefix: "$"}
+	case Mssql:
+		buf.PlaceholderFormatter = &PrefixPlaceholderFormatter{Prefix: "@p"}
+	case Oracle, Shentong:
+		buf.PlaceholderFormatter = &PrefixPlaceholderFormatter{Prefix: ":"}
+	default:
+		buf.PlaceholderFormatter = &QuestionPlaceholderFormatter{}
+	}
+
+	switch t {
+	case Mysql, Sqlite3, Gbase, Clickhouse:
+		// https://www.sqlite.org/lang_keywords.html
+		buf.IdQuoter = &MySQLIdQuoter{}
+	case Oracle:
+		buf.IdQuoter = &KeywordQuoter{DBType: Oracle, Upper: true}
+	default:
+		buf.IdQuoter = &DoubleQuoteIdQuoter{}
+	}
+
+	if t.translatesMySQL() {
+		buf.nodeFormatter = t.formatNode
+	}
+
+	return buf
+}
+
 type InPlaceholder struct {
 	Expr *ComparisonExpr
 	Num  int
@@ -0,0 +1,286 @@
+package sqlparser
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bingoohuang/gg/pkg/ss"
+)
+
+// ErrKeysetOrderBy tells that keyset pagination requires an ORDER BY clause.
+var ErrKeysetOrderBy = errors.New("keyset paging requires order by columns")
+
+// ErrBadCursor tells that the keyset cursor is malformed.
+var ErrBadCursor = errors.New("bad keyset cursor")
+
+// Keyset is a keyset (cursor) pagination object.
+// Instead of skipping rows by OFFSET, it seeks the rows after the last row of the previous page
+// by the ORDER BY columns, so every page costs the same no matter how deep it is.
+type Keyset struct {
+	PageSize   int      // How many items per page, 20 items by default
+	Cursor     string   // Opaque cursor of the last row of the previous page, empty for the first page
+	Columns    []string // Result column names of the ORDER BY items, set by Convert
+	NextCursor string   // Opaque cursor of the last row of the current page, set after query
+	HasNext    bool     // Is there a next page
+}
+
+// NewKeyset creates a Keyset object.
+func NewKeyset(cursor string) *Keyset { return &Keyset{PageSize: 20, Cursor: cursor} }
+
+// WithKeyset sets the keyset pagination, which takes precedence over WithPaging and WithLimit.
+func WithKeyset(v *Keyset) ConvertOption { return func(c *ConvertConfig) { c.Keyset = v } }
+
+// cursorTimeKey is the type tag of the time.Time values in the cursor, like {"time": "2024-01-01T00:00:00Z"}.
+const cursorTimeKey = "time"
+
+// EncodeCursor encodes the ORDER BY column values of a row to an opaque cursor token.
+// The time.Time values are tagged, so that they are decoded back to time.Time instead of strings.
+func EncodeCursor(values []interface{}) string {
+	vv := make([]interface{}, len(values))
+	for i, v := range values {
+		switch x := v.(type) {
+		case []byte:
+			vv[i] = string(x)
+		case time.Time:
+			vv[i] = map[string]string{cursorTimeKey: x.Format(time.RFC3339Nano)}
+		default:
+			vv[i] = v
+		}
+	}
+
+	data, _ := json.Marshal(vv)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor decodes the cursor token created by EncodeCursor to the ORDER BY column values.
+func DecodeCursor(cursor string) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadCursor, err)
+	}
+
+	var values []interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&values); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadCursor, err)
+	}
+
+	for i, v := range values {
+		switch x := v.(type) {
+		case json.Number:
+			if iv, err := x.Int64(); err == nil {
+				values[i] = iv
+			} else if fv, err := x.Float64(); err == nil {
+				values[i] = fv
+			}
+		case map[string]interface{}:
+			s, _ := x[cursorTimeKey].(string)
+			t, err := time.Parse(time.RFC3339Nano, s)
+			if err != nil {
+				return nil, fmt.Errorf("%w: bad time value %v", ErrBadCursor, x)
+			}
+			values[i] = t
+		}
+	}
+
+	return values, nil
+}
+
+type keysetItem struct {
+	Expr   Expr
+	Desc   bool
+	Column string
+}
+
+// parseKeysetItems resolves the ORDER BY items to the seek expressions and their result column names.
+func parseKeysetItems(sel *Select) ([]keysetItem, error) {
+	if len(sel.OrderBy) == 0 {
+		return nil, ErrKeysetOrderBy
+	}
+
+	items := make([]keysetItem, 0, len(sel.OrderBy))
+	for _, order := range sel.OrderBy {
+		item := keysetItem{Expr: order.Expr, Desc: order.Direction == DescScr}
+		orderStr := String(order.Expr)
+		if cn, ok := order.Expr.(*ColName); ok {
+			item.Column = cn.Name.String()
+		} else {
+			item.Column = orderStr
+		}
+
+		for _, se := range sel.SelectExprs {
+			ae, ok := se.(*AliasedExpr)
+			if !ok {
+				continue
+			}
+
+			if !ae.As.IsEmpty() && ae.As.EqualString(orderStr) { // order by alias
+				item.Expr = ae.Expr
+				item.Column = ae.As.String()
+				break
+			}
+			if String(ae.Expr) == orderStr {
+				if !ae.As.IsEmpty() {
+					item.Column = ae.As.String()
+				}
+				break
+			}
+		}
+
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+func (t DBType) supportsRowValues() bool {
+	switch t {
+	case Mysql, Sqlite3, Postgresql, Kingbase:
+		return true
+	default:
+		return false
+	}
+}
+
+// createKeysetCondition creates the seek condition like (a, b) > (?, ?),
+// or a > ? or (a = ? and b > ?) for the databases without row value comparison or with mixed directions.
+func (t DBType) createKeysetCondition(items []keysetItem, values []interface{}) (Expr, []interface{}) {
+	cmp := func(item keysetItem) string { return ss.If(item.Desc, LessThanStr, GreaterThanStr) }
+	mixed := false
+	for _, item := range items[1:] {
+		mixed = mixed || item.Desc != items[0].Desc
+	}
+
+	if len(items) == 1 || t.supportsRowValues() && !mixed {
+		left, right := make(ValTuple, len(items)), make(ValTuple, len(items))
+		for i, item := range items {
+			left[i], right[i] = item.Expr, NewValArg([]byte("?"))
+		}
+		if len(items) == 1 {
+			return &ComparisonExpr{Operator: cmp(items[0]), Left: left[0], Right: right[0]}, values
+		}
+		return &ComparisonExpr{Operator: cmp(items[0]), Left: left, Right: right}, values
+	}
+
+	var cond Expr
+	var args []interface{}
+	for i, item := range items {
+		var and Expr
+		for j := 0; j < i; j++ {
+			eq := &ComparisonExpr{Operator: EqualStr, Left: items[j].Expr, Right: NewValArg([]byte("?"))}
+			and = andExpr(and, eq)
+			args = append(args, values[j])
+		}
+		and = andExpr(and, &ComparisonExpr{Operator: cmp(item), Left: item.Expr, Right: NewValArg([]byte("?"))})
+		args = append(args, values[i])
+		if i > 0 {
+			and = &ParenExpr{Expr: and}
+		}
+
+		if cond == nil {
+			cond = and
+		} else {
+			cond = &OrExpr{Left: cond, Right: and}
+		}
+	}
+
+	return &ParenExpr{Expr: cond}, args
+}
+
+func andExpr(left, right Expr) Expr {
+	if left == nil {
+		return right
+	}
+	return &AndExpr{Left: left, Right: right}
+}
+
+// applyKeyset rewrites the select statement to seek after the cursor,
+// and returns the bind args of the seek condition.
+func (t DBType) applyKeyset(sel *Select, k *Keyset) ([]interface{}, error) {
+	if k.PageSize <= 0 {
+		k.PageSize = 20
+	}
+
+	items, err := parseKeysetItems(sel)
+	if err != nil {
+		return nil, err
+	}
+
+	k.Columns = make([]string, len(items))
+	for i, item := range items {
+		k.Columns[i] = item.Column
+	}
+
+	if err := checkKeysetTailPlaceholders(sel); err != nil {
+		return nil, err
+	}
+
+	if k.Cursor == "" {
+		return nil, nil
+	}
+
+	values, err := DecodeCursor(k.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != len(items) {
+		return nil, fmt.Errorf("%w: %d values for %d order by columns", ErrBadCursor, len(values), len(items))
+	}
+
+	cond, args := t.createKeysetCondition(items, values)
+	if sel.Where != nil {
+		if _, ok := sel.Where.Expr.(*OrExpr); ok {
+			sel.Where.Expr = &ParenExpr{Expr: sel.Where.Expr}
+		}
+	}
+	sel.AddWhere(cond)
+	return args, nil
+}
+
+// checkKeysetTailPlaceholders checks that there are no placeholders after the WHERE clause,
+// because the bind args of the seek condition are appended after the original ones.
+func checkKeysetTailPlaceholders(sel *Select) error {
+	found := false
+	_ = Walk(func(node SQLNode) (bool, error) {
+		if v, ok := node.(*SQLVal); ok && v.Type == ValArg {
+			found = true
+		}
+		return !found, nil
+	}, sel.GroupBy, sel.Having, sel.OrderBy)
+
+	if found {
+		return fmt.Errorf("keyset paging with placeholders after where clause, error %w", ErrSyntax)
+	}
+	return nil
+}
+
+// keysetColumnIndex returns the index of the column name in columns, ignoring case, or -1.
+func keysetColumnIndex(columns []string, name string) int {
+	for i, c := range columns {
+		if strings.EqualFold(c, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// NextCursorOf picks the values of the ORDER BY columns from the last row of the page, and encodes them to NextCursor.
+func (k *Keyset) NextCursorOf(columns []string, lastRow []interface{}) error {
+	values := make([]interface{}, len(k.Columns))
+	for i, name := range k.Columns {
+		idx := keysetColumnIndex(columns, name)
+		if idx < 0 {
+			return fmt.Errorf("keyset column %s not found in the query result columns %v", name, columns)
+		}
+		values[i] = lastRow[idx]
+	}
+
+	k.NextCursor = EncodeCursor(values)
+	return nil
+}
@@ -0,0 +1,88 @@
+package sqlparser
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeysetConvert(t *testing.T) {
+	const q0 = `select id, name from user where age > ? order by create_time desc, id desc`
+
+	k := &Keyset{PageSize: 10}
+	r, err := Mysql.Convert(q0, WithKeyset(k))
+	assert.Nil(t, err)
+	q, args := r.PickArgs([]interface{}{18})
+	assert.Equal(t, "select `id`, `name` from `user` where `age` > ? order by create_time desc, `id` desc limit ?,?", q)
+	assert.Equal(t, []interface{}{18, 0, 11}, args)
+	assert.Equal(t, []string{"create_time", "id"}, k.Columns)
+
+	cursor := EncodeCursor([]interface{}{"2024-01-01 00:00:00", int64(100)})
+	values, err := DecodeCursor(cursor)
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"2024-01-01 00:00:00", int64(100)}, values)
+
+	cases := []struct {
+		DBType
+		Q    string
+		Args []interface{}
+	}{
+		{Mysql, "select `id`, `name` from `user` where `age` > ? and (create_time, `id`) < (?, ?) order by create_time desc, `id` desc limit ?,?",
+			[]interface{}{18, "2024-01-01 00:00:00", int64(100), 0, 11}},
+		{Postgresql, `select "id", "name" from "user" where "age" > $1 and (create_time, "id") < ($2, $3) order by create_time desc, "id" desc limit $4 offset $5`,
+			[]interface{}{18, "2024-01-01 00:00:00", int64(100), 11, 0}},
+		{Kingbase, `select "id", "name" from "user" where "age" > $1 and (create_time, "id") < ($2, $3) order by create_time desc, "id" desc limit $4 offset $5`,
+			[]interface{}{18, "2024-01-01 00:00:00", int64(100), 11, 0}},
+		{Dm, `select "id", "name" from "user" where "age" > ? and (create_time < ? or (create_time = ? and "id" < ?)) order by create_time desc, "id" desc limit ?,?`,
+			[]interface{}{18, "2024-01-01 00:00:00", "2024-01-01 00:00:00", int64(100), 0, 11}},
+		{Mssql, `select "id", "name" from "user" where "age" > @p1 and (create_time < @p2 or (create_time = @p3 and "id" < @p4)) order by create_time desc, "id" desc offset @p5 rows fetch next @p6 rows only`,
+			[]interface{}{18, "2024-01-01 00:00:00", "2024-01-01 00:00:00", int64(100), 0, 11}},
+		{Oracle, `select id, name from "USER" where age > :1 and (create_time < :2 or (create_time = :3 and id < :4)) order by create_time desc, id desc offset :5 rows fetch next :6 rows only`,
+			[]interface{}{18, "2024-01-01 00:00:00", "2024-01-01 00:00:00", int64(100), 0, 11}},
+	}
+
+	for _, c := range cases {
+		r, err = c.DBType.Convert(q0, WithKeyset(&Keyset{PageSize: 10, Cursor: cursor}))
+		assert.Nil(t, err, c.DBType)
+		q, args = r.PickArgs([]interface{}{18})
+		assert.Equal(t, c.Q, q, c.DBType)
+		assert.Equal(t, c.Args, args, c.DBType)
+	}
+
+	// mixed directions fall back to the expanded condition, or where clause is wrapped by parentheses.
+	const q1 = `select a as x, b from t where a = 1 or a = 2 order by x, b desc`
+	k = &Keyset{PageSize: 5, Cursor: EncodeCursor([]interface{}{1, "b"})}
+	r, err = Mysql.Convert(q1, WithKeyset(k))
+	assert.Nil(t, err)
+	q, args = r.PickArgs(nil)
+	assert.Equal(t, "select `a` as `x`, `b` from `t` where (`a` = 1 or `a` = 2) and (`a` > ? or (`a` = ? and `b` < ?)) order by `x`, `b` desc limit ?,?", q)
+	assert.Equal(t, []interface{}{int64(1), int64(1), "b", 0, 6}, args)
+	assert.Equal(t, []string{"x", "b"}, k.Columns)
+
+	_, err = Mysql.Convert(`select a from t`, WithKeyset(NewKeyset("")))
+	assert.True(t, errors.Is(err, ErrKeysetOrderBy))
+
+	_, err = Mysql.Convert(q0, WithKeyset(NewKeyset("bad cursor")))
+	assert.True(t, errors.Is(err, ErrBadCursor))
+
+	// the time values are decoded back to time.Time
+	created := time.Date(2024, 1, 1, 8, 30, 0, 123, time.FixedZone("CST", 8*3600))
+	values, err = DecodeCursor(EncodeCursor([]interface{}{created, int64(100)}))
+	assert.Nil(t, err)
+	assert.True(t, created.Equal(values[0].(time.Time)))
+	assert.Equal(t, int64(100), values[1])
+
+	// ConvertQuery is idempotent
+	r, err = Mysql.Convert(q0, WithKeyset(&Keyset{PageSize: 10, Cursor: cursor}))
+	assert.Nil(t, err)
+	_, args = r.PickArgs([]interface{}{18})
+	q, args2 := r.PickArgs([]interface{}{18})
+	assert.Equal(t, cases[0].Q, q)
+	assert.Equal(t, args, args2)
+
+	k = &Keyset{Columns: []string{"create_time", "id"}}
+	assert.Nil(t, k.NextCursorOf([]string{"ID", "NAME", "CREATE_TIME"}, []interface{}{int64(100), "bingoo", "2024-01-01 00:00:00"}))
+	assert.Equal(t, cursor, k.NextCursor)
+}
@@ -0,0 +1,402 @@
+package sqlparser
+
+import (
+	"strings"
+)
+
+// FuncCall is a MySQL function call to be translated.
+type FuncCall struct {
+	Name     string // lowered function name
+	Distinct bool
+	Args     SelectExprs
+	// OrderBy and Separator are only for group_concat, Separator is "," by default.
+	OrderBy   OrderBy
+	Separator string
+}
+
+// Arg returns the i-th argument, nil if absent.
+func (c *FuncCall) Arg(i int) Expr {
+	if i < len(c.Args) {
+		if e, ok := c.Args[i].(*AliasedExpr); ok {
+			return e.Expr
+		}
+	}
+	return nil
+}
+
+// FuncTranslator formats the MySQL function call in the dialect of a db type.
+type FuncTranslator func(buf *TrackedBuffer, call *FuncCall)
+
+// TypeTranslator formats the MySQL type of CAST/CONVERT in the dialect of a db type.
+type TypeTranslator func(buf *TrackedBuffer, t *ConvertType)
+
+// RenameFunc translates the function call to the function name with the same arguments.
+func RenameFunc(name string) FuncTranslator {
+	return func(buf *TrackedBuffer, call *FuncCall) {
+		var distinct string
+		if call.Distinct {
+			distinct = "distinct "
+		}
+		buf.Myprintf("%s(%s%v)", name, distinct, call.Args)
+	}
+}
+
+// ConstFunc translates the function call to the fixed expression, like current_timestamp.
+func ConstFunc(expr string) FuncTranslator {
+	return func(buf *TrackedBuffer, _ *FuncCall) { buf.Myprintf("%s", expr) }
+}
+
+// RenameType translates the type to the name keeping the length and scale,
+// or the default length when the length is absent and the default one is not empty.
+func RenameType(name, defaultLength string) TypeTranslator {
+	return func(buf *TrackedBuffer, t *ConvertType) {
+		switch {
+		case t.Length != nil:
+			(&ConvertType{Type: name, Length: t.Length, Scale: t.Scale}).Format(buf)
+		case defaultLength != "":
+			buf.Myprintf("%s(%s)", name, defaultLength)
+		default:
+			buf.Myprintf("%s", name)
+		}
+	}
+}
+
+// ConstType translates the type to the fixed one, ignoring the length and scale.
+func ConstType(typ string) TypeTranslator {
+	return func(buf *TrackedBuffer, _ *ConvertType) { buf.Myprintf("%s", typ) }
+}
+
+var (
+	funcTranslators = map[DBType]map[string]FuncTranslator{
+		Postgresql: postgresqlFuncs(),
+		Kingbase:   postgresqlFuncs(),
+		Oracle:     oracleFuncs(),
+		Dm:         oracleFuncs(),
+		Mssql:      mssqlFuncs(),
+	}
+	typeTranslators = map[DBType]map[string]TypeTranslator{
+		Postgresql: postgresqlTypes(),
+		Kingbase:   postgresqlTypes(),
+		Oracle:     oracleTypes(),
+		Dm:         oracleTypes(),
+		Mssql:      mssqlTypes(),
+	}
+)
+
+// RegisterFunc registers the translator of the MySQL function name for the db type, which replaces the builtin one.
+func RegisterFunc(dbType DBType, name string, translator FuncTranslator) {
+	m := funcTranslators[dbType]
+	if m == nil {
+		m = make(map[string]FuncTranslator)
+		funcTranslators[dbType] = m
+	}
+	m[strings.ToLower(name)] = translator
+}
+
+// RegisterType registers the translator of the MySQL type name in CAST/CONVERT for the db type,
+// which replaces the builtin one.
+func RegisterType(dbType DBType, name string, translator TypeTranslator) {
+	m := typeTranslators[dbType]
+	if m == nil {
+		m = make(map[string]TypeTranslator)
+		typeTranslators[dbType] = m
+	}
+	m[strings.ToLower(name)] = translator
+}
+
+// translatesMySQL tells whether the MySQL functions and types should be translated for the db type.
+func (t DBType) translatesMySQL() bool {
+	switch t {
+	case Mysql, Gbase:
+		return false
+	default:
+		return true
+	}
+}
+
+// formatNode formats the node by the translators of the db type, see TrackedBuffer.nodeFormatter.
+func (t DBType) formatNode(buf *TrackedBuffer, node SQLNode) {
+	switch n := node.(type) {
+	case *FuncExpr:
+		if n.Qualifier.IsEmpty() {
+			if f := funcTranslators[t][n.Name.Lowered()]; f != nil {
+				f(buf, &FuncCall{Name: n.Name.Lowered(), Distinct: n.Distinct, Args: n.Exprs})
+				return
+			}
+		}
+	case *GroupConcatExpr:
+		if f := funcTranslators[t]["group_concat"]; f != nil {
+			f(buf, &FuncCall{
+				Name: "group_concat", Distinct: n.Distinct != "", Args: n.Exprs,
+				OrderBy: n.OrderBy, Separator: parseSeparator(n.Separator),
+			})
+			return
+		}
+	case *ConvertExpr:
+		// convert(expr, type) is MySQL only, and cast(expr as type) is standard.
+		buf.Myprintf("cast(%v as %v)", n.Expr, n.Type)
+		return
+	case *ConvertType:
+		if f := typeTranslators[t][strings.ToLower(n.Type)]; f != nil {
+			f(buf, n)
+			return
+		}
+		// the charset is MySQL only
+		(&ConvertType{Type: n.Type, Length: n.Length, Scale: n.Scale}).Format(buf)
+		return
+	}
+
+	node.Format(buf)
+}
+
+// parseSeparator parses the separator of group_concat like " separator ';'".
+func parseSeparator(s string) string {
+	if s == "" {
+		return ","
+	}
+	s = strings.TrimPrefix(s, " separator '")
+	return strings.TrimSuffix(s, "'")
+}
+
+// formatOrders formats the orders without the leading " order by " of OrderBy.Format.
+func formatOrders(buf *TrackedBuffer, orders OrderBy) {
+	for i, o := range orders {
+		if i > 0 {
+			buf.Myprintf(", ")
+		}
+		buf.Myprintf("%v", o)
+	}
+}
+
+// ifFunc translates if(cond, a, b) to the case expression.
+func ifFunc(buf *TrackedBuffer, call *FuncCall) {
+	if len(call.Args) != 3 {
+		RenameFunc(call.Name)(buf, call)
+		return
+	}
+	buf.Myprintf("case when %v then %v else %v end", call.Arg(0), call.Arg(1), call.Arg(2))
+}
+
+// dateFormatFunc translates date_format(d, '%Y-%m-%d') to the function like to_char(d, 'YYYY-MM-DD'),
+// the format which is not a string literal is kept as is.
+func dateFormatFunc(name string, specifiers map[byte]string, plain string) FuncTranslator {
+	return func(buf *TrackedBuffer, call *FuncCall) {
+		format, ok := call.Arg(1).(*SQLVal)
+		if len(call.Args) != 2 || !ok || format.Type != StrVal {
+			RenameFunc(call.Name)(buf, call)
+			return
+		}
+		pattern := translateDateFormat(string(format.Val), specifiers, plain)
+		buf.Myprintf("%s(%v, %v)", name, call.Arg(0), NewStrVal([]byte(pattern)))
+	}
+}
+
+// translateDateFormat translates the MySQL DATE_FORMAT specifiers to the pattern,
+// the literal characters other than the plain ones are double-quoted.
+func translateDateFormat(format string, specifiers map[byte]string, plain string) string {
+	var b, literal strings.Builder
+	flush := func() {
+		if literal.Len() > 0 {
+			b.WriteString(`"` + literal.String() + `"`)
+			literal.Reset()
+		}
+	}
+	for i := 0; i < len(format); i++ {
+		c := format[i]
+		if c == '%' && i+1 < len(format) {
+			i++
+			if p, ok := specifiers[format[i]]; ok {
+				flush()
+				b.WriteString(p)
+				continue
+			}
+			c = format[i] // %% and the unknown specifiers are the literal characters
+		}
+		if strings.IndexByte(plain, c) >= 0 {
+			flush()
+			b.WriteByte(c)
+		} else {
+			literal.WriteByte(c)
+		}
+	}
+	flush()
+	return b.String()
+}
+
+// toCharSpecifiers are the patterns of to_char for postgresql and oracle.
+var toCharSpecifiers = map[byte]string{
+	'Y': "YYYY", 'y': "YY", 'm': "MM", 'c': "MM", 'd': "DD", 'e': "DD",
+	'H': "HH24", 'k': "HH24", 'h': "HH12", 'I': "HH12", 'l': "HH12", 'i': "MI", 's': "SS", 'S': "SS",
+	'p': "AM", 'M': "Month", 'b': "Mon", 'W': "Day", 'a': "Dy", 'j': "DDD",
+}
+
+func postgresqlFuncs() map[string]FuncTranslator {
+	toChar := make(map[byte]string, len(toCharSpecifiers)+1)
+	for k, v := range toCharSpecifiers {
+		toChar[k] = v
+	}
+	toChar['f'] = "US"
+
+	return map[string]FuncTranslator{
+		"now":               ConstFunc("current_timestamp"),
+		"sysdate":           ConstFunc("current_timestamp"),
+		"current_timestamp": ConstFunc("current_timestamp"),
+		"localtimestamp":    ConstFunc("localtimestamp"),
+		"curdate":           ConstFunc("current_date"),
+		"current_date":      ConstFunc("current_date"),
+		"curtime":           ConstFunc("current_time"),
+		"ifnull":            RenameFunc("coalesce"),
+		"if":                ifFunc,
+		"rand":              RenameFunc("random"),
+		"lcase":             RenameFunc("lower"),
+		"ucase":             RenameFunc("upper"),
+		"date_format":       dateFormatFunc("to_char", toChar, " -/,.;:"),
+		"group_concat": func(buf *TrackedBuffer, call *FuncCall) {
+			// string_agg(distinct cast(a as text), ',' order by a)
+			var distinct string
+			if call.Distinct {
+				distinct = "distinct "
+			}
+			if len(call.Args) == 1 {
+				buf.Myprintf("string_agg(%scast(%v as text), ", distinct, call.Args)
+			} else {
+				buf.Myprintf("string_agg(%sconcat(%v), ", distinct, call.Args)
+			}
+			buf.Myprintf("%v%v)", NewStrVal([]byte(call.Separator)), call.OrderBy)
+		},
+	}
+}
+
+func oracleFuncs() map[string]FuncTranslator {
+	toChar := make(map[byte]string, len(toCharSpecifiers)+1)
+	for k, v := range toCharSpecifiers {
+		toChar[k] = v
+	}
+	toChar['f'] = "FF6"
+
+	concat := func(buf *TrackedBuffer, args SelectExprs) {
+		for i, arg := range args {
+			if i > 0 {
+				buf.Myprintf(" || ")
+			}
+			buf.Myprintf("%v", arg)
+		}
+	}
+
+	return map[string]FuncTranslator{
+		"now":               ConstFunc("sysdate"),
+		"sysdate":           ConstFunc("sysdate"),
+		"current_timestamp": ConstFunc("current_timestamp"),
+		"localtimestamp":    ConstFunc("localtimestamp"),
+		"curdate":           ConstFunc("trunc(sysdate)"),
+		"current_date":      ConstFunc("trunc(sysdate)"),
+		"ifnull":            RenameFunc("nvl"),
+		"if":                ifFunc,
+		"rand":              ConstFunc("dbms_random.value"),
+		"lcase":             RenameFunc("lower"),
+		"ucase":             RenameFunc("upper"),
+		"date_format":       dateFormatFunc("to_char", toChar, " -/,.;:"),
+		"concat": func(buf *TrackedBuffer, call *FuncCall) {
+			// concat of oracle accepts only 2 arguments
+			buf.Myprintf("(")
+			concat(buf, call.Args)
+			buf.Myprintf(")")
+		},
+		"group_concat": func(buf *TrackedBuffer, call *FuncCall) {
+			// listagg(distinct a, ',') within group (order by a)
+			var distinct string
+			if call.Distinct {
+				distinct = "distinct "
+			}
+			buf.Myprintf("listagg(%s", distinct)
+			concat(buf, call.Args)
+			buf.Myprintf(", %v) within group (order by ", NewStrVal([]byte(call.Separator)))
+			if len(call.OrderBy) > 0 {
+				formatOrders(buf, call.OrderBy)
+			} else {
+				concat(buf, call.Args)
+			}
+			buf.Myprintf(")")
+		},
+	}
+}
+
+// formatSpecifiers are the patterns of format for mssql.
+var formatSpecifiers = map[byte]string{
+	'Y': "yyyy", 'y': "yy", 'm': "MM", 'c': "M", 'd': "dd", 'e': "d",
+	'H': "HH", 'k': "H", 'h': "hh", 'I': "hh", 'l': "h", 'i': "mm", 's': "ss", 'S': "ss", 'f': "ffffff",
+	'p': "tt", 'M': "MMMM", 'b': "MMM", 'W': "dddd", 'a': "ddd",
+}
+
+func mssqlFuncs() map[string]FuncTranslator {
+	return map[string]FuncTranslator{
+		"now":               ConstFunc("getdate()"),
+		"sysdate":           ConstFunc("getdate()"),
+		"current_timestamp": ConstFunc("current_timestamp"),
+		"localtimestamp":    ConstFunc("getdate()"),
+		"curdate":           ConstFunc("cast(getdate() as date)"),
+		"current_date":      ConstFunc("cast(getdate() as date)"),
+		"curtime":           ConstFunc("cast(getdate() as time)"),
+		"ifnull":            RenameFunc("isnull"),
+		"if":                ifFunc,
+		"lcase":             RenameFunc("lower"),
+		"ucase":             RenameFunc("upper"),
+		// the : and / in the format are the culture-specific separators, which are quoted as the literal ones.
+		"date_format": dateFormatFunc("format", formatSpecifiers, " -,."),
+		"group_concat": func(buf *TrackedBuffer, call *FuncCall) {
+			// string_agg(a, ',') within group (order by a), sqlserver 2017+
+			var distinct string
+			if call.Distinct {
+				distinct = "distinct "
+			}
+			if len(call.Args) == 1 {
+				buf.Myprintf("string_agg(%s%v, ", distinct, call.Args)
+			} else {
+				buf.Myprintf("string_agg(%sconcat(%v), ", distinct, call.Args)
+			}
+			buf.Myprintf("%v)", NewStrVal([]byte(call.Separator)))
+			if len(call.OrderBy) > 0 {
+				buf.Myprintf(" within group (order by ")
+				formatOrders(buf, call.OrderBy)
+				buf.Myprintf(")")
+			}
+		},
+	}
+}
+
+func postgresqlTypes() map[string]TypeTranslator {
+	return map[string]TypeTranslator{
+		"char":     RenameType("varchar", ""),
+		"nchar":    RenameType("varchar", ""),
+		"signed":   RenameType("bigint", ""),
+		"unsigned": RenameType("bigint", ""),
+		"decimal":  RenameType("numeric", ""),
+		"datetime": RenameType("timestamp", ""),
+		"binary":   ConstType("bytea"),
+	}
+}
+
+func oracleTypes() map[string]TypeTranslator {
+	return map[string]TypeTranslator{
+		"char":     RenameType("varchar2", "4000"),
+		"nchar":    RenameType("nvarchar2", "2000"),
+		"signed":   ConstType("number(19)"),
+		"unsigned": ConstType("number(20)"),
+		"decimal":  RenameType("number", ""),
+		"datetime": RenameType("timestamp", ""),
+		"binary":   RenameType("raw", "2000"),
+		"json":     ConstType("clob"),
+	}
+}
+
+func mssqlTypes() map[string]TypeTranslator {
+	return map[string]TypeTranslator{
+		"char":     RenameType("varchar", "max"),
+		"nchar":    RenameType("nvarchar", "max"),
+		"signed":   ConstType("bigint"),
+		"unsigned": ConstType("bigint"),
+		"datetime": RenameType("datetime2", ""),
+		"binary":   RenameType("varbinary", "max"),
+		"json":     ConstType("nvarchar(max)"),
+	}
+}
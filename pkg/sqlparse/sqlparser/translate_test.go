@@ -0,0 +1,67 @@
+package sqlparser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTranslate(t *testing.T) {
+	cases := []struct {
+		dbType DBType
+		query  string
+		expect string
+	}{
+		{Mysql, "select now(), ifnull(a, 0), cast(a as char(10)) from t",
+			"select now(), ifnull(`a`, 0), convert(`a`, char(10)) from `t`"},
+
+		{Postgresql, "select now(), current_timestamp, curdate() from t",
+			`select current_timestamp, current_timestamp, current_date from "t"`},
+		{Postgresql, "select ifnull(a, 0), if(a > 1, 'x', 'y'), concat(a, b) from t",
+			`select coalesce("a", 0), case when "a" > 1 then 'x' else 'y' end, concat("a", "b") from "t"`},
+		{Postgresql, "select date_format(d, '%Y-%m-%d %H:%i:%s.%f at %p') from t",
+			`select to_char("d", 'YYYY-MM-DD HH24:MI:SS.US "at" AM') from "t"`},
+		{Postgresql, "select group_concat(distinct a order by a desc separator ';') from t",
+			`select string_agg(distinct cast("a" as text), ';' order by "a" desc) from "t"`},
+		{Postgresql, "select cast(a as char(10)), convert(b, signed), cast(c as decimal(10, 2)), cast(d as datetime) from t",
+			`select cast("a" as varchar(10)), cast("b" as bigint), cast("c" as numeric(10, 2)), cast("d" as timestamp) from "t"`},
+		{Kingbase, "select ifnull(a, 0) from t where d < now()",
+			`select coalesce("a", 0) from "t" where "d" < current_timestamp`},
+
+		{Oracle, "select now(), curdate(), ifnull(a, 0), concat(a, b, 'c') from t",
+			`select sysdate, trunc(sysdate), nvl(a, 0), (a || b || 'c') from t`},
+		{Oracle, "select date_format(d, '%Y/%m/%d %H:%i:%s.%f') from t",
+			`select to_char(d, 'YYYY/MM/DD HH24:MI:SS.FF6') from t`},
+		{Oracle, "select group_concat(a) from t",
+			`select listagg(a, ',') within group (order by a) from t`},
+		{Oracle, "select cast(a as char), convert(b, signed), cast(c as decimal(10, 2)) from t",
+			`select cast(a as varchar2(4000)), cast(b as number(19)), cast(c as number(10, 2)) from t`},
+		{Dm, "select ifnull(a, 0), group_concat(a separator '|') from t",
+			`select nvl("a", 0), listagg("a", '|') within group (order by "a") from "t"`},
+
+		{Mssql, "select now(), curdate(), ifnull(a, 0), concat(a, b) from t",
+			`select getdate(), cast(getdate() as date), isnull("a", 0), concat("a", "b") from "t"`},
+		{Mssql, "select date_format(d, '%Y-%m-%d %H:%i') from t",
+			`select format("d", 'yyyy-MM-dd HH":"mm') from "t"`},
+		{Mssql, "select group_concat(a order by a) from t",
+			`select string_agg("a", ',') within group (order by "a") from "t"`},
+		{Mssql, "select cast(a as char), cast(b as char(10) character set utf8mb4), cast(d as datetime) from t",
+			`select cast("a" as varchar(max)), cast("b" as varchar(10)), cast("d" as datetime2) from "t"`},
+	}
+
+	for _, c := range cases {
+		r, err := c.dbType.Convert(c.query)
+		assert.Nil(t, err, c.query)
+		assert.Equal(t, c.expect, r.ConvertQuery(), "%s: %s", c.dbType, c.query)
+	}
+}
+
+func TestRegisterTranslator(t *testing.T) {
+	const db DBType = "translate_test"
+	RegisterFunc(db, "IFNULL", RenameFunc("nullif_test"))
+	RegisterType(db, "char", RenameType("string", ""))
+
+	r, err := db.Convert("select ifnull(a, 0), cast(a as char(3)), length(a) from t")
+	assert.Nil(t, err)
+	assert.Equal(t, `select nullif_test("a", 0), cast("a" as string(3)), length("a") from "t"`, r.ConvertQuery())
+}
@@ -0,0 +1,208 @@
+package sqlparser
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrConflictKeys tells that the conflict keys are required to convert the on duplicate key update.
+var ErrConflictKeys = fmt.Errorf("conflict keys of on duplicate key update are unknown, error %w", ErrSyntax)
+
+// WithConflictKeys sets the unique key columns to convert INSERT ... ON DUPLICATE KEY UPDATE
+// for the databases other than MySQL, which can also be hinted in the insert comment like
+// insert /* unique_key(a, b) */ into t(a, b, c) values(?, ?, ?) on duplicate key update c = values(c).
+func WithConflictKeys(columns ...string) ConvertOption {
+	return func(c *ConvertConfig) { c.ConflictKeys = columns }
+}
+
+var uniqueKeyHintReg = regexp.MustCompile(`(?i)\bunique_key\s*\(([^)]*)\)`)
+
+// parseConflictKeys parses the conflict keys from the unique_key(...) hint in the comments.
+func parseConflictKeys(comments Comments) []string {
+	for _, c := range comments {
+		if sub := uniqueKeyHintReg.FindSubmatch(c); sub != nil {
+			var keys []string
+			for _, k := range strings.Split(string(sub[1]), ",") {
+				if k = strings.Trim(strings.TrimSpace(k), "`\""); k != "" {
+					keys = append(keys, k)
+				}
+			}
+			return keys
+		}
+	}
+	return nil
+}
+
+// upsert is an INSERT ... ON DUPLICATE KEY UPDATE statement formatted in the dialect of the db type,
+// INSERT ... ON CONFLICT (...) DO UPDATE for postgresql, kingbase and sqlite3,
+// and MERGE INTO ... USING for oracle, dm, shentong and mssql.
+// The conflict keys are required for all of them. INSERT OR REPLACE of sqlite3 is not used,
+// because it deletes the conflicting row and inserts a new one, which resets the columns not inserted.
+type upsert struct {
+	*Insert
+	DBType
+	Keys Columns
+	// Source is the alias of the inserting rows in the MERGE statement.
+	Source TableIdent
+}
+
+// newUpsert converts the on duplicate key update of the insert statement to the db type.
+func (t DBType) newUpsert(insert *Insert, conflictKeys []string) (*upsert, error) {
+	if len(conflictKeys) == 0 {
+		conflictKeys = parseConflictKeys(insert.Comments)
+	}
+
+	u := &upsert{Insert: insert, DBType: t, Source: NewTableIdent("src")}
+	for _, k := range conflictKeys {
+		u.Keys = append(u.Keys, NewColIdent(k))
+	}
+
+	switch t {
+	case Postgresql, Kingbase, Sqlite3:
+		if len(u.Keys) == 0 {
+			return nil, ErrConflictKeys
+		}
+		if err := u.removeKeysUpdate(false); err != nil {
+			return nil, err
+		}
+		u.qualifyColumns()
+		excluded := TableName{Name: NewTableIdent("excluded")}
+		u.resolveValues(func(name ColIdent) Expr { return &ColName{Name: name, Qualifier: excluded} })
+		return u, nil
+	case Oracle, Dm, Shentong, Mssql:
+		if len(u.Keys) == 0 {
+			return nil, ErrConflictKeys
+		}
+		if _, ok := insert.Rows.(Values); !ok {
+			return nil, fmt.Errorf("insert select on duplicate key update is not supported for %s, error %w", t, ErrSyntax)
+		}
+		if err := u.removeKeysUpdate(true); err != nil {
+			return nil, err
+		}
+		u.qualifyColumns()
+		source := TableName{Name: u.Source}
+		u.resolveValues(func(name ColIdent) Expr { return &ColName{Name: name, Qualifier: source} })
+		return u, nil
+	default:
+		return nil, fmt.Errorf("on duplicate key update is not supported for %s, error %w", t, ErrSyntax)
+	}
+}
+
+func (u *upsert) isKey(name ColIdent) bool {
+	for _, k := range u.Keys {
+		if k.Equal(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// removeKeysUpdate removes the no-op updates of the keys like k = values(k).
+// The other updates of the keys fail when strict,
+// because the columns referenced in the ON clause of MERGE can not be updated.
+func (u *upsert) removeKeysUpdate(strict bool) error {
+	var exprs OnDup
+	for _, e := range u.OnDup {
+		v, ok := e.Expr.(*ValuesFuncExpr)
+		switch {
+		case !u.isKey(e.Name.Name):
+			exprs = append(exprs, e)
+		case ok && v.Name.Equal(e.Name.Name): // no-op
+		case strict:
+			return fmt.Errorf("update of the conflict key %s is not supported for %s, error %w", e.Name.Name, u.DBType, ErrSyntax)
+		default:
+			exprs = append(exprs, e)
+		}
+	}
+	u.OnDup = exprs
+	return nil
+}
+
+// qualifyColumns qualifies the columns in the update expressions with the target table,
+// to distinguish them from the source columns in MERGE, or from the excluded ones in ON CONFLICT.
+func (u *upsert) qualifyColumns() {
+	target := TableName{Name: u.Table.Name, Qualifier: u.Table.Qualifier}
+	for _, e := range u.OnDup {
+		_ = Walk(func(node SQLNode) (bool, error) {
+			if cn, ok := node.(*ColName); ok && cn.Qualifier.IsEmpty() {
+				cn.Qualifier = target
+			}
+			return true, nil
+		}, e.Expr)
+	}
+}
+
+// resolveValues resolves the values(c) functions in the update expressions.
+func (u *upsert) resolveValues(resolve func(ColIdent) Expr) {
+	for _, e := range u.OnDup {
+		_ = Walk(func(node SQLNode) (bool, error) {
+			if v, ok := node.(*ValuesFuncExpr); ok {
+				v.Resolved = resolve(v.Name)
+			}
+			return true, nil
+		}, e.Expr)
+	}
+}
+
+// Format formats the node.
+func (u *upsert) Format(buf *TrackedBuffer) {
+	switch u.DBType {
+	case Postgresql, Kingbase, Sqlite3:
+		buf.Myprintf("insert %vinto %v%v %v on conflict %v", u.Comments, u.Table, u.Columns, u.Rows, u.Keys)
+		if len(u.OnDup) == 0 {
+			buf.Myprintf(" do nothing")
+		} else {
+			buf.Myprintf(" do update set %v", UpdateExprs(u.OnDup))
+		}
+	case Mssql:
+		// merge into t using (values (@p1, @p2)) as src (a, b) on (...) when matched ... ;
+		buf.Myprintf("merge into %v using (%v) as %v%v on (", u.Table, u.Rows, u.Source, u.Columns)
+		u.formatMergeTail(buf)
+		buf.Myprintf(";")
+	default:
+		// merge into t using (select :1 as a, :2 as b from dual union all ...) src on (...) when matched ...
+		buf.Myprintf("merge into %v using (", u.Table)
+		for i, row := range u.Rows.(Values) {
+			if i > 0 {
+				buf.Myprintf(" union all ")
+			}
+			buf.Myprintf("select ")
+			for j, v := range row {
+				if j > 0 {
+					buf.Myprintf(", ")
+				}
+				buf.Myprintf("%v as %v", v, u.Columns[j])
+			}
+			buf.Myprintf(" from dual")
+		}
+		buf.Myprintf(") %v on (", u.Source)
+		u.formatMergeTail(buf)
+	}
+}
+
+// formatMergeTail formats the MERGE statement after the ON (.
+func (u *upsert) formatMergeTail(buf *TrackedBuffer) {
+	target := TableName{Name: u.Table.Name, Qualifier: u.Table.Qualifier}
+	source := TableName{Name: u.Source}
+	for i, k := range u.Keys {
+		if i > 0 {
+			buf.Myprintf(" and ")
+		}
+		buf.Myprintf("%v = %v", &ColName{Name: k, Qualifier: target}, &ColName{Name: k, Qualifier: source})
+	}
+	buf.Myprintf(")")
+
+	if len(u.OnDup) > 0 {
+		buf.Myprintf(" when matched then update set %v", UpdateExprs(u.OnDup))
+	}
+
+	buf.Myprintf(" when not matched then insert %v values (", u.Columns)
+	for i, c := range u.Columns {
+		if i > 0 {
+			buf.Myprintf(", ")
+		}
+		buf.Myprintf("%v", &ColName{Name: c, Qualifier: source})
+	}
+	buf.Myprintf(")")
+}
@@ -0,0 +1,57 @@
+package sqlparser
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertUpsert(t *testing.T) {
+	const q = "insert into t (id, a, b) values(?, ?, ?) on duplicate key update id=values(id), a=values(a), b=b+values(b)"
+
+	r, err := Postgresql.Convert(q, WithConflictKeys("id"))
+	assert.Nil(t, err)
+	assert.Equal(t, `insert into "t"("id", "a", "b") values ($1, $2, $3) on conflict ("id") `+
+		`do update set "a" = "excluded"."a", "b" = "t"."b" + "excluded"."b"`, r.ConvertQuery())
+
+	r, err = Oracle.Convert(q, WithConflictKeys("id"))
+	assert.Nil(t, err)
+	assert.Equal(t, `merge into t using (select :1 as id, :2 as a, :3 as b from dual) src on (t.id = src.id) `+
+		`when matched then update set a = src.a, b = t.b + src.b `+
+		`when not matched then insert (id, a, b) values (src.id, src.a, src.b)`, r.ConvertQuery())
+
+	r, err = Oracle.Convert("insert /* unique_key(id) */ into t (id, a) values(?, ?), (?, ?) on duplicate key update a=values(a)")
+	assert.Nil(t, err)
+	assert.Equal(t, `merge into t using (select :1 as id, :2 as a from dual union all select :3 as id, :4 as a from dual) src `+
+		`on (t.id = src.id) when matched then update set a = src.a `+
+		`when not matched then insert (id, a) values (src.id, src.a)`, r.ConvertQuery())
+
+	r, err = Mssql.Convert("insert /* unique_key(id) */ into t (id, a) values(:id, :a) on duplicate key update a=values(a)")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"id", "a"}, r.VarNames)
+
+	// the partial update keeps the columns not updated, instead of replacing the whole row
+	r, err = Sqlite3.Convert("insert /* unique_key(a) */ into t (a, b, c) values(?, ?, ?) on duplicate key update c=values(c)")
+	assert.Nil(t, err)
+	assert.Equal(t, "insert /* unique_key(a) */ into `t`(`a`, `b`, `c`) values (?, ?, ?) on conflict (`a`) "+
+		"do update set `c` = `excluded`.`c`", r.ConvertQuery())
+	_, err = Sqlite3.Convert("insert into t (a, b, c) values(?, ?, ?) on duplicate key update c=values(c)")
+	assert.True(t, errors.Is(err, ErrConflictKeys))
+
+	r, err = Sqlite3.Convert("insert into t (id, a) values(?, ?) on duplicate key update a=a+values(a)", WithConflictKeys("id"))
+	assert.Nil(t, err)
+	assert.Equal(t, "insert into `t`(`id`, `a`) values (?, ?) on conflict (`id`) "+
+		"do update set `a` = `t`.`a` + `excluded`.`a`", r.ConvertQuery())
+
+	r, err = Kingbase.Convert("insert into t (id) values(?) on duplicate key update id=values(id)", WithConflictKeys("id"))
+	assert.Nil(t, err)
+	assert.Equal(t, `insert into "t"("id") values ($1) on conflict ("id") do nothing`, r.ConvertQuery())
+
+	_, err = Sqlite3.Convert("insert into t (id, a) values(?, ?) on duplicate key update a=a+1")
+	assert.True(t, errors.Is(err, ErrConflictKeys))
+	_, err = Dm.Convert("insert into t (id, a) values(?, ?) on duplicate key update id=id+1", WithConflictKeys("id"))
+	assert.True(t, errors.Is(err, ErrSyntax))
+	_, err = Mssql.Convert("insert into t (id, a) select id, a from s on duplicate key update a=values(a)", WithConflictKeys("id"))
+	assert.True(t, errors.Is(err, ErrSyntax))
+}
@@ -105,19 +105,19 @@ func (s *Sqx) DoExecRaw(arg *QueryArgs) (sql.Result, error) {
 
 func (a *QueryArgs) DoExecRaw(db SqxDB) (sql.Result, error) {
 	ns := NewSQL(a.Query, a.Args...)
-	ns.Ctx = a.Ctx
+	ns.Name, ns.Ctx = a.Desc, a.Ctx
 	return ns.WithConvertOptions(a.Options).UpdateRaw(db)
 }
 
 func (a *QueryArgs) DoExec(db SqxDB) (int64, error) {
 	ns := NewSQL(a.Query, a.Args...)
-	ns.Ctx = a.Ctx
+	ns.Name, ns.Ctx = a.Desc, a.Ctx
 	return ns.WithConvertOptions(a.Options).Update(db)
 }
 
 func (a *QueryArgs) DoQuery(db SqxDB) error {
 	ns := NewSQL(a.Query, a.Args...)
-	ns.Ctx = a.Ctx
+	ns.Name, ns.Ctx = a.Desc, a.Ctx
 	return ns.WithConvertOptions(a.Options).Query(db, a.Dest)
 }
 
@@ -182,7 +182,12 @@ func (s *Sqx) QueryContext(ctx context.Context, query string, args ...interface{
 		}
 	}
 
-	rows, err := s.DB.QueryContext(ctx, s2.Q, s2.Vars...)
+	var rows *sql.Rows
+	inv := &Invocation{Kind: InvokeQuery, Query: s2.Q, Vars: s2.Vars, Rows: -1}
+	err := intercept(ctx, inv, func(ctx context.Context) (err error) {
+		rows, err = s.DB.QueryContext(ctx, s2.Q, s2.Vars...)
+		return err
+	})
 	if err != nil {
 		logQueryError(false, "", nil, err)
 	}
@@ -203,16 +208,18 @@ func (s *Sqx) TxContext(ctx context.Context, f func(sqx *Sqx) error) error {
 		panic("can't begin transaction")
 	}
 
-	tx, err := btx.BeginTx(ctx, nil)
-	if err != nil {
-		return err
-	}
+	return intercept(ctx, &Invocation{Kind: InvokeTx, Rows: -1}, func(ctx context.Context) error {
+		tx, err := btx.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
 
-	if err := f(&Sqx{DB: tx, DBType: s.DBType}); err != nil {
-		return multierr.Append(err, tx.Rollback())
-	}
+		if err := f(&Sqx{DB: tx, DBType: s.DBType}); err != nil {
+			return multierr.Append(err, tx.Rollback())
+		}
 
-	return tx.Commit()
+		return tx.Commit()
+	})
 }
 
 type DBRaw struct {
@@ -1,6 +1,7 @@
 package sqx
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -31,6 +32,45 @@ var (
 // DBGetter is the interface to get a sql.DBGetter.
 type DBGetter interface{ GetDB() *sql.DB }
 
+// ReadDBGetter is the optional interface of DBGetter to get a db for read-only queries, like a replica.
+type ReadDBGetter interface{ GetReadDB() *sql.DB }
+
+// ContextDBGetter is the optional interface of DBGetter to get the dbs by the context of the dao,
+// like a Router which scopes the read-your-writes window by WithSession.
+type ContextDBGetter interface {
+	GetDBContext(ctx context.Context) *sql.DB
+	GetReadDBContext(ctx context.Context) *sql.DB
+}
+
+// ReadErrorReporter is the optional interface of DBGetter to learn the results of the queries on the read dbs,
+// like a Router which ejects the failing replicas.
+type ReadErrorReporter interface{ ReportReadError(db *sql.DB, err error) }
+
+func getDB(g DBGetter, ctx context.Context) *sql.DB {
+	if cg, ok := g.(ContextDBGetter); ok {
+		return cg.GetDBContext(ctx)
+	}
+
+	return g.GetDB()
+}
+
+func getReadDB(g DBGetter, ctx context.Context) *sql.DB {
+	if cg, ok := g.(ContextDBGetter); ok {
+		return cg.GetReadDBContext(ctx)
+	}
+	if rg, ok := g.(ReadDBGetter); ok {
+		return rg.GetReadDB()
+	}
+
+	return g.GetDB()
+}
+
+func reportReadError(g DBGetter, db *sql.DB, err error) {
+	if rr, ok := g.(ReadErrorReporter); ok {
+		rr.ReportReadError(db, err)
+	}
+}
+
 // StdDB is the wrapper for sql.DBGetter.
 type StdDB struct{ db *sql.DB }
 
@@ -75,10 +115,17 @@ func CreateDao(dao interface{}, createDaoOpts ...CreateDaoOpter) error {
 			return fmt.Errorf("failed to find sqlName %s", f.Name) // nolint:goerr113
 		}
 
+		if option.Linter != nil {
+			if err := option.lint(sqlName, sqlStmt); err != nil {
+				return err
+			}
+		}
+
 		parsed := &SQLParsed{
-			ID:  sqlName,
-			SQL: sqlStmt,
-			opt: option,
+			ID:    sqlName,
+			SQL:   sqlStmt,
+			Batch: isBatchSQL(f, sqlStmt),
+			opt:   option,
 		}
 
 		if err := parsed.fastParseSQL(sqlStmt.Raw()); err != nil {
@@ -184,6 +231,8 @@ func (r *sqlRun) MakeFunc(f StructField, numIn, numOut int) func([]reflect.Value
 
 func (r *sqlRun) getExecFn() func(int, StructField, []reflect.Type, []reflect.Value) ([]reflect.Value, error) {
 	switch isBindByName := r.isBindBy(ByName); {
+	case !r.IsQuery && isBindByName && r.Batch:
+		return r.execBatchByName
 	case !r.IsQuery && isBindByName:
 		return r.execByName
 	case !r.IsQuery && !isBindByName:
@@ -260,7 +309,7 @@ func (r *sqlRun) queryByName(numIn int, f StructField,
 	}
 
 	counterIndex := indexOfTypes(outTypes, CountType)
-	db := r.opt.DBGetter.GetDB()
+	db := getReadDB(r.opt.DBGetter, r.opt.Ctx)
 	rows, counter, err := parsed.doQueryDirectVars(db, vars, counterIndex >= 0)
 	if err != nil {
 		return nil, err
@@ -340,7 +389,7 @@ func (r *sqlRun) execByName(numIn int, f StructField, outTypes []reflect.Type, a
 	)
 
 	parsed := *r.SQLParsed
-	db := r.opt.DBGetter.GetDB()
+	db := getDB(r.opt.DBGetter, r.opt.Ctx)
 	tx, err := db.BeginTx(parsed.opt.Ctx, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to begin tx %w", err)
@@ -374,7 +423,13 @@ func (r *sqlRun) execByName(numIn int, f StructField, outTypes []reflect.Type, a
 			}
 		}
 
-		lastResult, err = pr.ExecContext(parsed.opt.Ctx, vars...)
+		inv := &Invocation{Kind: InvokeExec, Desc: parsed.ID, Query: lastSQL, Vars: vars, Rows: -1}
+		err = intercept(parsed.opt.Ctx, inv, func(ctx context.Context) (err error) {
+			if lastResult, err = pr.ExecContext(ctx, vars...); err == nil {
+				inv.Rows, _ = lastResult.RowsAffected()
+			}
+			return err
+		})
 		if err != nil {
 			return nil, fmt.Errorf("failed to execute %s with vars %v error %w", parsed.runSQL, vars, err)
 		}
@@ -489,7 +544,7 @@ func (r *sqlRun) execBySeq(numIn int, f StructField,
 	}
 
 	vars := parsed.makeVars(args)
-	db := r.opt.DBGetter.GetDB()
+	db := getDB(r.opt.DBGetter, r.opt.Ctx)
 	query, err := r.replaceQuery(db, parsed.runSQL)
 	if err != nil {
 		return nil, fmt.Errorf("replaceQuery %s error %w", parsed.runSQL, err)
@@ -497,7 +552,14 @@ func (r *sqlRun) execBySeq(numIn int, f StructField,
 
 	log.Printf("exec query %s [%s] with %v", r.ID, query, vars)
 
-	result, err := db.ExecContext(parsed.opt.Ctx, query, vars...)
+	var result sql.Result
+	inv := &Invocation{Kind: InvokeExec, Desc: r.ID, Query: query, Vars: vars, Rows: -1}
+	err = intercept(parsed.opt.Ctx, inv, func(ctx context.Context) (err error) {
+		if result, err = db.ExecContext(ctx, query, vars...); err == nil {
+			inv.Rows, _ = result.RowsAffected()
+		}
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("execute %s error %w", r.SQL, err)
 	}
@@ -520,7 +582,7 @@ func (r *sqlRun) queryBySeq(numIn int, f StructField,
 		return nil, err
 	}
 
-	db := r.opt.DBGetter.GetDB()
+	db := getReadDB(r.opt.DBGetter, r.opt.Ctx)
 	counterIndex := indexOfTypes(outTypes, CountType)
 
 	rows, counterFn, err := parsed.doQuery(db, args, counterIndex >= 0)
@@ -639,7 +701,13 @@ func (p *SQLParsed) doQueryDirectVars(db *sql.DB, vars []interface{}, counting b
 
 	log.Printf("exec query %s [%s] with %v", p.ID, query, vars)
 
-	rows, err := db.QueryContext(p.opt.Ctx, query, vars...)
+	var rows *sql.Rows
+	err = intercept(p.opt.Ctx, &Invocation{Kind: InvokeQuery, Desc: p.ID, Query: query, Vars: vars, Rows: -1},
+		func(ctx context.Context) (err error) {
+			rows, err = db.QueryContext(ctx, query, vars...)
+			reportReadError(p.opt.DBGetter, db, err)
+			return err
+		})
 	if err != nil || rows.Err() != nil {
 		if err == nil {
 			err = rows.Err()
@@ -698,7 +766,13 @@ func (p *SQLParsed) pagingCount(db *sql.DB, query string, vars []interface{}) (i
 		return 0, fmt.Errorf("replaceQuery %s error %w", countQuery, err)
 	}
 
-	rows, err := db.QueryContext(p.opt.Ctx, countQuery, vars...)
+	var rows *sql.Rows
+	err = intercept(p.opt.Ctx, &Invocation{Kind: InvokeQuery, Desc: p.ID, Query: countQuery, Vars: vars, Rows: -1},
+		func(ctx context.Context) (err error) {
+			rows, err = db.QueryContext(ctx, countQuery, vars...)
+			reportReadError(p.opt.DBGetter, db, err)
+			return err
+		})
 	if err != nil || rows.Err() != nil {
 		if err == nil {
 			err = rows.Err()
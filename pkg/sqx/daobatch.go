@@ -0,0 +1,162 @@
+package sqx
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"reflect"
+
+	"github.com/bingoohuang/gg/pkg/sqlparse/sqlparser"
+)
+
+// WithBatchMaxPlaceholders specifies the max placeholders in a single multi-row insert statement of batch dao funcs,
+// 0 for the default of the db type, see sqlparser.DBType.DefaultMaxPlaceholders.
+func WithBatchMaxPlaceholders(n int) CreateDaoOpter {
+	return CreateDaoOptFn(func(opt *CreateDaoOpt) { opt.BatchMaxPlaceholders = n })
+}
+
+// isBatchSQL tells whether the dao func is a batch insert one,
+// by the tag batch:"true" of the field, or the batch attribute of the dot sql when the field has no such tag.
+func isBatchSQL(f StructField, part SQLPart) bool {
+	if v, ok := f.Tag.Lookup("batch"); ok {
+		return v != "false"
+	}
+
+	p, ok := part.(*PostProcessingSQLPart)
+	if !ok {
+		return false
+	}
+
+	v, ok := p.Attrs["batch"]
+	return ok && v != "false"
+}
+
+// TxGetter is the optional interface of DBGetter to get the transaction in progress, nil when there is none.
+// The batch dao funcs join the transaction instead of beginning their own,
+// and leave the commit or rollback to the owner of the transaction.
+type TxGetter interface{ GetTx() *sql.Tx }
+
+func getTx(g DBGetter) *sql.Tx {
+	if tg, ok := g.(TxGetter); ok {
+		return tg.GetTx()
+	}
+
+	return nil
+}
+
+type batchResult struct {
+	lastInsertID int64
+	rowsAffected int64
+}
+
+func (r batchResult) LastInsertId() (int64, error) { return r.lastInsertID, nil }
+func (r batchResult) RowsAffected() (int64, error) { return r.rowsAffected, nil }
+
+// execBatchByName executes the batch insert like func([]Person) (int64, error),
+// which expands the insert statement into multi-row VALUES chunks in a transaction, or the one of TxGetter,
+// and returns the total rows affected (and the last insert id of the last chunk for the second out).
+func (r *sqlRun) execBatchByName(numIn int, f StructField, outTypes []reflect.Type, args []reflect.Value) ([]reflect.Value, error) {
+	if numIn != 1 || args[0].Kind() != reflect.Slice {
+		return nil, fmt.Errorf("batch func %s should have a single slice argument, but got %v", f.Name, f.Type)
+	}
+
+	beans := args[0]
+	if beans.Len() == 0 {
+		return convertBatchResult(batchResult{}, outTypes)
+	}
+
+	parsed := *r.SQLParsed
+	if err := parsed.eval(numIn, f, parsed.createNamedMap(beans.Index(0))); err != nil {
+		return nil, err
+	}
+
+	db := getDB(r.opt.DBGetter, r.opt.Ctx)
+	dbType := sqlparser.ToDBType(DriverName(db.Driver()))
+	rowsPerStmt := dbType.BatchRows(len(parsed.Vars), r.opt.BatchMaxPlaceholders)
+
+	var err error
+	tx := getTx(r.opt.DBGetter)
+	owned := tx == nil
+	if owned {
+		if tx, err = db.BeginTx(parsed.opt.Ctx, nil); err != nil {
+			return nil, fmt.Errorf("failed to begin tx %w", err)
+		}
+	}
+	rollback := func() {
+		if owned {
+			_ = tx.Rollback()
+		}
+	}
+
+	var result batchResult
+	queries := make(map[int]string)
+	for start := 0; start < beans.Len(); start += rowsPerStmt {
+		end := start + rowsPerStmt
+		if end > beans.Len() {
+			end = beans.Len()
+		}
+
+		query, ok := queries[end-start]
+		if !ok {
+			if query, err = dbType.ConvertBatchInsert(parsed.runSQL, end-start); err != nil {
+				rollback()
+				return nil, fmt.Errorf("convert batch insert %s error %w", parsed.runSQL, err)
+			}
+			queries[end-start] = query
+		}
+
+		vars := make([]interface{}, 0, (end-start)*len(parsed.Vars))
+		for i := start; i < end; i++ {
+			itemVars, err := parsed.createNamedVars(beans.Index(i))
+			if err != nil {
+				rollback()
+				return nil, err
+			}
+			vars = append(vars, itemVars...)
+		}
+
+		log.Printf("exec batch %s [%s] with %d rows", parsed.ID, query, end-start)
+		var chunkResult sql.Result
+		inv := &Invocation{Kind: InvokeExec, Desc: parsed.ID, Query: query, Vars: vars, Rows: -1}
+		err = intercept(parsed.opt.Ctx, inv, func(ctx context.Context) (err error) {
+			if chunkResult, err = tx.ExecContext(ctx, query, vars...); err == nil {
+				inv.Rows, _ = chunkResult.RowsAffected()
+			}
+			return err
+		})
+		if err != nil {
+			rollback()
+			return nil, fmt.Errorf("failed to execute batch %s with %d rows error %w", parsed.runSQL, end-start, err)
+		}
+
+		rowsAffected, _ := chunkResult.RowsAffected()
+		result.rowsAffected += rowsAffected
+		result.lastInsertID, _ = chunkResult.LastInsertId()
+	}
+
+	if owned {
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("failed to commit error %w", err)
+		}
+	}
+
+	LogSqlResult(result)
+	return convertBatchResult(result, outTypes)
+}
+
+func convertBatchResult(result batchResult, outTypes []reflect.Type) ([]reflect.Value, error) {
+	results := make([]reflect.Value, 0, len(outTypes))
+	for i, outType := range outTypes {
+		switch i {
+		case 0:
+			results = append(results, reflect.ValueOf(result.rowsAffected).Convert(outType))
+		case 1:
+			results = append(results, reflect.ValueOf(result.lastInsertID).Convert(outType))
+		default:
+			results = append(results, reflect.Zero(outType))
+		}
+	}
+
+	return results, nil
+}
@@ -0,0 +1,85 @@
+package sqx_test
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/bingoohuang/gg/pkg/sqx"
+	"github.com/stretchr/testify/assert"
+)
+
+type personBatchDao struct {
+	CreateTable func()                        `sql:"create table person(id varchar(100), age int)"`
+	AddBatch    func([]person) (int64, error) `sql:"insert into person(id, age) values(:id, :age)" batch:"true"`
+	AddBatch2   func(...person) int           `sql:"insert into person(id, age) values(:id, :age)" batch:"true"`
+	Count       func() int                    `sql:"select count(*) from person"`
+	ListAll     func() []person               `sql:"select id, age from person order by age"`
+}
+
+func TestDaoBatch(t *testing.T) {
+	rawDB, _ := openDB(t)
+
+	dao := &personBatchDao{}
+	// 5 placeholders at most, so 2 rows in each insert statement at most.
+	assert.Nil(t, sqx.CreateDao(dao, sqx.WithDB(rawDB), sqx.WithBatchMaxPlaceholders(5)))
+
+	dao.CreateTable()
+
+	var ps []person
+	for i := 0; i < 5; i++ {
+		ps = append(ps, person{ID: fmt.Sprintf("%d", i), Age: i})
+	}
+
+	affected, err := dao.AddBatch(ps)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(5), affected)
+	assert.Equal(t, 5, dao.Count())
+
+	affected, err = dao.AddBatch(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), affected)
+
+	assert.Equal(t, 2, dao.AddBatch2(person{ID: "x", Age: 10}, person{ID: "y", Age: 11}))
+	assert.Equal(t, append(ps, person{ID: "x", Age: 10}, person{ID: "y", Age: 11}), dao.ListAll())
+}
+
+type personBatchDotDao struct {
+	CreateTable func()                        `sqlName:"CreateTable"`
+	AddBatch    func([]person) (int64, error) `sqlName:"AddAll" batch:"true"`
+	Count       func() int                    `sql:"select count(*) from person"`
+}
+
+// txDBGetter is a DBGetter with the transaction in progress.
+type txDBGetter struct {
+	db *sql.DB
+	tx *sql.Tx
+}
+
+func (g txDBGetter) GetDB() *sql.DB { return g.db }
+func (g txDBGetter) GetTx() *sql.Tx { return g.tx }
+
+func TestDaoBatchDotSQLInTx(t *testing.T) {
+	rawDB, _ := openDB(t)
+	rawDB.SetMaxOpenConns(1) // every connection to :memory: is a new database
+
+	dao := &personBatchDotDao{}
+	assert.Nil(t, sqx.CreateDao(dao, sqx.WithDB(rawDB), sqx.WithSQLStr(dotSQL), sqx.WithBatchMaxPlaceholders(2)))
+	dao.CreateTable()
+
+	tx, err := rawDB.Begin()
+	assert.Nil(t, err)
+	txDao := &personBatchDotDao{}
+	assert.Nil(t, sqx.CreateDao(txDao, sqx.WithDBGetter(txDBGetter{db: rawDB, tx: tx}), sqx.WithSQLStr(dotSQL)))
+
+	affected, err := txDao.AddBatch([]person{{ID: "a", Age: 1}, {ID: "b", Age: 2}, {ID: "c", Age: 3}})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), affected)
+	assert.Nil(t, tx.Rollback())
+	assert.Equal(t, 0, dao.Count())
+
+	affected, err = dao.AddBatch([]person{{ID: "a", Age: 1}, {ID: "b", Age: 2}, {ID: "c", Age: 3}})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), affected)
+	assert.Equal(t, 3, dao.Count())
+}
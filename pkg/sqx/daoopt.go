@@ -8,6 +8,7 @@ import (
 
 	"github.com/bingoohuang/gg/pkg/defaults"
 	"github.com/bingoohuang/gg/pkg/reflector"
+	"github.com/bingoohuang/gg/pkg/sqlparse/sqllint"
 )
 
 // CreateDaoOpt defines the options for CreateDao.
@@ -20,6 +21,10 @@ type CreateDaoOpt struct {
 	Logger             DaoLogger
 	ErrSetter          func(err error)
 	DBGetter           DBGetter
+
+	BatchMaxPlaceholders int
+	// Linter lints the SQL of the dao functions, see WithLint.
+	Linter *sqllint.Linter
 }
 
 // CreateDaoOpter defines the option pattern interface for CreateDaoOpt.
@@ -65,6 +70,11 @@ func WithDB(db *sql.DB) CreateDaoOpter {
 	return CreateDaoOptFn(func(opt *CreateDaoOpt) { opt.DBGetter = &StdDB{db: db} })
 }
 
+// WithDBGetter imports a DBGetter, like a Router which implements ReadDBGetter to query on replicas.
+func WithDBGetter(g DBGetter) CreateDaoOpter {
+	return CreateDaoOptFn(func(opt *CreateDaoOpt) { opt.DBGetter = g })
+}
+
 // WithSQLStr imports SQL queries from the string.
 func WithSQLStr(s string) CreateDaoOpter {
 	return CreateDaoOptFn(func(opt *CreateDaoOpt) {
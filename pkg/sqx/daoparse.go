@@ -68,6 +68,7 @@ type SQLParsed struct {
 	Vars    []string
 	MaxSeq  int
 	IsQuery bool
+	Batch   bool
 
 	RawStmt string
 
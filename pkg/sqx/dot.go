@@ -29,6 +29,14 @@ type DotItem struct {
 	Content []string
 	Name    string
 	Attrs   map[string]string
+	// Lines is the positions of Content in the source.
+	Lines []DotLine
+}
+
+// DotLine tells the position of a content line in the source.
+type DotLine struct {
+	Num    int // 1-based line number
+	Indent int // the bytes of the leading spaces trimmed
 }
 
 var re = regexp.MustCompile(`\s*(\w+)\s*(:\s*(\S+))?`)
@@ -54,6 +62,7 @@ func ParseDotTag(line, prefix, mainTag string) (map[string]string, string) {
 // DotScanner scans the SQL statements from .sql files.
 type DotScanner struct {
 	line    string
+	lineNum int
 	queries map[string]DotItem
 	current DotItem
 }
@@ -91,15 +100,19 @@ func (s *DotScanner) appendQueryLine() {
 	}
 
 	s.current.Content = append(s.current.Content, strings.TrimSpace(line))
+	indent := len(s.line) - len(strings.TrimLeftFunc(s.line, unicode.IsSpace))
+	s.current.Lines = append(s.current.Lines, DotLine{Num: s.lineNum, Indent: indent})
 	s.queries[s.current.Name] = s.current
 }
 
 // Run runs the scanner.
 func (s *DotScanner) Run(io *bufio.Scanner) map[string]DotItem {
 	s.queries = make(map[string]DotItem)
+	s.lineNum = 0
 
 	for state := s.initialState; io.Scan(); {
 		s.line = io.Text()
+		s.lineNum++
 		state = state()
 	}
 
@@ -121,7 +121,7 @@ INSERT INTO users (?, ?, ?)
 
 	for name, query := range got {
 		if query.RawSQL() != expectedQueryMap[name] {
-			t.Errorf("QueryMap()[%s] == '%s', expected '%s'", name, query, expectedQueryMap[name])
+			t.Errorf("QueryMap()[%s] == '%s', expected '%s'", name, query.RawSQL(), expectedQueryMap[name])
 		}
 	}
 }
@@ -65,7 +65,14 @@ func (s SQL) UpdateRaw(db SqxDB) (sql.Result, error) {
 	ctx, cancel := s.prepareContext()
 	defer cancel()
 
-	result, err := db.ExecContext(ctx, s.Q, s.Vars...)
+	var result sql.Result
+	inv := &Invocation{Kind: InvokeExec, Desc: s.Name, Query: s.Q, Vars: s.Vars, Rows: -1}
+	err := intercept(ctx, inv, func(ctx context.Context) (err error) {
+		if result, err = db.ExecContext(ctx, s.Q, s.Vars...); err == nil {
+			inv.Rows, _ = result.RowsAffected()
+		}
+		return err
+	})
 	logQueryError(s.NoLog, s.Name, result, err)
 	return result, err
 }
@@ -381,9 +388,24 @@ func ScanStringRow(rows *sql.Rows, columns []string) ([]string, error) {
 
 // QueryRaw query rows for customized row scanner.
 func (s SQL) QueryRaw(db SqxDB, optionFns ...QueryOptionFn) error {
+	if err := s.adaptQuery(db); err != nil {
+		return err
+	}
+
+	inv := &Invocation{Kind: InvokeQuery, Desc: s.Name, Query: s.Q, Vars: s.Vars, Rows: -1}
+	return intercept(s.Ctx, inv, func(ctx context.Context) error {
+		s.Ctx = ctx
+		rows, err := s.queryRaw(db, optionFns...)
+		inv.Rows = int64(rows)
+		return err
+	})
+}
+
+// queryRaw queries the adapted query and returns the rows scanned.
+func (s SQL) queryRaw(db SqxDB, optionFns ...QueryOptionFn) (int, error) {
 	option, r, columns, err := s.prepareQuery(db, optionFns...)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	defer r.Close()
@@ -396,17 +418,17 @@ func (s SQL) QueryRaw(db SqxDB, optionFns ...QueryOptionFn) error {
 	for rn := 0; r.Next() && option.allowRowNum(rn+1); rn++ {
 		rows++
 		if continued, err := option.Scanner.ScanRow(columns, r, rn); err != nil {
-			return err
+			return rows, err
 		} else if !continued {
 			break
 		}
 	}
 
 	if rows == 0 {
-		return sql.ErrNoRows
+		return 0, sql.ErrNoRows
 	}
 
-	return nil
+	return rows, nil
 }
 
 func ScanRowValues(rows *sql.Rows) ([]interface{}, error) {
@@ -455,10 +477,6 @@ func (s SQL) prepareContext() (ctx context.Context, cancel func()) {
 }
 
 func (s *SQL) prepareQuery(db SqxDB, optionFns ...QueryOptionFn) (*QueryOption, *sql.Rows, []string, error) {
-	if err := s.adaptQuery(db); err != nil {
-		return nil, nil, nil, err
-	}
-
 	ctx, cancel := s.prepareContext()
 	defer cancel()
 	ctx = context.WithValue(ctx, AdaptedKey, s.adapted)
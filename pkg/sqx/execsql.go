@@ -53,22 +53,40 @@ func (o ExecOption) reachMaxRows(row int) bool {
 	return o.MaxRows > 0 && row >= o.MaxRows
 }
 
-// Exec executes a SQL.
-func Exec(db SQLExec, query string, option ExecOption) Result {
+// Exec executes a SQL in the interceptor chain.
+func Exec(db SQLExec, query string, option ExecOption) (r Result) {
 	firstKey, isQuerySQL := IsQuerySQL(query)
+	inv := &Invocation{Kind: InvokeExec, Query: query, Rows: -1}
 	if isQuerySQL {
-		return processQuery(db, query, firstKey, option)
+		inv.Kind = InvokeQuery
 	}
 
-	return execNonQuery(db, query, firstKey)
+	_ = intercept(context.Background(), inv, func(ctx context.Context) error {
+		if isQuerySQL {
+			r = processQuery(ctx, db, query, firstKey, option)
+			inv.Rows = int64(len(r.Rows))
+		} else {
+			r = execNonQuery(ctx, db, query, firstKey)
+			inv.Rows = r.RowsAffected
+		}
+		return r.Error
+	})
+
+	return r
 }
 
-func processQuery(db SQLExec, query string, firstKey string, option ExecOption) (r Result) {
+func processQuery(ctx context.Context, db SQLExec, query string, firstKey string, option ExecOption) (r Result) {
 	start := time.Now()
 	r.FirstKey = firstKey
 	r.IsQuerySQL = true
 
-	rows, err := db.Query(query)
+	var rows *sql.Rows
+	var err error
+	if dc, ok := db.(SQLExecContext); ok { // with the intercepted ctx, not to be intercepted again by Sqx
+		rows, err = dc.QueryContext(ctx, query)
+	} else {
+		rows, err = db.Query(query)
+	}
 	if err != nil || rows != nil && rows.Err() != nil {
 		if err == nil {
 			err = rows.Err()
@@ -127,9 +145,15 @@ func processQuery(db SQLExec, query string, firstKey string, option ExecOption)
 	return r.Return(start, nil)
 }
 
-func execNonQuery(db SQLExec, query string, firstKey string) Result {
+func execNonQuery(ctx context.Context, db SQLExec, query string, firstKey string) Result {
 	start := time.Now()
-	r, err := db.Exec(query)
+	var r sql.Result
+	var err error
+	if dc, ok := db.(SQLExecContext); ok {
+		r, err = dc.ExecContext(ctx, query)
+	} else {
+		r, err = db.Exec(query)
+	}
 
 	var affected int64
 	if r != nil {
@@ -0,0 +1,265 @@
+package sqx
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// InvocationKind is the kind of the sql invocation.
+type InvocationKind int
+
+const (
+	// InvokeQuery is the query invocation.
+	InvokeQuery InvocationKind = iota
+	// InvokeExec is the exec invocation, like insert/update/delete.
+	InvokeExec
+	// InvokeTx is the whole transaction invocation, from begin to commit or rollback.
+	InvokeTx
+)
+
+func (k InvocationKind) String() string {
+	switch k {
+	case InvokeQuery:
+		return "query"
+	case InvokeExec:
+		return "exec"
+	case InvokeTx:
+		return "tx"
+	default:
+		return "unknown"
+	}
+}
+
+// Invocation is the information of a sql invocation for the interceptors.
+// Duration, Rows and Err are available after the next invoker returns.
+type Invocation struct {
+	Kind InvocationKind
+	// Desc is the caller's desc, like the SQL.Name, QueryArgs.Desc or the dao func name.
+	Desc  string
+	Query string
+	// Vars are the bound vars, only for viewing, modifying them does not change the executed ones.
+	Vars []interface{}
+
+	Start    time.Time
+	Duration time.Duration
+	// Rows is the rows affected of exec, or the rows read of query, -1 when unknown.
+	Rows int64
+	Err  error
+}
+
+// Invoker invokes the next interceptor or the sql execution itself.
+type Invoker func(ctx context.Context) error
+
+// Interceptor intercepts every Query/Exec/Tx in sqx, including the CreateDao functions.
+type Interceptor func(ctx context.Context, inv *Invocation, next Invoker) error
+
+var (
+	interceptorsLock sync.RWMutex
+	interceptors     []Interceptor
+)
+
+// AddInterceptors adds interceptors to the global chain, the first added one is the outermost.
+func AddInterceptors(v ...Interceptor) {
+	interceptorsLock.Lock()
+	defer interceptorsLock.Unlock()
+
+	interceptors = append(interceptors, v...)
+}
+
+// ClearInterceptors removes all the interceptors in the global chain.
+func ClearInterceptors() {
+	interceptorsLock.Lock()
+	defer interceptorsLock.Unlock()
+
+	interceptors = nil
+}
+
+type interceptedKey struct{}
+
+// intercept runs fn in the interceptor chain.
+// The nested invocations, like Sqx.QueryContext called by SQL.Query, are not intercepted again.
+func intercept(ctx context.Context, inv *Invocation, fn Invoker) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	interceptorsLock.RLock()
+	chain := interceptors
+	interceptorsLock.RUnlock()
+
+	if len(chain) == 0 || ctx.Value(interceptedKey{}) != nil {
+		return fn(ctx)
+	}
+
+	var next Invoker = func(ctx context.Context) error {
+		inv.Err = fn(ctx)
+		inv.Duration = time.Since(inv.Start)
+		return inv.Err
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		interceptor, invoker := chain[i], next
+		next = func(ctx context.Context) error { return interceptor(ctx, inv, invoker) }
+	}
+
+	inv.Start = time.Now()
+	return next(context.WithValue(ctx, interceptedKey{}, true))
+}
+
+// SlowQuery creates an interceptor which logs the invocations costing more than threshold,
+// by logFn or the standard log when logFn is nil.
+func SlowQuery(threshold time.Duration, logFn func(inv *Invocation)) Interceptor {
+	if logFn == nil {
+		logFn = func(inv *Invocation) {
+			log.Printf("W! %sslow %s [%s] with args %v cost %s, rows: %d, error: %v",
+				quoteDesc(inv.Desc), inv.Kind, inv.Query, inv.Vars, inv.Duration, inv.Rows, inv.Err)
+		}
+	}
+
+	return func(ctx context.Context, inv *Invocation, next Invoker) error {
+		err := next(ctx)
+		if inv.Duration >= threshold {
+			logFn(inv)
+		}
+		return err
+	}
+}
+
+// RedactVars creates an interceptor which replaces the matched vars with *** for the inner interceptors,
+// so it should be added before the logging ones. The executed vars are not changed,
+// and the outer interceptors see the original vars again after the inner ones return.
+func RedactVars(match func(inv *Invocation, index int, v interface{}) bool) Interceptor {
+	return func(ctx context.Context, inv *Invocation, next Invoker) error {
+		vars := make([]interface{}, len(inv.Vars))
+		for i, v := range inv.Vars {
+			if match(inv, i, v) {
+				vars[i] = "***"
+			} else {
+				vars[i] = v
+			}
+		}
+
+		original := inv.Vars
+		inv.Vars = vars
+		defer func() { inv.Vars = original }()
+		return next(ctx)
+	}
+}
+
+// DefaultLatencyBuckets is the default upper bounds of the latency histogram buckets.
+var DefaultLatencyBuckets = []time.Duration{
+	time.Millisecond, 5 * time.Millisecond, 10 * time.Millisecond, 50 * time.Millisecond,
+	100 * time.Millisecond, 500 * time.Millisecond, time.Second, 5 * time.Second,
+}
+
+// LatencyStat is the latency statistics of a statement.
+type LatencyStat struct {
+	Count  int64         `json:"count"`
+	Errors int64         `json:"errors"`
+	Total  time.Duration `json:"total"`
+	Max    time.Duration `json:"max"`
+	// Buckets are the counts of the latencies <= the bucket bounds, and the last one for the overflows.
+	Buckets []int64 `json:"buckets"`
+}
+
+// DefaultLatencyMaxKeys is the default max number of the statement keys in LatencyMetrics.
+const DefaultLatencyMaxKeys = 1000
+
+// LatencyOthersKey is the statement key of the latencies beyond the max number of keys.
+const LatencyOthersKey = "(others)"
+
+// LatencyMetrics collects the per-statement latency histograms,
+// which implements expvar.Var, like expvar.Publish("sqx", sqx.NewLatencyMetrics()).
+type LatencyMetrics struct {
+	Buckets []time.Duration
+	// MaxKeys is the max number of the statement keys, the latencies of the new keys beyond it
+	// are recorded under LatencyOthersKey, to bound the keys of the raw queries without desc.
+	MaxKeys int
+
+	lock  sync.Mutex
+	stats map[string]*LatencyStat
+}
+
+// NewLatencyMetrics creates a LatencyMetrics with the bucket bounds, DefaultLatencyBuckets when empty.
+func NewLatencyMetrics(buckets ...time.Duration) *LatencyMetrics {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+
+	sorted := append([]time.Duration(nil), buckets...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return &LatencyMetrics{Buckets: sorted, MaxKeys: DefaultLatencyMaxKeys, stats: make(map[string]*LatencyStat)}
+}
+
+// Interceptor returns the interceptor which records the latencies by the desc, or the query when desc is empty,
+// so give the desc, like SQL.Name, to the statements with the literal values inlined.
+func (m *LatencyMetrics) Interceptor() Interceptor {
+	return func(ctx context.Context, inv *Invocation, next Invoker) error {
+		err := next(ctx)
+		key := inv.Desc
+		if key == "" {
+			key = inv.Query
+		}
+		m.Observe(key, inv.Duration, err)
+		return err
+	}
+}
+
+// Observe records a latency of the statement key.
+func (m *LatencyMetrics) Observe(key string, d time.Duration, err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	stat, ok := m.stats[key]
+	if !ok && m.MaxKeys > 0 && len(m.stats) >= m.MaxKeys {
+		key = LatencyOthersKey
+		stat, ok = m.stats[key]
+	}
+	if !ok {
+		stat = &LatencyStat{Buckets: make([]int64, len(m.Buckets)+1)}
+		m.stats[key] = stat
+	}
+
+	stat.Count++
+	stat.Total += d
+	if d > stat.Max {
+		stat.Max = d
+	}
+	if err != nil {
+		stat.Errors++
+	}
+
+	stat.Buckets[sort.Search(len(m.Buckets), func(i int) bool { return d <= m.Buckets[i] })]++
+}
+
+// Snapshot returns a copy of the statistics by the statement keys.
+func (m *LatencyMetrics) Snapshot() map[string]LatencyStat {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	snapshot := make(map[string]LatencyStat, len(m.stats))
+	for k, v := range m.stats {
+		stat := *v
+		stat.Buckets = append([]int64(nil), v.Buckets...)
+		snapshot[k] = stat
+	}
+
+	return snapshot
+}
+
+// String returns the JSON of the statistics for expvar.
+func (m *LatencyMetrics) String() string {
+	bounds := make([]string, len(m.Buckets))
+	for i, b := range m.Buckets {
+		bounds[i] = b.String()
+	}
+
+	j, _ := json.Marshal(struct {
+		Buckets []string               `json:"buckets"`
+		Stats   map[string]LatencyStat `json:"stats"`
+	}{Buckets: bounds, Stats: m.Snapshot()})
+	return string(j)
+}
@@ -0,0 +1,175 @@
+package sqx_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/bingoohuang/gg/pkg/sqx"
+	"github.com/stretchr/testify/assert"
+)
+
+type interceptorDao struct {
+	Age func(string) int `sql:"select age from person where id = :1"`
+}
+
+func TestInterceptors(t *testing.T) {
+	rawDB, db := openDB(t)
+	defer db.Close()
+
+	var invs []sqx.Invocation
+	metrics := sqx.NewLatencyMetrics()
+	sqx.AddInterceptors(
+		sqx.RedactVars(func(_ *sqx.Invocation, _ int, v interface{}) bool { return v == "secret" }),
+		metrics.Interceptor(),
+		sqx.SlowQuery(0, func(inv *sqx.Invocation) { invs = append(invs, *inv) }),
+	)
+	defer sqx.ClearInterceptors()
+
+	_, err := sqx.NewSQL("create table person(id varchar(100), age int)").Update(db)
+	assert.Nil(t, err)
+	_, err = sqx.SQL{Name: "addPerson", Q: "insert into person(id, age) values(?, ?)", Vars: sqx.Vars("secret", 10)}.Update(db)
+	assert.Nil(t, err)
+	age, err := sqx.SQL{Name: "getAge", Q: "select age from person where id = ?", Vars: sqx.Vars("secret")}.QueryAsNumber(db)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(10), age)
+
+	err = db.Tx(func(tx *sqx.Sqx) error {
+		_, err := tx.ExecContext(context.Background(), "update person set age = ?", 20)
+		return err
+	})
+	assert.Nil(t, err)
+
+	dao := &interceptorDao{}
+	assert.Nil(t, sqx.CreateDao(dao, sqx.WithDB(rawDB)))
+	assert.Equal(t, 20, dao.Age("secret"))
+
+	assert.Len(t, invs, 6)
+	assert.Equal(t, sqx.InvokeExec, invs[1].Kind)
+	assert.Equal(t, "addPerson", invs[1].Desc)
+	assert.Equal(t, []interface{}{"***", 10}, invs[1].Vars)
+	assert.Equal(t, int64(1), invs[1].Rows)
+
+	assert.Equal(t, sqx.InvokeQuery, invs[2].Kind)
+	assert.Equal(t, "getAge", invs[2].Desc)
+	assert.Equal(t, int64(1), invs[2].Rows)
+
+	// the statement inside the transaction is intercepted before the whole transaction
+	assert.Equal(t, sqx.InvokeExec, invs[3].Kind)
+	assert.Equal(t, sqx.InvokeTx, invs[4].Kind)
+	assert.True(t, invs[4].Duration >= invs[3].Duration)
+
+	assert.Equal(t, sqx.InvokeQuery, invs[5].Kind)
+	assert.Equal(t, "Age", invs[5].Desc)
+	assert.Equal(t, []interface{}{"***"}, invs[5].Vars)
+
+	stats := metrics.Snapshot()
+	assert.Equal(t, int64(1), stats["getAge"].Count)
+	assert.Equal(t, int64(1), stats["Age"].Count)
+
+	var exported struct {
+		Buckets []string
+		Stats   map[string]sqx.LatencyStat
+	}
+	assert.Nil(t, json.Unmarshal([]byte(metrics.String()), &exported))
+	assert.Equal(t, len(sqx.DefaultLatencyBuckets), len(exported.Buckets))
+	assert.Equal(t, len(sqx.DefaultLatencyBuckets)+1, len(exported.Stats["getAge"].Buckets))
+}
+
+func TestLatencyMetrics(t *testing.T) {
+	m := sqx.NewLatencyMetrics(10*time.Millisecond, time.Millisecond)
+	m.Observe("q", 500*time.Microsecond, nil)
+	m.Observe("q", 5*time.Millisecond, nil)
+	m.Observe("q", time.Second, context.Canceled)
+
+	stat := m.Snapshot()["q"]
+	assert.Equal(t, int64(3), stat.Count)
+	assert.Equal(t, int64(1), stat.Errors)
+	assert.Equal(t, time.Second, stat.Max)
+	assert.Equal(t, []int64{1, 1, 1}, stat.Buckets)
+}
+
+func TestInterceptorsExecSQL(t *testing.T) {
+	rawDB, db := openDB(t)
+	defer db.Close()
+
+	var outer [][]interface{}
+	var invs []sqx.Invocation
+	sqx.AddInterceptors(
+		func(ctx context.Context, inv *sqx.Invocation, next sqx.Invoker) error {
+			err := next(ctx)
+			outer = append(outer, inv.Vars)
+			return err
+		},
+		sqx.RedactVars(func(_ *sqx.Invocation, _ int, v interface{}) bool { return v == "secret" }),
+		sqx.SlowQuery(0, func(inv *sqx.Invocation) { invs = append(invs, *inv) }),
+	)
+	defer sqx.ClearInterceptors()
+
+	// the raw db and the Sqx are intercepted once
+	r := sqx.Exec(rawDB, "create table person(id varchar(100), age int)", sqx.ExecOption{})
+	assert.Nil(t, r.Error)
+	r = sqx.Exec(db, "insert into person(id, age) values('a', 10), ('b', 20)", sqx.ExecOption{})
+	assert.Nil(t, r.Error)
+	r = sqx.Exec(rawDB, "select id from person", sqx.ExecOption{})
+	assert.Nil(t, r.Error)
+
+	assert.Len(t, invs, 3)
+	assert.Equal(t, sqx.InvokeExec, invs[1].Kind)
+	assert.Equal(t, int64(2), invs[1].Rows)
+	assert.Equal(t, sqx.InvokeQuery, invs[2].Kind)
+	assert.Equal(t, int64(2), invs[2].Rows)
+
+	// the outer interceptor sees the original vars
+	_, err := sqx.SQL{Q: "select count(*) from person where id = ?", Vars: sqx.Vars("secret")}.QueryAsNumber(db)
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"***"}, invs[3].Vars)
+	assert.Equal(t, []interface{}{"secret"}, outer[3])
+}
+
+func TestLatencyMetricsMaxKeys(t *testing.T) {
+	m := sqx.NewLatencyMetrics()
+	m.MaxKeys = 2
+	m.Observe("a", time.Millisecond, nil)
+	m.Observe("b", time.Millisecond, nil)
+	m.Observe("c", time.Millisecond, nil)
+	m.Observe("d", time.Millisecond, nil)
+	m.Observe("a", time.Millisecond, nil)
+
+	stats := m.Snapshot()
+	assert.Len(t, stats, 3)
+	assert.Equal(t, int64(2), stats["a"].Count)
+	assert.Equal(t, int64(2), stats[sqx.LatencyOthersKey].Count)
+}
+
+func TestInterceptorsDesc(t *testing.T) {
+	_, db := openDB(t)
+	defer db.Close()
+
+	var invs []sqx.Invocation
+	metrics := sqx.NewLatencyMetrics()
+	sqx.AddInterceptors(
+		sqx.RedactVars(func(inv *sqx.Invocation, i int, _ interface{}) bool { return inv.Desc == "login" && i == 1 }),
+		metrics.Interceptor(),
+		sqx.SlowQuery(0, func(inv *sqx.Invocation) { invs = append(invs, *inv) }),
+	)
+	defer sqx.ClearInterceptors()
+
+	_, err := sqx.NewSQL("create table users(name varchar(100), password varchar(100))").Update(db)
+	assert.Nil(t, err)
+	_, err = db.DoExec(&sqx.QueryArgs{Desc: "register", Query: "insert into users(name, password) values(?, ?)",
+		Args: sqx.Vars("bingoo", "secret")})
+	assert.Nil(t, err)
+
+	var names []string
+	assert.Nil(t, db.SelectDesc("login", &names, "select name from users where name = ? and password = ?", "bingoo", "secret"))
+	assert.Equal(t, []string{"bingoo"}, names)
+
+	assert.Len(t, invs, 3)
+	assert.Equal(t, "register", invs[1].Desc)
+	assert.Equal(t, []interface{}{"bingoo", "secret"}, invs[1].Vars)
+	assert.Equal(t, "login", invs[2].Desc)
+	assert.Equal(t, []interface{}{"bingoo", "***"}, invs[2].Vars)
+	assert.Equal(t, int64(1), metrics.Snapshot()["login"].Count)
+}
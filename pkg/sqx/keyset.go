@@ -0,0 +1,79 @@
+package sqx
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/bingoohuang/gg/pkg/mapstruct"
+	"github.com/bingoohuang/gg/pkg/sqlparse/sqlparser"
+)
+
+// ErrKeysetDBType tells that keyset pagination requires a db which is aware of its DBType.
+var ErrKeysetDBType = errors.New("keyset paging requires a DBTypeAware db")
+
+// QueryKeyset queries a page of rows by keyset (cursor) pagination into result,
+// which should be a pointer to a slice of structs or maps.
+// The query should be a select with ORDER BY on unique columns, like order by create_time desc, id desc.
+// After querying, k.HasNext and k.NextCursor are set for fetching the next page.
+func (s SQL) QueryKeyset(db SqxDB, result interface{}, k *sqlparser.Keyset, optionFns ...QueryOptionFn) error {
+	if _, ok := db.(DBTypeAware); !ok {
+		return ErrKeysetDBType
+	}
+
+	k.HasNext, k.NextCursor = false, ""
+	s.ConvertOptions = append(s.ConvertOptions, sqlparser.WithKeyset(k))
+	option := QueryOptionFns(optionFns).Options()
+	scanner := &keysetScanner{Keyset: k}
+	if err := s.QueryRaw(db, WithOptions(option), WithRowScanner(scanner)); err != nil {
+		return err
+	}
+
+	if k.HasNext {
+		if err := k.NextCursorOf(scanner.columns, scanner.last); err != nil {
+			return err
+		}
+	}
+
+	decoder, err := mapstruct.NewDecoder(&mapstruct.Config{
+		Result:   result,
+		TagNames: option.TagNames,
+		Squash:   true,
+		WeakType: true,
+	})
+	if err != nil {
+		return err
+	}
+
+	return decoder.Decode(scanner.Data)
+}
+
+// keysetScanner scans at most PageSize rows, and keeps the raw values of the last row for the next cursor.
+type keysetScanner struct {
+	*sqlparser.Keyset
+	Data    []map[string]string
+	columns []string
+	last    []interface{}
+}
+
+func (s *keysetScanner) ScanRow(columns []string, rows *sql.Rows, _ int) (bool, error) {
+	if len(s.Data) >= s.PageSize {
+		s.HasNext = true
+		return false, nil
+	}
+
+	holders, err := ScanRow(len(columns), rows)
+	if err != nil {
+		return false, err
+	}
+
+	m := make(map[string]string)
+	last := make([]interface{}, len(columns))
+	for i, h := range holders {
+		m[columns[i]] = h.String()
+		last[i] = h.Get()
+	}
+
+	s.Data = append(s.Data, m)
+	s.columns, s.last = columns, last
+	return true, nil
+}
@@ -0,0 +1,54 @@
+package sqx_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/bingoohuang/gg/pkg/sqlparse/sqlparser"
+	"github.com/bingoohuang/gg/pkg/sqx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryKeyset(t *testing.T) {
+	_, db := openDB(t)
+
+	_, err := sqx.NewSQL("create table person(id varchar(100), age int)").Update(db)
+	assert.Nil(t, err)
+	for i := 1; i <= 5; i++ {
+		_, err = sqx.NewSQL("insert into person(id, age) values(?, ?)", fmt.Sprintf("p%d", i), 10*(i%3)).Update(db)
+		assert.Nil(t, err)
+	}
+
+	type Person struct {
+		ID  string
+		Age int
+	}
+
+	s := sqx.SQL{Q: "select id, age from person where age >= ? order by age desc, id", Vars: []interface{}{0}}
+	k := &sqlparser.Keyset{PageSize: 2}
+
+	var pages [][]Person
+	for {
+		var ps []Person
+		assert.Nil(t, s.QueryKeyset(db, &ps, k))
+		pages = append(pages, ps)
+		if !k.HasNext {
+			break
+		}
+
+		k.Cursor = k.NextCursor
+	}
+
+	assert.Equal(t, [][]Person{
+		{{ID: "p2", Age: 20}, {ID: "p5", Age: 20}},
+		{{ID: "p1", Age: 10}, {ID: "p4", Age: 10}},
+		{{ID: "p3", Age: 0}},
+	}, pages)
+	assert.Equal(t, "", k.NextCursor)
+
+	c, err := s.CreateCount()
+	assert.Nil(t, err)
+	count, err := c.QueryAsNumber(db)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(5), count)
+}
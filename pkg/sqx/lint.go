@@ -0,0 +1,126 @@
+package sqx
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/bingoohuang/gg/pkg/sqlparse/sqllint"
+)
+
+// DotFinding is a finding of the linter in the dot SQL.
+type DotFinding struct {
+	sqllint.Finding
+	// Name is the name of the dot SQL.
+	Name string
+}
+
+func (f DotFinding) String() string { return f.Name + ":" + f.Finding.String() }
+
+// Lint lints the SQL statements with the linter,
+// the positions of the findings are in the source when the DotSQL is loaded from a file or a string.
+func (d DotSQL) Lint(linter *sqllint.Linter) []DotFinding {
+	var findings []DotFinding
+	for name, item := range d.Dots {
+		for _, f := range item.lint(linter) {
+			findings = append(findings, DotFinding{Finding: f, Name: name})
+		}
+	}
+
+	sort.Slice(findings, func(i, j int) bool {
+		a, b := findings[i].Pos, findings[j].Pos
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return findings
+}
+
+// LintDotSQLFile lints the SQL statements in the file loaded by DotSQLLoadFile, which is used in CI usually.
+func LintDotSQLFile(sqlFile string, options ...sqllint.Option) ([]DotFinding, error) {
+	ds, err := DotSQLLoadFile(sqlFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return ds.Lint(sqllint.New(options...)), nil
+}
+
+// lint lints the raw SQL of the item, and maps the positions of the findings to the source.
+func (d DotItem) lint(linter *sqllint.Linter) sqllint.Findings {
+	dynamic := d.isDynamic()
+	var findings sqllint.Findings
+	for _, f := range linter.Lint(d.RawSQL()) {
+		// the raw SQL of the dynamic one contains all the branches, which may be not a valid SQL.
+		if dynamic && f.Rule == sqllint.RuleSyntax {
+			continue
+		}
+		if i := f.Pos.Line - 1; i < len(d.Lines) {
+			f.Pos.Column += d.Lines[i].Indent
+			f.Pos.Line = d.Lines[i].Num
+		}
+		findings = append(findings, f)
+	}
+	return findings
+}
+
+// isDynamic tells whether the item has the dynamic parts like -- if ... -- end.
+func (d DotItem) isDynamic() bool {
+	for _, l := range ConvertSQLLines(d.Content) {
+		if strings.HasPrefix(l, "--") {
+			commentLine := strings.TrimSpace(l[2:])
+			if CreateParser(firstWord(commentLine, 1), "") != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// WithLint lints the SQL of the dao functions when creating the dao,
+// the findings of the error severity fail the creation, and the others are logged.
+// The dynamic SQL like -- if ... -- end is skipped, which can be linted by LintDotSQLFile.
+func WithLint(options ...sqllint.Option) CreateDaoOpter {
+	return CreateDaoOptFn(func(opt *CreateDaoOpt) { opt.Linter = sqllint.New(options...) })
+}
+
+func (option *CreateDaoOpt) lint(sqlName string, part SQLPart) error {
+	if isDynamicPart(part) {
+		return nil
+	}
+
+	item := DotItem{Name: sqlName, Content: strings.Split(part.Raw(), "\n")}
+	var errs []string
+	for _, f := range item.lint(option.Linter) {
+		if f.Severity >= sqllint.Error {
+			errs = append(errs, f.String())
+		} else {
+			log.Printf("W! lint %s: %s", sqlName, f)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("lint %s: %s", sqlName, strings.Join(errs, "; ")) // nolint:goerr113
+	}
+	return nil
+}
+
+func isDynamicPart(part SQLPart) bool {
+	switch p := part.(type) {
+	case *PostProcessingSQLPart:
+		return isDynamicPart(p.Part)
+	case *MultiPart:
+		for _, sub := range p.Parts {
+			if isDynamicPart(sub) {
+				return true
+			}
+		}
+		return false
+	case *LiteralPart:
+		return false
+	default:
+		return true
+	}
+}
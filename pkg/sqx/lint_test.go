@@ -0,0 +1,40 @@
+package sqx_test
+
+import (
+	"testing"
+
+	"github.com/bingoohuang/gg/pkg/sqlparse/sqllint"
+	"github.com/bingoohuang/gg/pkg/sqx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLintDotSQLFile(t *testing.T) {
+	findings, err := sqx.LintDotSQLFile("testdata/lint.sql")
+	assert.Nil(t, err)
+
+	var s []string
+	for _, f := range findings {
+		s = append(s, f.String())
+	}
+	assert.Equal(t, []string{
+		"ListAll:2:1: warning: select without where and limit reads all the rows [missing-limit]",
+		"ListAll:2:8: warning: select * depends on the columns order of the table [select-star]",
+		"ClearAll:5:3: error: delete without where removes all the rows [delete-without-where]",
+		"FindByName:12:12: warning: like '%x' with the leading wildcard can not use the index [leading-wildcard-like]",
+	}, s)
+}
+
+type lintDao struct {
+	ListAll  func() []person
+	ClearAll func()
+}
+
+func TestCreateDaoWithLint(t *testing.T) {
+	dao := &lintDao{}
+	err := sqx.CreateDao(dao, sqx.WithSQLFile("testdata/lint.sql"), sqx.WithLint())
+	assert.EqualError(t, err, "lint ClearAll: 1:1: error: delete without where removes all the rows [delete-without-where]")
+
+	err = sqx.CreateDao(dao, sqx.WithSQLFile("testdata/lint.sql"),
+		sqx.WithLint(sqllint.WithSeverity(sqllint.RuleDeleteWithoutWhere, sqllint.Warning)))
+	assert.Nil(t, err)
+}
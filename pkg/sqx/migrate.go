@@ -0,0 +1,472 @@
+package sqx
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/bingoohuang/gg/pkg/reflector"
+	"github.com/bingoohuang/gg/pkg/sqlparse/sqlparser"
+	"github.com/bingoohuang/gg/pkg/ss"
+	"github.com/bingoohuang/gg/pkg/strcase"
+)
+
+// TableNamer tells the table name of a struct, or the snake case of the struct name is used.
+type TableNamer interface {
+	TableName() string
+}
+
+// Migrator works out the DDL statements to create or evolve tables from the structs tagged by col and ddl.
+//
+//	type Person struct {
+//		ID   int64  `col:"id" ddl:"pk,autoincr"`
+//		Name string `ddl:"size=100,notnull,index"`   // index named idx_person_name
+//		Code string `ddl:"unique=uk_person_code"`   // unique index with the specified name
+//		Addr string `ddl:"type=text,default='-'"`   // specified column type and default value
+//		Nooo string `col:"-"`                       // ignored
+//	}
+//
+// Only the missing tables, columns and indexes are created, nothing will be dropped or modified.
+type Migrator struct {
+	DB     SqxDB
+	DBType sqlparser.DBType
+	// DryRun prints the statements to Out instead of executing them.
+	DryRun bool
+	Out    io.Writer
+}
+
+// NewMigrator creates a Migrator on the Sqx.
+func NewMigrator(s *Sqx) *Migrator {
+	return &Migrator{DB: s.DB, DBType: s.DBType, Out: os.Stdout}
+}
+
+// ErrMigrateBean tells that the bean to be migrated should be a struct or its pointer.
+var ErrMigrateBean = errors.New("migrate bean should be struct or its pointer")
+
+// Migrate creates or evolves the tables of the beans.
+func (m *Migrator) Migrate(beans ...interface{}) error {
+	stmts, err := m.Plan(beans...)
+	if err != nil {
+		return err
+	}
+
+	for _, stmt := range stmts {
+		if m.DryRun {
+			out := m.Out
+			if out == nil {
+				out = os.Stdout
+			}
+			_, _ = fmt.Fprintf(out, "%s;\n", stmt)
+			continue
+		}
+
+		logQuery("migrate", stmt, nil)
+		if _, err := m.rawDB().Exec(stmt); err != nil {
+			return fmt.Errorf("migrate %s error %w", stmt, err)
+		}
+	}
+
+	return nil
+}
+
+// Plan works out the DDL statements to migrate the tables of the beans, by diffing with the current schema.
+func (m *Migrator) Plan(beans ...interface{}) ([]string, error) {
+	var stmts []string
+	for _, bean := range beans {
+		table, err := parseTableSchema(bean)
+		if err != nil {
+			return nil, err
+		}
+
+		columns, indexes, err := m.currentSchema(table.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(columns) == 0 {
+			stmts = append(stmts, m.createTable(table))
+		} else {
+			for _, c := range table.Columns {
+				if !columns[strings.ToLower(c.Name)] {
+					stmts = append(stmts, m.addColumn(table.Name, c))
+				}
+			}
+		}
+
+		for _, idx := range table.Indexes {
+			if !indexes[strings.ToLower(idx.Name)] {
+				stmts = append(stmts, createIndex(table.Name, idx))
+			}
+		}
+	}
+
+	return stmts, nil
+}
+
+type tableSchema struct {
+	Name    string
+	Columns []columnSchema
+	Indexes []indexSchema
+}
+
+type columnSchema struct {
+	Name     string
+	Type     reflect.Type
+	SQLType  string
+	Size     int
+	Default  string
+	PK       bool
+	AutoIncr bool
+	NotNull  bool
+}
+
+type indexSchema struct {
+	Name    string
+	Columns []string
+	Unique  bool
+}
+
+var identifierReg = regexp.MustCompile(`^\w+$`)
+
+func parseTableSchema(bean interface{}) (*tableSchema, error) {
+	v := reflect.ValueOf(bean)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct {
+		return nil, ErrMigrateBean
+	}
+
+	t := &tableSchema{Name: strcase.ToSnake(v.Type().Name())}
+	if namer, ok := bean.(TableNamer); ok {
+		t.Name = namer.TableName()
+	}
+
+	if !identifierReg.MatchString(t.Name) {
+		return nil, fmt.Errorf("bad table name %q", t.Name)
+	}
+
+	if err := t.parseFields(v.Type()); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (t *tableSchema) parseFields(st reflect.Type) error {
+	for i := 0; i < st.NumField(); i++ {
+		f := st.Field(i)
+		col := f.Tag.Get("col")
+		if col == "-" {
+			continue
+		}
+
+		// the exported fields of the embedded struct are promoted, even if the struct type is unexported.
+		if f.Anonymous && f.Type.Kind() == reflect.Struct && f.Type != reflector.TimeType {
+			if err := t.parseFields(f.Type); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if f.PkgPath != "" { // not exported
+			continue
+		}
+
+		c := columnSchema{Name: col, Type: f.Type}
+		if c.Name == "" {
+			c.Name = strcase.ToSnake(f.Name)
+		}
+		if !identifierReg.MatchString(c.Name) {
+			return fmt.Errorf("bad column name %q of field %s", c.Name, f.Name)
+		}
+
+		if err := t.parseDDLTag(&c, f.Tag.Get("ddl")); err != nil {
+			return fmt.Errorf("bad ddl tag of field %s: %w", f.Name, err)
+		}
+
+		t.Columns = append(t.Columns, c)
+	}
+
+	return nil
+}
+
+// parseDDLTag parses the ddl tag like pk,autoincr,size=100,notnull,index,unique=uk_name,type=text,default=0.
+func (t *tableSchema) parseDDLTag(c *columnSchema, tag string) error {
+	for _, item := range strings.Split(tag, ",") {
+		k, v := strings.TrimSpace(item), ""
+		if p := strings.Index(k, "="); p >= 0 {
+			k, v = strings.TrimSpace(k[:p]), strings.TrimSpace(k[p+1:])
+		}
+
+		switch k {
+		case "":
+		case "pk":
+			c.PK, c.NotNull = true, true
+		case "autoincr":
+			c.AutoIncr = true
+		case "notnull":
+			c.NotNull = true
+		case "size":
+			size, err := strconv.Atoi(v)
+			if err != nil {
+				return err
+			}
+			c.Size = size
+		case "type":
+			c.SQLType = v
+		case "default":
+			c.Default = v
+		case "index", "unique":
+			t.addIndex(c.Name, v, k == "unique")
+		default:
+			return fmt.Errorf("unknown ddl tag item %q", item)
+		}
+	}
+
+	return nil
+}
+
+// addIndex adds the column to the index, the index with the same name will be a composite one.
+func (t *tableSchema) addIndex(column, name string, unique bool) {
+	if name == "" {
+		name = ss.If(unique, "uk_", "idx_") + t.Name + "_" + column
+	}
+
+	for i, idx := range t.Indexes {
+		if idx.Name == name {
+			t.Indexes[i].Columns = append(t.Indexes[i].Columns, column)
+			return
+		}
+	}
+
+	t.Indexes = append(t.Indexes, indexSchema{Name: name, Columns: []string{column}, Unique: unique})
+}
+
+func (m *Migrator) rawDB() SqxDB {
+	if s, ok := m.DB.(*Sqx); ok {
+		return s.DB
+	}
+	return m.DB
+}
+
+// currentSchema reads the lower-cased column names and index names of the table from the catalog of the db type.
+func (m *Migrator) currentSchema(table string) (columns, indexes map[string]bool, err error) {
+	var columnsQuery, indexesQuery string
+	nameIndex := 0
+
+	switch m.DBType {
+	case sqlparser.Mysql:
+		columnsQuery = `select column_name from information_schema.columns where table_schema = database() and table_name = '` + table + `'`
+		indexesQuery = `select distinct index_name from information_schema.statistics where table_schema = database() and table_name = '` + table + `'`
+	case sqlparser.Postgresql, sqlparser.Kingbase, sqlparser.Shentong:
+		columnsQuery = `select column_name from information_schema.columns where table_schema = current_schema() and table_name = '` + table + `'`
+		indexesQuery = `select indexname from pg_indexes where schemaname = current_schema() and tablename = '` + table + `'`
+	case sqlparser.Mssql:
+		columnsQuery = `select column_name from information_schema.columns where table_name = '` + table + `'`
+		indexesQuery = `select i.name from sys.indexes i join sys.tables t on i.object_id = t.object_id where t.name = '` + table + `' and i.name is not null`
+	case sqlparser.Oracle, sqlparser.Dm:
+		columnsQuery = `select column_name from user_tab_columns where table_name = upper('` + table + `')`
+		indexesQuery = `select index_name from user_indexes where table_name = upper('` + table + `')`
+	case sqlparser.Sqlite3:
+		columnsQuery = `pragma table_info('` + table + `')`
+		indexesQuery = `pragma index_list('` + table + `')`
+		nameIndex = 1
+	default:
+		return nil, nil, sqlparser.ErrUnsupportedDBType
+	}
+
+	if columns, err = m.queryNames(columnsQuery, nameIndex); err != nil {
+		return nil, nil, err
+	}
+	if indexes, err = m.queryNames(indexesQuery, nameIndex); err != nil {
+		return nil, nil, err
+	}
+
+	return columns, indexes, nil
+}
+
+func (m *Migrator) queryNames(query string, nameIndex int) (map[string]bool, error) {
+	s := &SQL{Name: "migrate", Q: query, NoLog: true}
+	rows, err := s.QueryAsRows(m.rawDB())
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("query schema %s error %w", query, err)
+	}
+
+	names := make(map[string]bool)
+	for _, row := range rows {
+		names[strings.ToLower(row[nameIndex])] = true
+	}
+
+	return names, nil
+}
+
+func (m *Migrator) createTable(t *tableSchema) string {
+	var b strings.Builder
+	b.WriteString("create table " + t.Name + " (")
+
+	var pks []string
+	for i, c := range t.Columns {
+		b.WriteString(ss.If(i == 0, "\n  ", ",\n  "))
+		b.WriteString(m.columnDefinition(c))
+		if c.PK && !(c.AutoIncr && m.DBType == sqlparser.Sqlite3) {
+			pks = append(pks, c.Name)
+		}
+	}
+
+	if len(pks) > 0 {
+		b.WriteString(",\n  primary key (" + strings.Join(pks, ", ") + ")")
+	}
+
+	b.WriteString("\n)")
+	return b.String()
+}
+
+func (m *Migrator) addColumn(table string, c columnSchema) string {
+	switch m.DBType {
+	case sqlparser.Oracle, sqlparser.Mssql:
+		return "alter table " + table + " add " + m.columnDefinition(c)
+	default:
+		return "alter table " + table + " add column " + m.columnDefinition(c)
+	}
+}
+
+func createIndex(table string, idx indexSchema) string {
+	return "create " + ss.If(idx.Unique, "unique ", "") + "index " + idx.Name +
+		" on " + table + " (" + strings.Join(idx.Columns, ", ") + ")"
+}
+
+func (m *Migrator) columnDefinition(c columnSchema) string {
+	def := c.Name + " " + m.columnType(c)
+	if c.AutoIncr {
+		switch m.DBType {
+		case sqlparser.Mysql:
+			def += " not null auto_increment"
+		case sqlparser.Sqlite3:
+			def += " primary key autoincrement"
+		case sqlparser.Oracle:
+			def += " generated by default as identity"
+		case sqlparser.Mssql, sqlparser.Dm:
+			def += " identity(1,1) not null"
+		}
+		return def // serial types of postgresql-like databases are not null already
+	}
+
+	if c.Default != "" {
+		def += " default " + c.Default
+	}
+	if c.NotNull {
+		def += " not null"
+	}
+
+	return def
+}
+
+// columnType maps the go type of the field to the column type of the db type.
+func (m *Migrator) columnType(c columnSchema) string {
+	if c.SQLType != "" {
+		return c.SQLType
+	}
+
+	t := c.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	pgLike := m.DBType == sqlparser.Postgresql || m.DBType == sqlparser.Kingbase || m.DBType == sqlparser.Shentong
+	switch t.Kind() {
+	case reflect.Bool:
+		switch m.DBType {
+		case sqlparser.Mysql:
+			return "tinyint(1)"
+		case sqlparser.Oracle:
+			return "number(1)"
+		case sqlparser.Mssql, sqlparser.Dm:
+			return "bit"
+		case sqlparser.Sqlite3:
+			return "integer"
+		default:
+			return "boolean"
+		}
+	case reflect.Int8, reflect.Int16, reflect.Uint8, reflect.Uint16:
+		return m.intType("smallint", "number(5)")
+	case reflect.Int, reflect.Int32, reflect.Uint32:
+		if c.AutoIncr && pgLike {
+			return "serial"
+		}
+		return m.intType("int", "number(10)")
+	case reflect.Int64, reflect.Uint, reflect.Uint64:
+		if c.AutoIncr && pgLike {
+			return "bigserial"
+		}
+		return m.intType("bigint", "number(19)")
+	case reflect.Float32, reflect.Float64:
+		switch m.DBType {
+		case sqlparser.Oracle:
+			return "binary_double"
+		case sqlparser.Mssql:
+			return "float"
+		case sqlparser.Sqlite3:
+			return "real"
+		case sqlparser.Mysql, sqlparser.Dm:
+			return "double"
+		default:
+			return "double precision"
+		}
+	case reflect.String:
+		size := c.Size
+		if size <= 0 {
+			size = 255
+		}
+		switch m.DBType {
+		case sqlparser.Oracle:
+			return "varchar2(" + strconv.Itoa(size) + ")"
+		case sqlparser.Mssql:
+			return "nvarchar(" + strconv.Itoa(size) + ")"
+		default:
+			return "varchar(" + strconv.Itoa(size) + ")"
+		}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			switch m.DBType {
+			case sqlparser.Mssql:
+				return "varbinary(max)"
+			case sqlparser.Postgresql, sqlparser.Kingbase, sqlparser.Shentong:
+				return "bytea"
+			default:
+				return "blob"
+			}
+		}
+	case reflect.Struct:
+		if t == reflector.TimeType || t.ConvertibleTo(reflector.TimeType) {
+			switch m.DBType {
+			case sqlparser.Mysql, sqlparser.Sqlite3:
+				return "datetime"
+			case sqlparser.Mssql:
+				return "datetime2"
+			default:
+				return "timestamp"
+			}
+		}
+	}
+
+	return ss.If(m.DBType == sqlparser.Oracle, "varchar2(255)", "varchar(255)")
+}
+
+func (m *Migrator) intType(typ, oracleType string) string {
+	switch m.DBType {
+	case sqlparser.Oracle:
+		return oracleType
+	case sqlparser.Sqlite3:
+		return "integer" // only integer primary key can be autoincrement in sqlite
+	default:
+		return typ
+	}
+}
@@ -0,0 +1,81 @@
+package sqx_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/bingoohuang/gg/pkg/sqx"
+	"github.com/stretchr/testify/assert"
+)
+
+type migrateUserV1 struct {
+	ID   int64  `col:"id" ddl:"pk,autoincr"`
+	Name string `ddl:"size=100,notnull,index"`
+}
+
+func (migrateUserV1) TableName() string { return "migrate_user" }
+
+type migrateUserV2 struct {
+	migrateUserV1
+	Code    string    `ddl:"unique=uk_migrate_user_code"`
+	Age     int       `ddl:"default=0"`
+	Created time.Time `col:"created_at"`
+	Nooo    string    `col:"-"`
+}
+
+func (migrateUserV2) TableName() string { return "migrate_user" }
+
+func TestMigrator(t *testing.T) {
+	_, db := openDB(t)
+	m := sqx.NewMigrator(db)
+
+	stmts, err := m.Plan(migrateUserV1{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"create table migrate_user (\n  id integer primary key autoincrement,\n  name varchar(100) not null\n)",
+		"create index idx_migrate_user_name on migrate_user (name)",
+	}, stmts)
+	assert.Nil(t, m.Migrate(&migrateUserV1{}))
+
+	stmts, err = m.Plan(migrateUserV1{})
+	assert.Nil(t, err)
+	assert.Empty(t, stmts)
+
+	var out bytes.Buffer
+	m.DryRun, m.Out = true, &out
+	assert.Nil(t, m.Migrate(migrateUserV2{}))
+	assert.Equal(t, `alter table migrate_user add column code varchar(255);
+alter table migrate_user add column age integer default 0;
+alter table migrate_user add column created_at datetime;
+create unique index uk_migrate_user_code on migrate_user (code);
+`, out.String())
+
+	m.DryRun = false
+	assert.Nil(t, m.Migrate(migrateUserV2{}))
+	stmts, err = m.Plan(migrateUserV2{})
+	assert.Nil(t, err)
+	assert.Empty(t, stmts)
+
+	_, err = sqx.NewSQL("insert into migrate_user(name, code, age, created_at) values(?)", "bingoo", "c1", 18, time.Now()).Update(db)
+	assert.Nil(t, err)
+}
+
+func TestMigratorEmbeddedOnEmptyDB(t *testing.T) {
+	_, db := openDB(t)
+	m := sqx.NewMigrator(db)
+
+	stmts, err := m.Plan(migrateUserV2{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"create table migrate_user (\n  id integer primary key autoincrement,\n  name varchar(100) not null,\n" +
+			"  code varchar(255),\n  age integer default 0,\n  created_at datetime\n)",
+		"create index idx_migrate_user_name on migrate_user (name)",
+		"create unique index uk_migrate_user_code on migrate_user (code)",
+	}, stmts)
+	assert.Nil(t, m.Migrate(migrateUserV2{}))
+
+	stmts, err = m.Plan(migrateUserV2{})
+	assert.Nil(t, err)
+	assert.Empty(t, stmts)
+}
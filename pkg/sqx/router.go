@@ -0,0 +1,381 @@
+package sqx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bingoohuang/gg/pkg/sqlparse/sqlparser"
+	"go.uber.org/multierr"
+)
+
+// RoutePolicy defines how to pick a replica for reading.
+type RoutePolicy int
+
+const (
+	// RoundRobin picks the healthy replicas in turn.
+	RoundRobin RoutePolicy = iota
+	// Weighted picks the healthy replicas by their weights, in smooth weighted round-robin.
+	Weighted
+)
+
+// Replica is a read-only database of the Router.
+type Replica struct {
+	DB     *sql.DB
+	Weight int
+
+	healthy       int32
+	failures      int32
+	ejectedAt     int64
+	currentWeight int
+}
+
+// Healthy tells whether the replica is healthy for reading.
+func (r *Replica) Healthy() bool { return atomic.LoadInt32(&r.healthy) == 1 }
+
+func (r *Replica) setHealthy(v bool) {
+	if v {
+		atomic.StoreInt32(&r.failures, 0)
+		atomic.StoreInt32(&r.healthy, 1)
+	} else if atomic.SwapInt32(&r.healthy, 0) == 1 {
+		atomic.StoreInt64(&r.ejectedAt, time.Now().UnixNano())
+	}
+}
+
+// LagFn queries the replication lag of the replica.
+type LagFn func(ctx context.Context, db *sql.DB) (time.Duration, error)
+
+// Router is a SqxDB which splits reading and writing, that sends Query to replicas and Exec/Tx to the primary.
+// Reads are pinned to the primary inside transactions, when no replicas are healthy,
+// or during the read-your-writes window after a recent write.
+//
+// The read-your-writes window is global to the router, so a write of any caller pins the reads of all callers,
+// unless the reads and writes are made with a context of WithSession, which has its own window.
+type Router struct {
+	Primary  *sql.DB
+	Replicas []*Replica
+	DBType   sqlparser.DBType
+
+	Policy RoutePolicy
+	// ReadYourWrites is the window after a write in which reads are sent to the primary.
+	ReadYourWrites time.Duration
+	// MaxFailures is the max number of consecutive query errors before a replica is ejected.
+	MaxFailures int
+	// EjectDuration is the duration after which an ejected replica is tried again,
+	// when the health check is disabled, 0 to keep it ejected.
+	EjectDuration time.Duration
+	// HealthInterval is the interval to health-check the replicas, 0 to disable.
+	HealthInterval time.Duration
+	// MaxLag is the max replication lag of a healthy replica, checked by LagFn.
+	MaxLag time.Duration
+	LagFn  LagFn
+
+	lastWrite int64
+	next      uint32
+	mu        sync.Mutex
+	stop      chan struct{}
+	stopOnce  sync.Once
+}
+
+// RouterOption is the option to create a Router.
+type RouterOption func(*Router)
+
+// WithRoutePolicy specifies the policy to pick a replica.
+func WithRoutePolicy(v RoutePolicy) RouterOption { return func(r *Router) { r.Policy = v } }
+
+// WithReadYourWrites specifies the window after a write in which reads are sent to the primary.
+func WithReadYourWrites(v time.Duration) RouterOption {
+	return func(r *Router) { r.ReadYourWrites = v }
+}
+
+// WithMaxFailures specifies the max number of consecutive query errors before a replica is ejected.
+func WithMaxFailures(v int) RouterOption { return func(r *Router) { r.MaxFailures = v } }
+
+// WithEjectDuration specifies the duration after which an ejected replica is tried again,
+// when the health check is disabled.
+func WithEjectDuration(v time.Duration) RouterOption { return func(r *Router) { r.EjectDuration = v } }
+
+// WithHealthCheck specifies the interval to health-check the replicas,
+// and the max replication lag queried by lagFn (nil to ping only).
+func WithHealthCheck(interval, maxLag time.Duration, lagFn LagFn) RouterOption {
+	return func(r *Router) { r.HealthInterval, r.MaxLag, r.LagFn = interval, maxLag, lagFn }
+}
+
+// WithReplica adds a replica with the weight for the Weighted policy.
+func WithReplica(db *sql.DB, weight int) RouterOption {
+	return func(r *Router) { r.Replicas = append(r.Replicas, &Replica{DB: db, Weight: weight}) }
+}
+
+// NewRouter creates a Router with the primary and the replicas (weight 1).
+func NewRouter(primary *sql.DB, replicas []*sql.DB, options ...RouterOption) *Router {
+	r := &Router{
+		Primary:       primary,
+		DBType:        sqlparser.ToDBType(DriverName(primary.Driver())),
+		MaxFailures:   3,
+		EjectDuration: 30 * time.Second,
+		stop:          make(chan struct{}),
+	}
+	for _, db := range replicas {
+		r.Replicas = append(r.Replicas, &Replica{DB: db, Weight: 1})
+	}
+	for _, f := range options {
+		f(r)
+	}
+	for _, replica := range r.Replicas {
+		replica.setHealthy(true)
+	}
+
+	if r.HealthInterval > 0 && len(r.Replicas) > 0 {
+		go r.healthLoop()
+	}
+
+	return r
+}
+
+// Sqx creates a Sqx on the router.
+func (r *Router) Sqx() *Sqx { return &Sqx{DB: r, DBType: r.DBType, CloseFn: r.Close} }
+
+// GetDBType returns the DBType of the primary.
+func (r *Router) GetDBType() sqlparser.DBType { return r.DBType }
+
+// GetDB returns the primary for writing, and starts the global read-your-writes window.
+func (r *Router) GetDB() *sql.DB { return r.GetDBContext(context.Background()) }
+
+// GetReadDB returns a db for reading.
+func (r *Router) GetReadDB() *sql.DB { return r.GetReadDBContext(context.Background()) }
+
+// GetDBContext returns the primary for writing, as the ContextDBGetter behind CreateDao,
+// and starts the read-your-writes window of the session in ctx, or the global one.
+func (r *Router) GetDBContext(ctx context.Context) *sql.DB {
+	r.markWrite(ctx)
+	return r.Primary
+}
+
+// GetReadDBContext returns a db for reading, as the ContextDBGetter behind CreateDao.
+func (r *Router) GetReadDBContext(ctx context.Context) *sql.DB {
+	if replica := r.pick(ctx); replica != nil {
+		return replica.DB
+	}
+
+	return r.Primary
+}
+
+// ReportReadError reports the result of a query on the db got by GetReadDBContext,
+// as the ReadErrorReporter behind CreateDao, to eject the failing replica.
+func (r *Router) ReportReadError(db *sql.DB, err error) {
+	for _, replica := range r.Replicas {
+		if replica.DB == db {
+			r.reportError(replica, err)
+			return
+		}
+	}
+}
+
+type usePrimaryKey struct{}
+
+// UsePrimary returns a context which pins the reads with it to the primary.
+func UsePrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, usePrimaryKey{}, true)
+}
+
+type sessionKey struct{}
+
+// session is the read-your-writes window of a context.
+type session struct {
+	lastWrite int64
+}
+
+// WithSession returns a context which has its own read-your-writes window,
+// so that the reads with it are only pinned to the primary by the writes with it,
+// instead of the writes of all the callers of the router.
+func WithSession(ctx context.Context) context.Context {
+	return context.WithValue(ctx, sessionKey{}, &session{})
+}
+
+// lastWriteOf returns the last write time of the session in the context, or the global one.
+func (r *Router) lastWriteOf(ctx context.Context) *int64 {
+	if s, ok := ctx.Value(sessionKey{}).(*session); ok {
+		return &s.lastWrite
+	}
+	return &r.lastWrite
+}
+
+func (r *Router) markWrite(ctx context.Context) {
+	if r.ReadYourWrites > 0 {
+		atomic.StoreInt64(r.lastWriteOf(ctx), time.Now().UnixNano())
+	}
+}
+
+// pick picks a healthy replica for reading, or nil for the primary.
+func (r *Router) pick(ctx context.Context) *Replica {
+	if len(r.Replicas) == 0 {
+		return nil
+	}
+	if v, ok := ctx.Value(usePrimaryKey{}).(bool); ok && v {
+		return nil
+	}
+	if r.ReadYourWrites > 0 {
+		if time.Since(time.Unix(0, atomic.LoadInt64(r.lastWriteOf(ctx)))) < r.ReadYourWrites {
+			return nil
+		}
+	}
+
+	if r.Policy == Weighted {
+		return r.pickWeighted()
+	}
+
+	n := len(r.Replicas)
+	start := int(atomic.AddUint32(&r.next, 1))
+	for i := 0; i < n; i++ {
+		if replica := r.Replicas[(start+i)%n]; r.available(replica) {
+			return replica
+		}
+	}
+
+	return nil
+}
+
+// pickWeighted picks in the smooth weighted round-robin like nginx.
+func (r *Router) pickWeighted() *Replica {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var best *Replica
+	total := 0
+	for _, replica := range r.Replicas {
+		if !r.available(replica) || replica.Weight <= 0 {
+			continue
+		}
+
+		replica.currentWeight += replica.Weight
+		total += replica.Weight
+		if best == nil || replica.currentWeight > best.currentWeight {
+			best = replica
+		}
+	}
+
+	if best != nil {
+		best.currentWeight -= total
+	}
+
+	return best
+}
+
+// available tells whether the replica is healthy, or tries it again after the EjectDuration
+// when the health check is disabled, to be ejected again by a single error.
+func (r *Router) available(replica *Replica) bool {
+	if replica.Healthy() {
+		return true
+	}
+	if r.HealthInterval > 0 || r.EjectDuration <= 0 {
+		return false
+	}
+	if time.Since(time.Unix(0, atomic.LoadInt64(&replica.ejectedAt))) < r.EjectDuration {
+		return false
+	}
+
+	if atomic.CompareAndSwapInt32(&replica.healthy, 0, 1) {
+		log.Printf("I! replica tried again after ejected for %s", r.EjectDuration)
+		atomic.StoreInt32(&replica.failures, int32(r.MaxFailures)-1)
+	}
+	return true
+}
+
+// reportError ejects the replica after MaxFailures consecutive errors.
+func (r *Router) reportError(replica *Replica, err error) {
+	if err == nil || errors.Is(err, sql.ErrNoRows) || errors.Is(err, context.Canceled) {
+		atomic.StoreInt32(&replica.failures, 0)
+		return
+	}
+
+	if failures := atomic.AddInt32(&replica.failures, 1); r.MaxFailures > 0 && int(failures) >= r.MaxFailures {
+		if replica.Healthy() {
+			log.Printf("W! replica ejected after %d failures, last error: %v", failures, err)
+		}
+		replica.setHealthy(false)
+	}
+}
+
+// CheckHealth checks the replicas by ping and LagFn, ejects the unhealthy ones and restores the recovered ones.
+func (r *Router) CheckHealth(ctx context.Context) {
+	for _, replica := range r.Replicas {
+		err := replica.DB.PingContext(ctx)
+		if err == nil && r.LagFn != nil {
+			var lag time.Duration
+			if lag, err = r.LagFn(ctx, replica.DB); err == nil && r.MaxLag > 0 && lag > r.MaxLag {
+				err = errors.New("replication lag " + lag.String() + " exceeds " + r.MaxLag.String())
+			}
+		}
+
+		if healthy := err == nil; healthy != replica.Healthy() {
+			log.Printf("I! replica healthy changed to %t, error: %v", healthy, err)
+			replica.setHealthy(healthy)
+		}
+	}
+}
+
+func (r *Router) healthLoop() {
+	ticker := time.NewTicker(r.HealthInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), r.HealthInterval)
+			r.CheckHealth(ctx)
+			cancel()
+		}
+	}
+}
+
+// QueryContext queries on a replica, or the primary when pinned.
+func (r *Router) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	replica := r.pick(ctx)
+	if replica == nil {
+		return r.Primary.QueryContext(ctx, query, args...)
+	}
+
+	rows, err := replica.DB.QueryContext(ctx, query, args...)
+	r.reportError(replica, err)
+	return rows, err
+}
+
+// Query queries on a replica, or the primary when pinned.
+func (r *Router) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return r.QueryContext(context.Background(), query, args...)
+}
+
+// ExecContext executes on the primary.
+func (r *Router) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	r.markWrite(ctx)
+	return r.Primary.ExecContext(ctx, query, args...)
+}
+
+// Exec executes on the primary.
+func (r *Router) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return r.ExecContext(context.Background(), query, args...)
+}
+
+// BeginTx begins a transaction on the primary, so all the reads and writes inside it are on the primary.
+func (r *Router) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	r.markWrite(ctx)
+	return r.Primary.BeginTx(ctx, opts)
+}
+
+// Close stops the health check and closes the primary and replicas.
+func (r *Router) Close() error {
+	r.stopOnce.Do(func() { close(r.stop) })
+
+	err := r.Primary.Close()
+	for _, replica := range r.Replicas {
+		err = multierr.Append(err, replica.DB.Close())
+	}
+
+	return err
+}
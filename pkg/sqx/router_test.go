@@ -0,0 +1,194 @@
+package sqx_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/bingoohuang/gg/pkg/sqx"
+	"github.com/stretchr/testify/assert"
+)
+
+func openNamedDB(t *testing.T, name string) *sql.DB {
+	db, err := sql.Open("sqlite3", ":memory:")
+	assert.Nil(t, err)
+	db.SetMaxOpenConns(1) // every connection to :memory: is a new database
+
+	_, err = db.Exec("create table node(name varchar(10))")
+	assert.Nil(t, err)
+	_, err = db.Exec("insert into node(name) values(?)", name)
+	assert.Nil(t, err)
+	return db
+}
+
+func queryNode(t *testing.T, db sqx.SqxDB) string {
+	return queryNodeContext(t, context.Background(), db)
+}
+
+func queryNodeContext(t *testing.T, ctx context.Context, db sqx.SqxDB) string {
+	s, err := sqx.SQL{Q: "select name from node", Ctx: ctx}.QueryAsString(db)
+	assert.Nil(t, err)
+	return s
+}
+
+func TestRouter(t *testing.T) {
+	r := sqx.NewRouter(openNamedDB(t, "primary"),
+		[]*sql.DB{openNamedDB(t, "r1"), openNamedDB(t, "r2")},
+		sqx.WithMaxFailures(1))
+	defer r.Close()
+
+	db := r.Sqx()
+	assert.Equal(t, []string{"r2", "r1", "r2"}, []string{queryNode(t, db), queryNode(t, db), queryNode(t, db)})
+	assert.Equal(t, "primary", queryNodeContext(t, sqx.UsePrimary(context.Background()), db))
+
+	_, err := sqx.NewSQL("insert into node(name) values('written')").Update(db)
+	assert.Nil(t, err)
+	n, err := sqx.NewSQL("select count(*) from node").QueryAsNumber(r.Primary)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), n)
+
+	// the failed replica is ejected, and restored by the health check
+	_, err = r.Replicas[1].DB.Exec("drop table node")
+	assert.Nil(t, err)
+	_, _ = sqx.NewSQL("select name from node").QueryAsString(db)
+	_, _ = sqx.NewSQL("select name from node").QueryAsString(db)
+	assert.False(t, r.Replicas[1].Healthy())
+	assert.Equal(t, []string{"r1", "r1"}, []string{queryNode(t, db), queryNode(t, db)})
+
+	r.CheckHealth(context.Background())
+	assert.True(t, r.Replicas[1].Healthy())
+}
+
+func TestRouterReadYourWrites(t *testing.T) {
+	r := sqx.NewRouter(openNamedDB(t, "primary"), []*sql.DB{openNamedDB(t, "r1")},
+		sqx.WithReadYourWrites(100*time.Millisecond))
+	defer r.Close()
+
+	db := r.Sqx()
+	assert.Equal(t, "r1", queryNode(t, db))
+	_, err := sqx.NewSQL("update node set name = 'updated'").Update(db)
+	assert.Nil(t, err)
+	assert.Equal(t, "updated", queryNode(t, db))
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, "r1", queryNode(t, db))
+}
+
+func TestRouterSession(t *testing.T) {
+	r := sqx.NewRouter(openNamedDB(t, "primary"), []*sql.DB{openNamedDB(t, "r1")},
+		sqx.WithReadYourWrites(time.Hour))
+	defer r.Close()
+
+	db := r.Sqx()
+	writer, reader := sqx.WithSession(context.Background()), sqx.WithSession(context.Background())
+	_, err := sqx.SQL{Q: "update node set name = 'updated'", Ctx: writer}.Update(db)
+	assert.Nil(t, err)
+	assert.Equal(t, "updated", queryNodeContext(t, writer, db))
+	assert.Equal(t, "r1", queryNodeContext(t, reader, db))
+	assert.Equal(t, "r1", queryNode(t, db))
+}
+
+func TestRouterEjectDuration(t *testing.T) {
+	r := sqx.NewRouter(openNamedDB(t, "primary"), []*sql.DB{openNamedDB(t, "r1")},
+		sqx.WithMaxFailures(2), sqx.WithEjectDuration(50*time.Millisecond))
+	defer r.Close()
+
+	db := r.Sqx()
+	_, err := r.Replicas[0].DB.Exec("drop table node")
+	assert.Nil(t, err)
+	_, _ = sqx.NewSQL("select name from node").QueryAsString(db)
+	_, _ = sqx.NewSQL("select name from node").QueryAsString(db)
+	assert.False(t, r.Replicas[0].Healthy())
+	assert.Equal(t, "primary", queryNode(t, db))
+
+	// tried again after the eject duration, and ejected again by a single error
+	time.Sleep(50 * time.Millisecond)
+	_, _ = sqx.NewSQL("select name from node").QueryAsString(db)
+	assert.False(t, r.Replicas[0].Healthy())
+	assert.Equal(t, "primary", queryNode(t, db))
+
+	time.Sleep(50 * time.Millisecond)
+	_, err = r.Replicas[0].DB.Exec("create table node(name varchar(10)); insert into node(name) values('r1')")
+	assert.Nil(t, err)
+	assert.Equal(t, "r1", queryNode(t, db))
+	assert.True(t, r.Replicas[0].Healthy())
+}
+
+func TestRouterWeighted(t *testing.T) {
+	r := sqx.NewRouter(openNamedDB(t, "primary"), nil, sqx.WithRoutePolicy(sqx.Weighted),
+		sqx.WithReplica(openNamedDB(t, "r1"), 2), sqx.WithReplica(openNamedDB(t, "r2"), 1))
+	defer r.Close()
+
+	db := r.Sqx()
+	var names []string
+	for i := 0; i < 6; i++ {
+		names = append(names, queryNode(t, db))
+	}
+	assert.Equal(t, []string{"r1", "r2", "r1", "r1", "r2", "r1"}, names)
+}
+
+func TestRouterLag(t *testing.T) {
+	lag := time.Second
+	r := sqx.NewRouter(openNamedDB(t, "primary"), []*sql.DB{openNamedDB(t, "r1")},
+		sqx.WithHealthCheck(time.Hour, 500*time.Millisecond, func(context.Context, *sql.DB) (time.Duration, error) {
+			return lag, nil
+		}))
+	defer r.Close()
+
+	r.CheckHealth(context.Background())
+	assert.False(t, r.Replicas[0].Healthy())
+	assert.Equal(t, "primary", queryNode(t, r.Sqx()))
+
+	lag = 0
+	r.CheckHealth(context.Background())
+	assert.Equal(t, "r1", queryNode(t, r.Sqx()))
+}
+
+type routerDao struct {
+	Name   func() string    `sql:"select name from node"`
+	Rename func(string) int `sql:"update node set name = :1"`
+}
+
+func TestRouterDao(t *testing.T) {
+	r := sqx.NewRouter(openNamedDB(t, "primary"), []*sql.DB{openNamedDB(t, "r1")})
+	defer r.Close()
+
+	dao := &routerDao{}
+	assert.Nil(t, sqx.CreateDao(dao, sqx.WithDBGetter(r)))
+	assert.Equal(t, "r1", dao.Name())
+	assert.Equal(t, 1, dao.Rename("renamed"))
+	assert.Equal(t, "r1", dao.Name())
+	assert.Equal(t, "renamed", queryNodeContext(t, sqx.UsePrimary(context.Background()), r.Sqx()))
+}
+
+func TestRouterDaoEject(t *testing.T) {
+	r := sqx.NewRouter(openNamedDB(t, "primary"), []*sql.DB{openNamedDB(t, "r1")}, sqx.WithMaxFailures(1))
+	defer r.Close()
+
+	var err error
+	dao := &routerDao{}
+	assert.Nil(t, sqx.CreateDao(dao, sqx.WithDBGetter(r), sqx.WithError(&err)))
+	_, err = r.Replicas[0].DB.Exec("drop table node")
+	assert.Nil(t, err)
+
+	dao.Name()
+	assert.NotNil(t, err)
+	assert.False(t, r.Replicas[0].Healthy())
+	assert.Equal(t, "primary", dao.Name())
+}
+
+func TestRouterDaoSession(t *testing.T) {
+	r := sqx.NewRouter(openNamedDB(t, "primary"), []*sql.DB{openNamedDB(t, "r1")},
+		sqx.WithReadYourWrites(time.Hour))
+	defer r.Close()
+
+	writer, reader := &routerDao{}, &routerDao{}
+	assert.Nil(t, sqx.CreateDao(writer, sqx.WithDBGetter(r), sqx.WithCtx(sqx.WithSession(context.Background()))))
+	assert.Nil(t, sqx.CreateDao(reader, sqx.WithDBGetter(r), sqx.WithCtx(sqx.WithSession(context.Background()))))
+
+	assert.Equal(t, 1, writer.Rename("renamed"))
+	assert.Equal(t, "renamed", writer.Name())
+	assert.Equal(t, "r1", reader.Name())
+	assert.Equal(t, "r1", queryNode(t, r.Sqx()))
+}
@@ -0,0 +1,13 @@
+-- name: ListAll
+select * from person;
+
+-- name: ClearAll
+  delete from person;
+
+-- name: FindByName
+select id, age from person where 1 = 1
+-- if name != ""
+  and name like :name
+-- else
+  and name like '%x'
+-- end
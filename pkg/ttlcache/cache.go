@@ -4,6 +4,7 @@ import (
 	"container/list"
 	"context"
 	"fmt"
+	"log"
 	"sync"
 	"time"
 
@@ -15,6 +16,11 @@ const (
 	EvictionReasonDeleted EvictionReason = iota + 1
 	EvictionReasonCapacityReached
 	EvictionReasonExpired
+	EvictionReasonMaxWeightReached
+	// EvictionReasonRejected is used for the new items which are
+	// rejected by the admission filter of PolicyTinyLFU, or are
+	// heavier than the maximum weight, so they are never stored.
+	EvictionReasonRejected
 )
 
 // EvictionReason is used to specify why a certain item was
@@ -34,6 +40,12 @@ type Cache[K comparable, V any] struct {
 		lru      *list.List
 		expQueue expirationQueue[K, V]
 
+		// weight is the total weight of the items.
+		weight    uint64
+		lfu       lfuQueue[K, V]
+		accessSeq uint64
+		sketch    *countMinSketch
+
 		timerCh chan time.Duration
 	}
 
@@ -71,6 +83,12 @@ func New[K comparable, V any](opts ...Option[K, V]) *Cache[K, V] {
 
 	applyOptions(&c.options, opts...)
 
+	if c.options.snapshotFile != "" {
+		if err := c.RestoreFile(c.options.snapshotFile); err != nil {
+			log.Printf("W! ttlcache restore from %s failed: %v", c.options.snapshotFile, err)
+		}
+	}
+
 	return c
 }
 
@@ -131,26 +149,52 @@ func (c *Cache[K, V]) set(key K, value V, ttl time.Duration) *Item[K, V] {
 		ttl = c.options.ttl
 	}
 
+	var weight uint64
+	if c.options.weigher != nil {
+		weight = c.options.weigher(key, value)
+	}
+
 	elem := c.get(key, false)
 	if elem != nil {
 		// update/overwrite an existing item
 		item := elem.Value.(*Item[K, V])
 		item.update(value, ttl)
+		c.items.weight = c.items.weight - item.weight + weight
+		item.setWeight(weight)
 		c.updateExpirations(false, elem)
+		c.onAccess(elem)
+		if c.options.maxWeight != 0 && weight > c.options.maxWeight {
+			c.evict(EvictionReasonMaxWeightReached, elem)
+			return item
+		}
+		c.evictOverflow(0, 0, elem)
 
 		return item
 	}
 
-	if c.options.capacity != 0 && uint64(len(c.items.values)) >= c.options.capacity {
-		// delete the oldest item
-		c.evict(EvictionReasonCapacityReached, c.items.lru.Back())
-	}
-
 	// create a new item
 	item := newItem(key, value, ttl)
+	item.setWeight(weight)
+
+	c.record(key)
+	if c.options.maxWeight != 0 && weight > c.options.maxWeight {
+		c.reject(item)
+		return item
+	}
+
+	if c.overflow(1, weight) != 0 && !c.admit(key, c.overflowVictims(1, weight)) {
+		c.reject(item)
+		return item
+	}
+
+	// delete the victims (the oldest items by default)
+	c.evictOverflow(1, weight, nil)
+
 	elem = c.items.lru.PushFront(item)
 	c.items.values[key] = elem
+	c.items.weight += weight
 	c.updateExpirations(true, elem)
+	c.onInsert(elem)
 
 	c.metricsMu.Lock()
 	c.metrics.Insertions++
@@ -165,6 +209,70 @@ func (c *Cache[K, V]) set(key K, value V, ttl time.Duration) *Item[K, V] {
 	return item
 }
 
+// overflow returns the eviction reason if n more items of the weight
+// overflow the capacity or the maximum weight, or 0 if not.
+// Not concurrently safe.
+func (c *Cache[K, V]) overflow(n int, weight uint64) EvictionReason {
+	return c.exceeds(len(c.items.values)+n, c.items.weight+weight)
+}
+
+// exceeds returns the eviction reason if the count of the items or their total weight
+// exceeds the capacity or the maximum weight, or 0 if not.
+func (c *Cache[K, V]) exceeds(count int, weight uint64) EvictionReason {
+	if c.options.capacity != 0 && uint64(count) > c.options.capacity {
+		return EvictionReasonCapacityReached
+	}
+
+	if c.options.maxWeight != 0 && weight > c.options.maxWeight {
+		return EvictionReasonMaxWeightReached
+	}
+
+	return 0
+}
+
+// overflowVictims returns the items which would be evicted by the LRU order
+// to fit n more items of the weight, without evicting them.
+// Not concurrently safe.
+func (c *Cache[K, V]) overflowVictims(n int, weight uint64) []*list.Element {
+	var victims []*list.Element
+	count, total := len(c.items.values)+n, c.items.weight+weight
+	for elem := c.items.lru.Back(); elem != nil && c.exceeds(count, total) != 0; elem = elem.Prev() {
+		victims = append(victims, elem)
+		count--
+		total -= elem.Value.(*Item[K, V]).weight
+	}
+
+	return victims
+}
+
+// evictOverflow evicts the victims by the policy, except the excluded item,
+// until n more items of the weight fit in the capacity and the maximum weight.
+// Not concurrently safe.
+func (c *Cache[K, V]) evictOverflow(n int, weight uint64, exclude *list.Element) {
+	for reason := c.overflow(n, weight); reason != 0; reason = c.overflow(n, weight) {
+		victim := c.victim(exclude)
+		if victim == nil {
+			return
+		}
+
+		c.evict(reason, victim)
+	}
+}
+
+// reject notifies the eviction subscribers that the new item is rejected.
+// Not concurrently safe.
+func (c *Cache[K, V]) reject(item *Item[K, V]) {
+	c.metricsMu.Lock()
+	c.metrics.Rejections++
+	c.metricsMu.Unlock()
+
+	c.events.eviction.mu.RLock()
+	for _, fn := range c.events.eviction.fns {
+		fn(EvictionReasonRejected, item)
+	}
+	c.events.eviction.mu.RUnlock()
+}
+
 // get retrieves an item from the cache and extends its expiration
 // time if 'touch' is set to true.
 // It returns nil if the item is not found or is expired.
@@ -197,7 +305,7 @@ func (c *Cache[K, V]) get(key K, touch bool) *list.Element {
 func (c *Cache[K, V]) evict(reason EvictionReason, elems ...*list.Element) {
 	if len(elems) > 0 {
 		c.metricsMu.Lock()
-		c.metrics.Evictions += uint64(len(elems))
+		c.metrics.addEvictions(reason, uint64(len(elems)))
 		c.metricsMu.Unlock()
 
 		c.events.eviction.mu.RLock()
@@ -206,6 +314,8 @@ func (c *Cache[K, V]) evict(reason EvictionReason, elems ...*list.Element) {
 			delete(c.items.values, item.key)
 			c.items.lru.Remove(elems[i])
 			c.items.expQueue.remove(elems[i])
+			c.items.weight -= item.weight
+			c.onRemove(elems[i])
 
 			for _, fn := range c.events.eviction.fns {
 				fn(reason, item)
@@ -217,7 +327,7 @@ func (c *Cache[K, V]) evict(reason EvictionReason, elems ...*list.Element) {
 	}
 
 	c.metricsMu.Lock()
-	c.metrics.Evictions += uint64(len(c.items.values))
+	c.metrics.addEvictions(reason, uint64(len(c.items.values)))
 	c.metricsMu.Unlock()
 
 	c.events.eviction.mu.RLock()
@@ -233,6 +343,8 @@ func (c *Cache[K, V]) evict(reason EvictionReason, elems ...*list.Element) {
 	c.items.values = make(map[K]*list.Element)
 	c.items.lru.Init()
 	c.items.expQueue = newExpirationQueue[K, V]()
+	c.items.weight = 0
+	c.items.lfu = nil
 }
 
 // Set creates a new item from the provided key and value, adds
@@ -259,6 +371,11 @@ func (c *Cache[K, V]) Get(key K, opts ...Option[K, V]) *Item[K, V] {
 
 	c.items.mu.Lock()
 	elem := c.get(key, !getOpts.disableTouchOnHit)
+	if elem != nil {
+		c.onAccess(elem)
+	} else {
+		c.record(key)
+	}
 	c.items.mu.Unlock()
 
 	if elem == nil {
@@ -370,12 +487,25 @@ func (c *Cache[K, V]) Items() map[K]*Item[K, V] {
 	return items
 }
 
+// Weight returns the total weight of the items in the cache.
+func (c *Cache[K, V]) Weight() uint64 {
+	c.items.mu.RLock()
+	defer c.items.mu.RUnlock()
+
+	return c.items.weight
+}
+
 // Metrics returns the metrics of the cache.
 func (c *Cache[K, V]) Metrics() Metrics {
+	weight := c.Weight()
+
 	c.metricsMu.RLock()
 	defer c.metricsMu.RUnlock()
 
-	return c.metrics
+	m := c.metrics
+	m.Policy = c.options.policy
+	m.Weight = weight
+	return m
 }
 
 // Start starts an automatic cleanup process that
@@ -417,10 +547,15 @@ func (c *Cache[K, V]) Start() {
 
 	defer stop()
 
+	snapshotC, stopSnapshot := c.snapshotTicker()
+	defer stopSnapshot()
+
 	for {
 		select {
 		case <-c.stopCh:
 			return
+		case <-snapshotC:
+			c.snapshotToFile()
 		case d := <-c.items.timerCh:
 			stop()
 			timer.Reset(d)
@@ -433,9 +568,11 @@ func (c *Cache[K, V]) Start() {
 }
 
 // Stop stops the automatic cleanup process.
-// It blocks until the cleanup process exits.
+// It blocks until the cleanup process exits, and the snapshot file, if any, is written.
 func (c *Cache[K, V]) Stop() {
 	c.stopCh <- struct{}{}
+	// the cleanup process has left the loop, so the snapshot does not race with the periodical one.
+	c.snapshotToFile()
 }
 
 // OnInsertion adds the provided function to be executed when
@@ -194,8 +194,9 @@ func Test_Cache_set(t *testing.T) {
 			Key:      newKey,
 			TTL:      DefaultTTL,
 			Metrics: Metrics{
-				Insertions: 1,
-				Evictions:  1,
+				Insertions:        1,
+				Evictions:         1,
+				CapacityEvictions: 1,
 			},
 			ExpectFns: true,
 		},
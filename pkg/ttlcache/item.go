@@ -32,6 +32,13 @@ type Item[K comparable, V any] struct {
 	ttl        time.Duration
 	expiresAt  time.Time
 	queueIndex int
+
+	// weight is the weight of the item by the weigher.
+	weight uint64
+	// frequency, accessSeq and lfuIndex are used by PolicyLFU.
+	frequency uint64
+	accessSeq uint64
+	lfuIndex  int
 }
 
 // newItem creates a new cache item.
@@ -60,6 +67,14 @@ func (item *Item[K, V]) update(value V, ttl time.Duration) {
 	item.touchUnsafe()
 }
 
+// setWeight sets the weight of the item by the weigher.
+func (item *Item[K, V]) setWeight(weight uint64) {
+	item.mu.Lock()
+	defer item.mu.Unlock()
+
+	item.weight = weight
+}
+
 // touch updates the item's expiration timestamp.
 func (item *Item[K, V]) touch() {
 	item.mu.Lock()
@@ -97,6 +112,14 @@ func (item *Item[K, V]) isExpiredUnsafe() bool {
 	return item.expiresAt.Before(time.Now())
 }
 
+// Weight returns the weight of the item by the weigher of the cache, 0 without weigher.
+func (item *Item[K, V]) Weight() uint64 {
+	item.mu.RLock()
+	defer item.mu.RUnlock()
+
+	return item.weight
+}
+
 // Key returns the key of the item.
 func (item *Item[K, V]) Key() K {
 	item.mu.RLock()
@@ -18,4 +18,48 @@ type Metrics struct {
 	// Evictions specifies how many items were removed from the
 	// cache.
 	Evictions uint64
+
+	// Deletions, Expirations, CapacityEvictions and WeightEvictions
+	// specify how many items were removed from the cache by the
+	// eviction reasons.
+	Deletions         uint64
+	Expirations       uint64
+	CapacityEvictions uint64
+	WeightEvictions   uint64
+
+	// Rejections specifies how many new items were rejected by the
+	// admission filter of PolicyTinyLFU, or were heavier than the
+	// maximum weight. They are not counted in Evictions.
+	Rejections uint64
+
+	// Policy specifies the eviction policy of the cache.
+	Policy Policy
+
+	// Weight specifies the current total weight of the items.
+	Weight uint64
+}
+
+// HitRatio returns the ratio of hits to all the retrievals.
+func (m Metrics) HitRatio() float64 {
+	if total := m.Hits + m.Misses; total > 0 {
+		return float64(m.Hits) / float64(total)
+	}
+
+	return 0
+}
+
+// addEvictions adds the evicted items count by the reason.
+func (m *Metrics) addEvictions(reason EvictionReason, n uint64) {
+	m.Evictions += n
+
+	switch reason {
+	case EvictionReasonDeleted:
+		m.Deletions += n
+	case EvictionReasonExpired:
+		m.Expirations += n
+	case EvictionReasonCapacityReached:
+		m.CapacityEvictions += n
+	case EvictionReasonMaxWeightReached:
+		m.WeightEvictions += n
+	}
 }
@@ -21,6 +21,12 @@ type options[K comparable, V any] struct {
 	ttl               time.Duration
 	loader            Loader[K, V]
 	disableTouchOnHit bool
+	weigher           func(key K, value V) uint64
+	maxWeight         uint64
+	policy            Policy
+	codec             Codec
+	snapshotFile      string
+	snapshotInterval  time.Duration
 }
 
 // applyOptions applies the provided option values to the option struct.
@@ -65,3 +71,47 @@ func WithDisableTouchOnHit[K comparable, V any]() Option[K, V] {
 		opts.disableTouchOnHit = true
 	})
 }
+
+// WithWeigher sets the weigher to calculate the weight (like bytes) of the items,
+// which is used with WithMaxWeight.
+// It has no effect when passing into Get().
+func WithWeigher[K comparable, V any](w func(key K, value V) uint64) Option[K, V] {
+	return optionFunc[K, V](func(opts *options[K, V]) {
+		opts.weigher = w
+	})
+}
+
+// WithMaxWeight sets the maximum total weight of the items in the cache.
+// It has no effect when passing into Get().
+func WithMaxWeight[K comparable, V any](w uint64) Option[K, V] {
+	return optionFunc[K, V](func(opts *options[K, V]) {
+		opts.maxWeight = w
+	})
+}
+
+// WithPolicy sets the eviction policy of the cache when the capacity or the maximum weight is reached.
+// It has no effect when passing into Get().
+func WithPolicy[K comparable, V any](p Policy) Option[K, V] {
+	return optionFunc[K, V](func(opts *options[K, V]) {
+		opts.policy = p
+	})
+}
+
+// WithCodec sets the codec of Snapshot and Restore, GobCodec by default.
+// It has no effect when passing into Get().
+func WithCodec[K comparable, V any](codec Codec) Option[K, V] {
+	return optionFunc[K, V](func(opts *options[K, V]) {
+		opts.codec = codec
+	})
+}
+
+// WithSnapshotFile sets the snapshot file, which is restored when the
+// cache is created, and written in every interval by Start() and when
+// Stop() is called.
+// It has no effect when passing into Get().
+func WithSnapshotFile[K comparable, V any](path string, interval time.Duration) Option[K, V] {
+	return optionFunc[K, V](func(opts *options[K, V]) {
+		opts.snapshotFile = path
+		opts.snapshotInterval = interval
+	})
+}
@@ -0,0 +1,284 @@
+package ttlcache
+
+import (
+	"container/heap"
+	"container/list"
+	"fmt"
+	"hash/fnv"
+)
+
+// Available eviction policies.
+const (
+	// PolicyLRU evicts the least recently used items.
+	PolicyLRU Policy = iota
+	// PolicyLFU evicts the least frequently used items,
+	// and the least recently used ones among the same frequency.
+	PolicyLFU
+	// PolicyTinyLFU evicts the least recently used items, but a new item
+	// is only admitted when it is estimated more frequently used than the
+	// item to evict, by a TinyLFU count-min sketch of the recent accesses
+	// (including the misses), or else the new item is rejected.
+	PolicyTinyLFU
+)
+
+// Policy is used to specify which items are evicted when the capacity
+// or the maximum weight of the cache is reached.
+type Policy int
+
+func (p Policy) String() string {
+	switch p {
+	case PolicyLRU:
+		return "LRU"
+	case PolicyLFU:
+		return "LFU"
+	case PolicyTinyLFU:
+		return "TinyLFU"
+	}
+
+	return "Unknown"
+}
+
+// onInsert updates the policy state of the newly inserted item.
+// Not concurrently safe.
+func (c *Cache[K, V]) onInsert(elem *list.Element) {
+	if c.options.policy == PolicyLFU {
+		item := elem.Value.(*Item[K, V])
+		c.items.accessSeq++
+		item.frequency, item.accessSeq = 1, c.items.accessSeq
+		c.items.lfu.push(elem)
+	}
+}
+
+// onAccess updates the policy state of the retrieved or updated item.
+// Not concurrently safe.
+func (c *Cache[K, V]) onAccess(elem *list.Element) {
+	item := elem.Value.(*Item[K, V])
+
+	switch c.options.policy {
+	case PolicyLFU:
+		c.items.accessSeq++
+		item.frequency++
+		item.accessSeq = c.items.accessSeq
+		c.items.lfu.update(elem)
+	case PolicyTinyLFU:
+		c.record(item.key)
+	}
+}
+
+// onRemove removes the policy state of the evicted item.
+// Not concurrently safe.
+func (c *Cache[K, V]) onRemove(elem *list.Element) {
+	if c.options.policy == PolicyLFU {
+		c.items.lfu.remove(elem)
+	}
+}
+
+// record records an access of the key, including the misses, for PolicyTinyLFU.
+// Not concurrently safe.
+func (c *Cache[K, V]) record(key K) {
+	if c.options.policy != PolicyTinyLFU {
+		return
+	}
+
+	if c.items.sketch == nil {
+		c.items.sketch = newCountMinSketch(c.options.capacity)
+	}
+
+	c.items.sketch.add(hashKey(key))
+}
+
+// victim returns the next item to evict except the excluded one, like the item just updated.
+// Not concurrently safe.
+func (c *Cache[K, V]) victim(exclude *list.Element) *list.Element {
+	if c.options.policy == PolicyLFU {
+		q := c.items.lfu
+		if q.Len() == 0 {
+			return nil
+		}
+		if q[0] != exclude {
+			return q[0]
+		}
+
+		// the next least frequently used one is one of the children of the heap root
+		switch {
+		case q.Len() == 1:
+			return nil
+		case q.Len() == 2 || q.Less(1, 2):
+			return q[1]
+		default:
+			return q[2]
+		}
+	}
+
+	back := c.items.lru.Back()
+	if back != nil && back == exclude {
+		return back.Prev()
+	}
+
+	return back
+}
+
+// admit tells whether the new item of the key should be admitted in place of the victims,
+// which is estimated more frequently used than all the victims together.
+// Not concurrently safe.
+func (c *Cache[K, V]) admit(key K, victims []*list.Element) bool {
+	if c.options.policy != PolicyTinyLFU || c.items.sketch == nil || len(victims) == 0 {
+		return true
+	}
+
+	var total int
+	for _, victim := range victims {
+		total += int(c.items.sketch.estimate(hashKey(victim.Value.(*Item[K, V]).key)))
+	}
+
+	return int(c.items.sketch.estimate(hashKey(key))) > total
+}
+
+// lfuQueue stores items that are ordered by their frequencies and
+// then their access sequences. The 0th item is the least frequently used.
+type lfuQueue[K comparable, V any] []*list.Element
+
+// update updates an existing item's position in the queue.
+func (q *lfuQueue[K, V]) update(elem *list.Element) {
+	heap.Fix(q, elem.Value.(*Item[K, V]).lfuIndex)
+}
+
+// push pushes a new item into the queue.
+func (q *lfuQueue[K, V]) push(elem *list.Element) {
+	heap.Push(q, elem)
+}
+
+// remove removes an item from the queue.
+func (q *lfuQueue[K, V]) remove(elem *list.Element) {
+	heap.Remove(q, elem.Value.(*Item[K, V]).lfuIndex)
+}
+
+// Len returns the total number of items in the queue.
+func (q lfuQueue[K, V]) Len() int {
+	return len(q)
+}
+
+// Less checks if the item at the i position is less frequently used
+// (or less recently used for the same frequency) than the one at the j position.
+func (q lfuQueue[K, V]) Less(i, j int) bool {
+	item1, item2 := q[i].Value.(*Item[K, V]), q[j].Value.(*Item[K, V])
+	if item1.frequency != item2.frequency {
+		return item1.frequency < item2.frequency
+	}
+
+	return item1.accessSeq < item2.accessSeq
+}
+
+// Swap switches the places of two queue items.
+func (q lfuQueue[K, V]) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].Value.(*Item[K, V]).lfuIndex = i
+	q[j].Value.(*Item[K, V]).lfuIndex = j
+}
+
+// Push appends a new item to the item slice.
+func (q *lfuQueue[K, V]) Push(x interface{}) {
+	elem := x.(*list.Element)
+	elem.Value.(*Item[K, V]).lfuIndex = len(*q)
+	*q = append(*q, elem)
+}
+
+// Pop removes and returns the last item.
+func (q *lfuQueue[K, V]) Pop() interface{} {
+	old := *q
+	i := len(old) - 1
+	elem := old[i]
+	elem.Value.(*Item[K, V]).lfuIndex = -1
+	old[i] = nil // avoid memory leak
+	*q = old[:i]
+
+	return elem
+}
+
+const sketchDepth = 4
+
+var sketchSeeds = [sketchDepth]uint64{
+	0xc3a5c85c97cb3127, 0xb492b66fbe98f273, 0x9ae16a3b2f90404f, 0xcbf29ce484222325,
+}
+
+// countMinSketch estimates the access frequencies of the keys with 4-bit counters.
+// All the counters are halved after every 10*width additions, so the
+// estimations are about the recent accesses.
+type countMinSketch struct {
+	rows      [sketchDepth][]uint8
+	mask      uint64
+	additions int
+	resetAt   int
+}
+
+// newCountMinSketch creates a sketch for about the size of keys, 0 for unknown.
+func newCountMinSketch(size uint64) *countMinSketch {
+	if size == 0 {
+		size = 1024
+	}
+
+	width := uint64(16)
+	for width < size && width < 1<<24 {
+		width <<= 1
+	}
+
+	s := &countMinSketch{mask: width - 1, resetAt: int(width) * 10}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+
+	return s
+}
+
+func (s *countMinSketch) index(h uint64, i int) uint64 {
+	h = (h + sketchSeeds[i]) * 0x9e3779b97f4a7c15
+	return (h >> 32) & s.mask
+}
+
+// add increments the counters of the key hash.
+func (s *countMinSketch) add(h uint64) {
+	for i := range s.rows {
+		if idx := s.index(h, i); s.rows[i][idx] < 15 {
+			s.rows[i][idx]++
+		}
+	}
+
+	if s.additions++; s.additions >= s.resetAt {
+		s.reset()
+	}
+}
+
+// estimate returns the estimated frequency of the key hash.
+func (s *countMinSketch) estimate(h uint64) uint8 {
+	min := uint8(15)
+	for i := range s.rows {
+		if v := s.rows[i][s.index(h, i)]; v < min {
+			min = v
+		}
+	}
+
+	return min
+}
+
+// reset halves all the counters to age the frequencies.
+func (s *countMinSketch) reset() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] >>= 1
+		}
+	}
+
+	s.additions /= 2
+}
+
+// hashKey hashes the key for the sketch.
+func hashKey[K comparable](key K) uint64 {
+	h := fnv.New64a()
+	if s, ok := any(key).(string); ok {
+		_, _ = h.Write([]byte(s))
+	} else {
+		_, _ = fmt.Fprint(h, key)
+	}
+
+	return h.Sum64()
+}
@@ -0,0 +1,183 @@
+package ttlcache
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Cache_MaxWeight(t *testing.T) {
+	evicted := make(chan string, 10)
+	cache := New[string, string](
+		WithWeigher[string, string](func(_ string, v string) uint64 { return uint64(len(v)) }),
+		WithMaxWeight[string, string](10),
+	)
+	unsubscribe := cache.OnEviction(func(_ context.Context, r EvictionReason, item *Item[string, string]) {
+		evicted <- item.key + ":" + map[EvictionReason]string{
+			EvictionReasonMaxWeightReached: "weight", EvictionReasonRejected: "rejected",
+		}[r]
+	})
+
+	cache.Set("a", "1234", NoTTL)
+	cache.Set("b", "1234", NoTTL)
+	assert.Equal(t, uint64(8), cache.Weight())
+
+	cache.Set("c", "123", NoTTL)
+	assert.Equal(t, "a:weight", <-evicted)
+	assert.Equal(t, uint64(7), cache.Weight())
+
+	cache.Set("b", "123456", NoTTL)
+	assert.Equal(t, uint64(9), cache.Weight())
+	assert.Equal(t, uint64(6), cache.Get("b").Weight())
+
+	cache.Set("d", "12345678901", NoTTL)
+	assert.Equal(t, "d:rejected", <-evicted)
+	assert.Nil(t, cache.Get("d"))
+
+	unsubscribe()
+	assert.Len(t, evicted, 0)
+
+	m := cache.Metrics()
+	assert.Equal(t, uint64(1), m.WeightEvictions)
+	assert.Equal(t, uint64(1), m.Rejections)
+	assert.Equal(t, uint64(9), m.Weight)
+	assert.Equal(t, 0.5, m.HitRatio())
+}
+
+func Test_Cache_MaxWeightUpdate(t *testing.T) {
+	for _, policy := range []Policy{PolicyLRU, PolicyLFU} {
+		cache := New[string, string](
+			WithWeigher[string, string](func(_ string, v string) uint64 { return uint64(len(v)) }),
+			WithMaxWeight[string, string](10),
+			WithPolicy[string, string](policy),
+		)
+		cache.Set("a", "1234", NoTTL)
+		cache.Set("b", "1234", NoTTL)
+		cache.Get("b")
+		cache.Get("b")
+
+		// the updated item is the least recently and frequently used one, but the other one is evicted
+		cache.Set("a", "12345678", NoTTL)
+		assert.Equal(t, []string{"a"}, cache.Keys(), policy)
+		assert.Equal(t, uint64(8), cache.Weight(), policy)
+
+		// the updated item exceeding the max weight on its own is evicted
+		cache.Set("a", "12345678901", NoTTL)
+		assert.Nil(t, cache.Get("a"), policy)
+		assert.Equal(t, uint64(0), cache.Weight(), policy)
+		assert.Equal(t, uint64(2), cache.Metrics().WeightEvictions, policy)
+	}
+}
+
+func Test_Cache_PolicyLFU(t *testing.T) {
+	cache := New[string, string](WithCapacity[string, string](3), WithPolicy[string, string](PolicyLFU))
+	cache.Set("a", "a", NoTTL)
+	cache.Set("b", "b", NoTTL)
+	cache.Set("c", "c", NoTTL)
+	cache.Get("a")
+	cache.Get("a")
+	cache.Get("b")
+	cache.Get("c")
+
+	cache.Set("d", "d", NoTTL) // b and c have the same frequency, b is less recently used
+	assert.ElementsMatch(t, []string{"a", "c", "d"}, cache.Keys())
+
+	cache.Set("e", "e", NoTTL) // d is the least frequently used
+	assert.ElementsMatch(t, []string{"a", "c", "e"}, cache.Keys())
+
+	cache.Delete("a")
+	assert.Equal(t, 2, cache.items.lfu.Len())
+	assert.Equal(t, PolicyLFU, cache.Metrics().Policy)
+}
+
+func Test_Cache_PolicyTinyLFU(t *testing.T) {
+	cache := New[string, string](WithCapacity[string, string](2), WithPolicy[string, string](PolicyTinyLFU))
+	cache.Set("a", "a", NoTTL)
+	cache.Set("b", "b", NoTTL)
+	for i := 0; i < 3; i++ {
+		cache.Get("a")
+		cache.Get("b")
+	}
+
+	// the one-hit wonder is rejected
+	cache.Set("c", "c", NoTTL)
+	assert.ElementsMatch(t, []string{"a", "b"}, cache.Keys())
+	assert.Equal(t, uint64(1), cache.Metrics().Rejections)
+
+	// the frequently requested one is admitted
+	for i := 0; i < 5; i++ {
+		cache.Get("d")
+	}
+	cache.Set("d", "d", NoTTL)
+	assert.ElementsMatch(t, []string{"b", "d"}, cache.Keys())
+	assert.Equal(t, uint64(1), cache.Metrics().CapacityEvictions)
+}
+
+func Test_Cache_PolicyTinyLFUMaxWeight(t *testing.T) {
+	cache := New[string, string](
+		WithMaxWeight[string, string](4),
+		WithWeigher[string, string](func(_ string, v string) uint64 { return uint64(len(v)) }),
+		WithPolicy[string, string](PolicyTinyLFU),
+	)
+	cache.Set("a", "aa", NoTTL)
+	cache.Set("b", "bb", NoTTL)
+	for i := 0; i < 3; i++ {
+		cache.Get("a")
+		cache.Get("b")
+	}
+
+	// more frequent than either victim, but not than both of them
+	for i := 0; i < 5; i++ {
+		cache.Get("c")
+	}
+	cache.Set("c", "cccc", NoTTL)
+	assert.ElementsMatch(t, []string{"a", "b"}, cache.Keys())
+	assert.Equal(t, uint64(1), cache.Metrics().Rejections)
+
+	for i := 0; i < 5; i++ {
+		cache.Get("c")
+	}
+	cache.Set("c", "cccc", NoTTL)
+	assert.ElementsMatch(t, []string{"c"}, cache.Keys())
+	assert.Equal(t, uint64(4), cache.Weight())
+}
+
+func Test_Cache_WeightRace(t *testing.T) {
+	cache := New[string, string](
+		WithWeigher[string, string](func(_ string, v string) uint64 { return uint64(len(v)) }),
+	)
+	item := cache.Set("a", "a", NoTTL)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			cache.Set("a", strings.Repeat("a", i%10), NoTTL)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			item.Weight()
+		}
+	}()
+	wg.Wait()
+	assert.Equal(t, uint64(9), item.Weight())
+}
+
+func Test_countMinSketch(t *testing.T) {
+	s := newCountMinSketch(16)
+	h := hashKey("hello")
+	for i := 0; i < 20; i++ {
+		s.add(h)
+	}
+	assert.Equal(t, uint8(15), s.estimate(h))
+	assert.Equal(t, uint8(0), s.estimate(hashKey("world")))
+
+	s.reset()
+	assert.Equal(t, uint8(7), s.estimate(h))
+}
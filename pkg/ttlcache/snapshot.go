@@ -0,0 +1,209 @@
+package ttlcache
+
+import (
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bingoohuang/gg/pkg/jsoni"
+)
+
+// Codec encodes and decodes the cache snapshots.
+type Codec interface {
+	Encode(w io.Writer, v interface{}) error
+	Decode(r io.Reader, v interface{}) error
+}
+
+// GobCodec is the Codec in encoding/gob, which is the default one.
+// The concrete types of the interface keys or values should be registered by gob.Register.
+type GobCodec struct{}
+
+// Encode encodes v to w in gob.
+func (GobCodec) Encode(w io.Writer, v interface{}) error { return gob.NewEncoder(w).Encode(v) }
+
+// Decode decodes v from r in gob.
+func (GobCodec) Decode(r io.Reader, v interface{}) error { return gob.NewDecoder(r).Decode(v) }
+
+// JSONCodec is the Codec in encoding/json.
+type JSONCodec struct{}
+
+// Encode encodes v to w in JSON.
+func (JSONCodec) Encode(w io.Writer, v interface{}) error { return json.NewEncoder(w).Encode(v) }
+
+// Decode decodes v from r in JSON.
+func (JSONCodec) Decode(r io.Reader, v interface{}) error { return json.NewDecoder(r).Decode(v) }
+
+// JsoniCodec is the Codec in pkg/jsoni.
+type JsoniCodec struct{}
+
+// Encode encodes v to w in JSON by jsoni.
+func (JsoniCodec) Encode(w io.Writer, v interface{}) error {
+	return jsoni.NewEncoder(w).Encode(context.Background(), v)
+}
+
+// Decode decodes v from r in JSON by jsoni.
+func (JsoniCodec) Decode(r io.Reader, v interface{}) error {
+	return jsoni.NewDecoder(r).Decode(context.Background(), v)
+}
+
+// snapshotVersion is the version of the snapshot format.
+const snapshotVersion = 1
+
+// snapshot is the serialized form of the cache.
+type snapshot[K comparable, V any] struct {
+	Version int
+	Time    time.Time
+	// Items are in the order from the least recently used to the most recently used.
+	Items []snapshotItem[K, V]
+}
+
+// snapshotItem is the serialized form of an item.
+type snapshotItem[K comparable, V any] struct {
+	Key   K
+	Value V
+	TTL   time.Duration
+	// Remaining is the remaining TTL when the snapshot is taken, 0 for never expired.
+	Remaining time.Duration
+}
+
+// Snapshot writes the items, with their remaining TTLs and the recently
+// used order, to w by the codec of the cache (GobCodec by default).
+func (c *Cache[K, V]) Snapshot(w io.Writer) error {
+	now := time.Now()
+	s := snapshot[K, V]{Version: snapshotVersion, Time: now}
+
+	c.items.mu.RLock()
+	for elem := c.items.lru.Back(); elem != nil; elem = elem.Prev() {
+		item := elem.Value.(*Item[K, V])
+		if item.isExpiredUnsafe() {
+			continue
+		}
+
+		si := snapshotItem[K, V]{Key: item.key, Value: item.value, TTL: item.ttl}
+		if item.ttl > 0 {
+			si.Remaining = item.expiresAt.Sub(now)
+		}
+		s.Items = append(s.Items, si)
+	}
+	c.items.mu.RUnlock()
+
+	return c.codec().Encode(w, s)
+}
+
+// Restore reads the items from the snapshot in r by the codec of the cache,
+// and sets them in the recently used order. The remaining TTLs are counted from the
+// time of the snapshot, so the items expired since then are dropped.
+// The existing items of the same keys are overwritten.
+func (c *Cache[K, V]) Restore(r io.Reader) error {
+	var s snapshot[K, V]
+	if err := c.codec().Decode(r, &s); err != nil {
+		return err
+	}
+
+	if s.Version != snapshotVersion {
+		return fmt.Errorf("ttlcache: unsupported snapshot version %d", s.Version)
+	}
+
+	c.items.mu.Lock()
+	defer c.items.mu.Unlock()
+
+	now := time.Now()
+	elapsed := now.Sub(s.Time) // the time passed since the snapshot, like the downtime of the process
+	for _, si := range s.Items {
+		ttl := si.TTL
+		if ttl == DefaultTTL { // the zero TTL was resolved to the default one on set
+			ttl = NoTTL
+		}
+		remaining := si.Remaining - elapsed
+		if ttl > 0 && remaining <= 0 {
+			continue
+		}
+
+		item := c.set(si.Key, si.Value, ttl)
+		if ttl > 0 && remaining < ttl {
+			item.mu.Lock()
+			item.expiresAt = now.Add(remaining)
+			item.mu.Unlock()
+
+			if elem := c.items.values[si.Key]; elem != nil {
+				c.updateExpirations(false, elem)
+			}
+		}
+	}
+
+	return nil
+}
+
+// SnapshotFile writes the snapshot to a temporary file and renames it
+// to the path, in case of partial writes.
+func (c *Cache[K, V]) SnapshotFile(path string) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	tmp := f.Name()
+	if err := c.Snapshot(f); err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// RestoreFile restores the snapshot from the file of the path,
+// a non-existing file does NOT lead to an error.
+func (c *Cache[K, V]) RestoreFile(path string) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	return c.Restore(f)
+}
+
+// codec returns the codec of the cache, GobCodec by default.
+func (c *Cache[K, V]) codec() Codec {
+	if c.options.codec != nil {
+		return c.options.codec
+	}
+
+	return GobCodec{}
+}
+
+// snapshotTicker returns the ticker channel of the periodical snapshot, nil when disabled.
+func (c *Cache[K, V]) snapshotTicker() (<-chan time.Time, func()) {
+	if c.options.snapshotFile == "" || c.options.snapshotInterval <= 0 {
+		return nil, func() {}
+	}
+
+	t := time.NewTicker(c.options.snapshotInterval)
+	return t.C, t.Stop
+}
+
+// snapshotToFile takes the periodical snapshot.
+func (c *Cache[K, V]) snapshotToFile() {
+	if c.options.snapshotFile == "" {
+		return
+	}
+
+	if err := c.SnapshotFile(c.options.snapshotFile); err != nil {
+		log.Printf("W! ttlcache snapshot to %s failed: %v", c.options.snapshotFile, err)
+	}
+}
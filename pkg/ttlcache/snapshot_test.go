@@ -0,0 +1,81 @@
+package ttlcache
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Cache_SnapshotRestore(t *testing.T) {
+	for name, codec := range map[string]Codec{"gob": GobCodec{}, "json": JSONCodec{}, "jsoni": JsoniCodec{}} {
+		codec := codec
+
+		t.Run(name, func(t *testing.T) {
+			cache := New[string, int](WithTTL[string, int](time.Hour), WithCodec[string, int](codec))
+			cache.Set("a", 1, NoTTL)
+			cache.Set("b", 2, time.Minute)
+			cache.Set("c", 3, DefaultTTL)
+			cache.Set("expired", 4, time.Millisecond)
+			cache.Get("a") // a is the most recently used
+			time.Sleep(2 * time.Millisecond)
+
+			var buf bytes.Buffer
+			require.Nil(t, cache.Snapshot(&buf))
+
+			restored := New[string, int](WithCodec[string, int](codec))
+			require.Nil(t, restored.Restore(&buf))
+
+			assert.ElementsMatch(t, []string{"a", "b", "c"}, restored.Keys())
+			assert.Equal(t, "a", restored.items.lru.Front().Value.(*Item[string, int]).key)
+
+			a := restored.Get("a")
+			assert.Equal(t, 1, a.Value())
+			assert.True(t, a.ExpiresAt().IsZero())
+
+			b := restored.Get("b", WithDisableTouchOnHit[string, int]())
+			assert.Equal(t, time.Minute, b.TTL())
+			assert.WithinDuration(t, cache.Get("b", WithDisableTouchOnHit[string, int]()).ExpiresAt(), b.ExpiresAt(), time.Second)
+
+			assert.Equal(t, time.Hour, restored.Get("c").TTL())
+		})
+	}
+}
+
+func Test_Cache_RestoreBackdated(t *testing.T) {
+	var buf bytes.Buffer
+	require.Nil(t, GobCodec{}.Encode(&buf, snapshot[string, int]{
+		Version: snapshotVersion,
+		Time:    time.Now().Add(-time.Hour), // taken an hour ago, like before a restart
+		Items: []snapshotItem[string, int]{
+			{Key: "forever", Value: 1},
+			{Key: "expired", Value: 2, TTL: 2 * time.Hour, Remaining: 30 * time.Minute},
+			{Key: "alive", Value: 3, TTL: 2 * time.Hour, Remaining: 90 * time.Minute},
+		},
+	}))
+
+	restored := New[string, int]()
+	require.Nil(t, restored.Restore(&buf))
+
+	assert.ElementsMatch(t, []string{"forever", "alive"}, restored.Keys())
+	alive := restored.Get("alive", WithDisableTouchOnHit[string, int]())
+	assert.WithinDuration(t, time.Now().Add(30*time.Minute), alive.ExpiresAt(), time.Second)
+}
+
+func Test_Cache_SnapshotFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.snapshot")
+
+	cache := New[string, string](WithSnapshotFile[string, string](path, time.Hour))
+	cache.Set("k", "v", time.Hour)
+	go cache.Start()
+	cache.Stop() // snapshots on stop
+
+	warm := New[string, string](WithSnapshotFile[string, string](path, time.Hour))
+	require.NotNil(t, warm.Get("k"))
+	assert.Equal(t, "v", warm.Get("k").Value())
+
+	assert.Nil(t, New[string, string]().RestoreFile(filepath.Join(t.TempDir(), "missing")))
+}